@@ -8,15 +8,20 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/compose"
 	"github.com/sirupsen/logrus"
 
 	"llm-cache/configs"
 	"llm-cache/internal/app/handlers"
+	"llm-cache/internal/app/jobs"
+	"llm-cache/internal/app/middleware"
 	"llm-cache/internal/app/server"
 	"llm-cache/internal/eino/components"
 	einoconfig "llm-cache/internal/eino/config"
 	"llm-cache/internal/eino/flows"
+	"llm-cache/internal/eino/nodes"
 	"llm-cache/pkg/logger"
 )
 
@@ -54,16 +59,62 @@ func run(ctx context.Context) error {
 		"eino_embedder_provider", config.Eino.Embedder.Provider,
 		"eino_retriever_provider", config.Eino.Retriever.Provider)
 
-	// 3. 初始化 Eino 组件
-	queryRunner, storeRunner, deleteService, err := initializeEinoComponents(ctx, &config.Eino, appLogger)
+	// 3. 初始化 Eino 组件（PII 扫描器同时用于存储流程和查询日志脱敏）
+	piiScanner, err := nodes.NewPIIScanner(&config.Eino.PII)
+	if err != nil {
+		return fmt.Errorf("pii scanner 初始化失败: %w", err)
+	}
+	eino, err := initializeEinoComponents(ctx, &config.Eino, piiScanner, appLogger)
 	if err != nil {
 		return fmt.Errorf("eino 组件初始化失败: %w", err)
 	}
 	appLogger.InfoContext(ctx, "Eino 组件初始化完成")
 
 	// 4. 初始化应用层
-	cacheHandler := handlers.NewCacheHandler(queryRunner, storeRunner, deleteService, appLogger)
+	cacheHandler := handlers.NewCacheHandler(eino.queryRunner, eino.storeRunner, eino.deleteService, appLogger).
+		WithQueryLog(config.Eino.PII.QueryLogs, piiScanner).
+		WithQualityEvaluator(eino.qualityChecker)
+	if config.Eino.Query.Coalescing.Enabled {
+		coalescer := flows.NewQueryCoalescer(eino.queryRunner, &config.Eino.Retriever)
+		cacheHandler.WithQueryCoalescer(coalescer).
+			WithStatistics("query_coalescing", coalescer.GetMetrics)
+		appLogger.InfoContext(ctx, "相同并发查询合并已启用")
+	}
+	for name, collect := range components.EmbedderStatistics(eino.embedder) {
+		cacheHandler.WithStatistics(name, collect)
+	}
 	httpServer := server.NewServer(&config.Server, cacheHandler, appLogger)
+	if config.Eino.Trust.Enabled {
+		storeAuth, err := middleware.NewSourceAuth(&config.Eino.Trust, appLogger)
+		if err != nil {
+			return fmt.Errorf("存储来源认证初始化失败: %w", err)
+		}
+		httpServer.WithStoreAuth(storeAuth)
+		appLogger.InfoContext(ctx, "存储来源认证已启用",
+			"sources", len(config.Eino.Trust.Sources),
+			"allow_anonymous", config.Eino.Trust.AllowAnonymous,
+			"confirmations", config.Eino.Trust.Confirmations)
+	}
+	if config.Eino.Store.Async.Enabled {
+		storeQueue, err := jobs.NewStoreQueue(&config.Eino.Store.Async,
+			func(ctx context.Context, input *flows.CacheStoreInput) (*flows.CacheStoreOutput, error) {
+				return eino.storeRunner.Invoke(ctx, input)
+			}, appLogger)
+		if err != nil {
+			return fmt.Errorf("异步写入队列初始化失败: %w", err)
+		}
+		if config.Eino.PII.Enabled {
+			storeQueue.WithPII(piiScanner)
+		}
+		storeQueue.Start(ctx)
+		// 在 HTTP 服务器关闭后停止 worker，未完成的任务保留在日志中
+		defer func() {
+			if err := storeQueue.Close(); err != nil {
+				appLogger.ErrorContext(ctx, "异步写入队列关闭失败", "error", err)
+			}
+		}()
+		cacheHandler.WithStoreQueue(storeQueue)
+	}
 
 	// 5. 启动服务并等待停止信号
 	return runApplication(ctx, httpServer, appLogger)
@@ -105,17 +156,22 @@ func initializeLogger(config configs.LoggingConfig) (logger.Logger, error) {
 	return logger.New(loggerConfig), nil
 }
 
+// einoComponents 保存初始化完成的 Eino 组件
+type einoComponents struct {
+	embedder       embedding.Embedder
+	queryRunner    compose.Runnable[*flows.CacheQueryInput, *flows.CacheQueryOutput]
+	storeRunner    compose.Runnable[*flows.CacheStoreInput, *flows.CacheStoreOutput]
+	deleteService  flows.CacheDeleter
+	qualityChecker *nodes.QualityChecker
+}
+
 // initializeEinoComponents 初始化 Eino 组件
 func initializeEinoComponents(
 	ctx context.Context,
 	einoCfg *einoconfig.EinoConfig,
+	piiScanner *nodes.PIIScanner,
 	log logger.Logger,
-) (
-	compose.Runnable[*flows.CacheQueryInput, *flows.CacheQueryOutput],
-	compose.Runnable[*flows.CacheStoreInput, *flows.CacheStoreOutput],
-	flows.CacheDeleter,
-	error,
-) {
+) (*einoComponents, error) {
 	// 1. 创建 Embedder
 	log.InfoContext(ctx, "正在初始化 Embedder",
 		"provider", einoCfg.Embedder.Provider,
@@ -123,9 +179,12 @@ func initializeEinoComponents(
 
 	embedder, err := components.NewEmbedder(ctx, &einoCfg.Embedder)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("embedder 初始化失败: %w", err)
+		return nil, fmt.Errorf("embedder 初始化失败: %w", err)
 	}
-	log.InfoContext(ctx, "Embedder 初始化成功")
+	log.InfoContext(ctx, "Embedder 初始化成功",
+		"cache_enabled", einoCfg.Embedder.Cache.Enabled,
+		"cache_backend", einoCfg.Embedder.Cache.Backend,
+		"batching_enabled", einoCfg.Embedder.Batching.Enabled)
 
 	// 2. 创建 Retriever
 	log.InfoContext(ctx, "正在初始化 Retriever",
@@ -134,7 +193,7 @@ func initializeEinoComponents(
 
 	retriever, err := components.NewRetriever(ctx, &einoCfg.Retriever, embedder)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("retriever 初始化失败: %w", err)
+		return nil, fmt.Errorf("retriever 初始化失败: %w", err)
 	}
 	log.InfoContext(ctx, "Retriever 初始化成功")
 
@@ -145,34 +204,97 @@ func initializeEinoComponents(
 
 	indexer, err := components.NewIndexer(ctx, &einoCfg.Indexer, embedder)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("indexer 初始化失败: %w", err)
+		return nil, fmt.Errorf("indexer 初始化失败: %w", err)
 	}
 	log.InfoContext(ctx, "Indexer 初始化成功")
 
-	// 4. 创建 Query Graph 并编译
-	queryGraph := flows.NewCacheQueryGraph(embedder, retriever, &einoCfg.Query)
+	// 4. 创建 Query Graph 并编译（与 Store Graph 共用同一模板剥离和文本规范化流水线）
+	normalizer, err := nodes.NewNormalizer(&einoCfg.Normalization)
+	if err != nil {
+		return nil, fmt.Errorf("normalizer 初始化失败: %w", err)
+	}
+	templates, err := nodes.NewTemplateExtractor(&einoCfg.Templates)
+	if err != nil {
+		return nil, fmt.Errorf("prompt templates 初始化失败: %w", err)
+	}
+	// 答案改写和 LLM 评审共用同一个 ChatModel
+	var chatModel model.BaseChatModel
+	if einoCfg.Query.Adaptation.Enabled || einoCfg.Quality.Judge.Enabled {
+		chatModel, err = components.NewChatModel(ctx, &einoCfg.ChatModel)
+		if err != nil {
+			return nil, fmt.Errorf("chat model 初始化失败: %w", err)
+		}
+		log.InfoContext(ctx, "ChatModel 初始化成功", "provider", einoCfg.ChatModel.Provider)
+	}
+
+	queryGraph := flows.NewCacheQueryGraph(embedder, retriever, &einoCfg.Query).
+		WithConversation(&einoCfg.Conversation).
+		WithGeneration(&einoCfg.Generation).
+		WithNormalizer(normalizer).
+		WithTemplates(templates).
+		WithSlots(&einoCfg.Slots).
+		WithHitThreshold(einoCfg.Retriever.ScoreThreshold)
+	if einoCfg.Reranker.Enabled {
+		reranker, err := components.NewReranker(ctx, &einoCfg.Reranker)
+		if err != nil {
+			return nil, fmt.Errorf("reranker 初始化失败: %w", err)
+		}
+		queryGraph.WithReranker(reranker, &einoCfg.Reranker)
+		log.InfoContext(ctx, "Reranker 初始化成功", "provider", einoCfg.Reranker.Provider)
+	}
+	if einoCfg.Query.Adaptation.Enabled {
+		adapter := nodes.NewAnswerAdapter(chatModel, &einoCfg.Query.Adaptation)
+		queryGraph.WithAdaptation(adapter, einoCfg.Retriever.ScoreThreshold)
+		log.InfoContext(ctx, "答案改写已启用", "provider", einoCfg.ChatModel.Provider, "threshold", einoCfg.Query.Adaptation.Threshold)
+	}
 	queryRunner, err := queryGraph.Compile(ctx)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("query graph 编译失败: %w", err)
+		return nil, fmt.Errorf("query graph 编译失败: %w", err)
 	}
 	log.InfoContext(ctx, "Query Graph 编译成功")
 
 	// 5. 创建 Store Graph 并编译
-	storeGraph := flows.NewCacheStoreGraph(embedder, indexer, &einoCfg.Store, &einoCfg.Quality)
+	storeGraph := flows.NewCacheStoreGraph(embedder, indexer, &einoCfg.Store, &einoCfg.Quality).
+		WithConversation(&einoCfg.Conversation).
+		WithGeneration(&einoCfg.Generation).
+		WithNormalizer(normalizer).
+		WithTemplates(templates).
+		WithSlots(&einoCfg.Slots)
+	if einoCfg.PII.Enabled {
+		storeGraph.WithPII(piiScanner)
+		log.InfoContext(ctx, "PII 扫描已启用", "default_action", einoCfg.PII.DefaultAction)
+	}
+	if einoCfg.Trust.Enabled {
+		storeGraph.WithTrust(&einoCfg.Trust, retriever)
+	}
+	if einoCfg.Quality.Judge.Enabled {
+		storeGraph.WithJudge(nodes.NewQualityJudge(chatModel, &einoCfg.Quality.Judge))
+		log.InfoContext(ctx, "LLM 评审已启用", "threshold", einoCfg.Quality.Judge.Threshold, "fail_mode", einoCfg.Quality.Judge.FailMode)
+	}
 	storeRunner, err := storeGraph.Compile(ctx)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("store graph 编译失败: %w", err)
+		return nil, fmt.Errorf("store graph 编译失败: %w", err)
+	}
+	qualityChecker, err := storeGraph.QualityChecker()
+	if err != nil {
+		return nil, fmt.Errorf("quality checker 初始化失败: %w", err)
 	}
 	log.InfoContext(ctx, "Store Graph 编译成功")
 
 	// 6. 创建 Delete Service（使用工厂函数，支持多种向量数据库）
 	deleteService, err := flows.NewCacheDeleter(&einoCfg.Retriever)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("delete service 初始化失败: %w", err)
+		return nil, fmt.Errorf("delete service 初始化失败: %w", err)
 	}
 	log.InfoContext(ctx, "Delete Service 创建成功", "provider", einoCfg.Retriever.Provider)
 
-	return queryRunner, storeRunner, deleteService, nil
+	return &einoComponents{
+		queryRunner:    queryRunner,
+		embedder:       embedder,
+		storeRunner:    storeRunner,
+		deleteService:  deleteService,
+		qualityChecker: qualityChecker,
+	}, nil
 }
 
 // runApplication 运行应用程序，监听停止信号
@@ -114,13 +114,8 @@ type QualityConfig struct {
 }
 
 // QualityStrategy 定义具体的质量评估策略。
-// 包含策略名称、权重和特定的配置参数。
-type QualityStrategy struct {
-	Name    string                 `yaml:"name"`
-	Weight  float64                `yaml:"weight"`
-	Enabled bool                   `yaml:"enabled"`
-	Config  map[string]interface{} `yaml:"config"`
-}
+// 与 Eino 存储流程使用的策略定义相同，未配置 eino.quality.strategies 时沿用此处的策略。
+type QualityStrategy = einoconfig.QualityStrategy
 
 // QualityBlacklist 定义质量评估的黑名单规则。
 // 包含关键词过滤和文本长度限制等条件。
@@ -46,6 +46,17 @@ func Load(ctx context.Context) (*Config, error) {
 	// 从环境变量覆盖配置
 	loadFromEnv(config)
 
+	// 存储流程未单独配置质量策略和拒答关键词时，沿用顶层 quality 配置
+	if len(config.Eino.Quality.Strategies) == 0 {
+		config.Eino.Quality.Strategies = config.Quality.Strategies
+	}
+	if len(config.Eino.Quality.Refusal.ApologyKeywords) == 0 {
+		config.Eino.Quality.Refusal.ApologyKeywords = config.Quality.Blacklist.ApologyKeywords
+	}
+	if len(config.Eino.Quality.Refusal.ErrorKeywords) == 0 {
+		config.Eino.Quality.Refusal.ErrorKeywords = config.Quality.Blacklist.ErrorKeywords
+	}
+
 	// 验证配置
 	if err := config.Validate(); err != nil {
 		return nil, err
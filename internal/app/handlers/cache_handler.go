@@ -1,15 +1,21 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
 	"github.com/gin-gonic/gin"
 
+	"llm-cache/internal/app/jobs"
 	"llm-cache/internal/app/middleware"
+	"llm-cache/internal/eino/components"
 	"llm-cache/internal/eino/flows"
+	"llm-cache/internal/eino/nodes"
 	"llm-cache/pkg/logger"
 	"llm-cache/pkg/status"
 )
@@ -21,6 +27,16 @@ type CacheHandler struct {
 	storeRunner   compose.Runnable[*flows.CacheStoreInput, *flows.CacheStoreOutput]
 	deleteService flows.CacheDeleter
 	logger        logger.Logger
+	// queryLog 查询日志中记录问题文本的方式（为 nil 时不记录问题）
+	queryLog func(question string) string
+	// qualityChecker 质量检查试运行使用的检查器（为 nil 时试运行接口不可用）
+	qualityChecker *nodes.QualityChecker
+	// coalescer 相同并发查询合并器（为 nil 时每个查询独立执行）
+	coalescer *flows.QueryCoalescer
+	// storeQueue 异步写入队列（为 nil 时同步写入）
+	storeQueue *jobs.StoreQueue
+	// statistics 统计接口附加的组件指标（名称 -> 指标采集函数）
+	statistics map[string]func() map[string]interface{}
 }
 
 // NewCacheHandler 创建一个新的 CacheHandler 实例。
@@ -39,6 +55,52 @@ func NewCacheHandler(
 	}
 }
 
+// WithQueryLog 设置查询日志中问题文本的记录方式。
+// 参数 mode: omit（不记录）, redact（使用 PII 扫描器脱敏后记录）, raw（记录原文）。
+// 参数 scanner: PII 扫描器（redact 模式必需）。
+// 返回: CacheHandler 指针，便于链式调用。
+func (h *CacheHandler) WithQueryLog(mode string, scanner *nodes.PIIScanner) *CacheHandler {
+	h.queryLog = nil
+	switch mode {
+	case "raw":
+		h.queryLog = func(question string) string { return question }
+	case "redact":
+		if scanner != nil {
+			h.queryLog = scanner.Redact
+		}
+	}
+	return h
+}
+
+// WithStoreQueue 设置异步写入队列。
+// 设置后存储请求在参数验证通过后写入队列并返回 202 和任务 ID，由后台 worker 完成写入。
+// 参数 queue: 异步写入队列。
+// 返回: CacheHandler 指针，便于链式调用。
+func (h *CacheHandler) WithStoreQueue(queue *jobs.StoreQueue) *CacheHandler {
+	h.storeQueue = queue
+	return h
+}
+
+// WithQueryCoalescer 设置相同并发查询合并器。
+// 参数 coalescer: 查询合并器，键相同的并发查询共享一次 Graph 执行。
+// 返回: CacheHandler 指针，便于链式调用。
+func (h *CacheHandler) WithQueryCoalescer(coalescer *flows.QueryCoalescer) *CacheHandler {
+	h.coalescer = coalescer
+	return h
+}
+
+// WithStatistics 在统计接口中附加组件指标。
+// 参数 name: 指标在统计结果中的键名。
+// 参数 collect: 指标采集函数，每次请求统计接口时调用。
+// 返回: CacheHandler 指针，便于链式调用。
+func (h *CacheHandler) WithStatistics(name string, collect func() map[string]interface{}) *CacheHandler {
+	if h.statistics == nil {
+		h.statistics = make(map[string]func() map[string]interface{})
+	}
+	h.statistics[name] = collect
+	return h
+}
+
 // APIResponse 定义统一的 API 响应结构。
 // 包含请求是否成功、状态码、提示消息、数据载荷以及请求追踪信息。
 type APIResponse struct {
@@ -58,23 +120,48 @@ type ErrorDetail struct {
 	Code    string `json:"code,omitempty"`
 }
 
+// ChatMessage 定义多轮对话中的一条消息。
+type ChatMessage struct {
+	Role    string `json:"role"` // system, user, assistant
+	Content string `json:"content"`
+}
+
 // QueryRequest 定义缓存查询请求的参数结构。
 // 包含查询问题、用户类型以及可选的搜索参数（TopK、相似度阈值）。
+// 提供 messages 时，question 可省略（取最后一轮用户消息）。
 type QueryRequest struct {
-	Question            string  `json:"question" binding:"required"`
-	UserType            string  `json:"user_type" binding:"required"`
-	TopK                int     `json:"top_k,omitempty"`
-	SimilarityThreshold float64 `json:"similarity_threshold,omitempty"`
+	Question            string        `json:"question"`
+	UserType            string        `json:"user_type" binding:"required"`
+	TopK                int           `json:"top_k,omitempty"`
+	SimilarityThreshold float64       `json:"similarity_threshold,omitempty"`
+	Messages            []ChatMessage `json:"messages,omitempty"`
+
+	// Generation 生成上下文，仅命中由相同（或兼容）生成参数产生的缓存
+	Generation *nodes.GenerationContext `json:"generation,omitempty"`
+
+	// Explain 为 true 时返回候选列表及其被拒绝的原因（调试用）
+	Explain bool `json:"explain,omitempty"`
+
+	// Slots 参数化答案的槽位取值（优先于从问题中提取）
+	Slots map[string]string `json:"slots,omitempty"`
 }
 
 // StoreRequest 定义缓存存储请求的参数结构。
 // 包含问题、答案、用户类型以及元数据，支持强制写入选项。
+// messages 为产生该问答的多轮对话历史（可选）。
 type StoreRequest struct {
 	Question   string         `json:"question" binding:"required"`
 	Answer     string         `json:"answer" binding:"required"`
 	UserType   string         `json:"user_type" binding:"required"`
 	Metadata   map[string]any `json:"metadata,omitempty"`
 	ForceWrite bool           `json:"force_write,omitempty"`
+	Messages   []ChatMessage  `json:"messages,omitempty"`
+
+	// Generation 产生该答案时使用的生成上下文
+	Generation *nodes.GenerationContext `json:"generation,omitempty"`
+
+	// Slots 槽位提取规则，非空时答案作为带 {name} 槽位的模板存储
+	Slots []nodes.SlotRule `json:"slots,omitempty"`
 }
 
 // DeleteRequest 定义缓存删除请求的参数结构。
@@ -119,11 +206,24 @@ func (h *CacheHandler) QueryCache(c *gin.Context) {
 		UserType:       req.UserType,
 		TopK:           req.TopK,
 		ScoreThreshold: req.SimilarityThreshold,
+		Messages:       toSchemaMessages(req.Messages),
+		Generation:     req.Generation,
+		Explain:        req.Explain,
+		Slots:          req.Slots,
 	}
 
 	// 调用 Eino Runnable
 	startTime := time.Now()
-	result, err := h.queryRunner.Invoke(ctx, input)
+	var (
+		result    *flows.CacheQueryOutput
+		coalesced bool
+		err       error
+	)
+	if h.coalescer != nil {
+		result, coalesced, err = h.coalescer.Invoke(ctx, input)
+	} else {
+		result, err = h.queryRunner.Invoke(ctx, input)
+	}
 	duration := time.Since(startTime).Milliseconds()
 
 	if err != nil {
@@ -131,14 +231,22 @@ func (h *CacheHandler) QueryCache(c *gin.Context) {
 			"request_id", requestID,
 			"duration_ms", duration,
 			"error", err.Error())
-		h.respondWithError(c, status.ErrCodeInternal, "缓存查询失败", err.Error())
+		h.respondWithError(c, graphErrorCode(err), "缓存查询失败", err.Error())
 		return
 	}
 
-	h.logger.InfoContext(ctx, "缓存查询请求处理完成",
+	fields := []any{
 		"request_id", requestID,
 		"duration_ms", duration,
-		"found", result.Hit)
+		"found", result.Hit,
+		"coalesced", coalesced,
+	}
+	if h.queryLog != nil {
+		// 未显式提供问题时记录从 messages 解析出的问题（与查询 Graph 的取值一致）
+		question := nodes.BuildConversationContext(input.Query, input.Messages, 0).Question
+		fields = append(fields, "question", h.queryLog(question))
+	}
+	h.logger.InfoContext(ctx, "缓存查询请求处理完成", fields...)
 
 	// 返回成功响应
 	h.respondWithSuccess(c, result, "缓存查询成功")
@@ -179,6 +287,37 @@ func (h *CacheHandler) StoreCache(c *gin.Context) {
 		UserType:   req.UserType,
 		Metadata:   req.Metadata,
 		ForceWrite: req.ForceWrite,
+		Messages:   toSchemaMessages(req.Messages),
+		Generation: req.Generation,
+		Slots:      req.Slots,
+	}
+	if source := middleware.GetSource(c); source != nil {
+		input.Source = source.ID
+		input.Trust = source.Trust
+	}
+
+	// 异步模式：写入本地日志后立即返回任务 ID
+	if h.storeQueue != nil {
+		job, err := h.storeQueue.Enqueue(input)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "缓存存储任务入队失败",
+				"request_id", requestID,
+				"error", err.Error())
+			code := status.ErrCodeInternal
+			if errors.Is(err, jobs.ErrQueueFull) {
+				code = status.ErrCodeUnavailable
+			}
+			h.respondWithError(c, code, "缓存存储任务入队失败", err.Error())
+			return
+		}
+
+		h.logger.InfoContext(ctx, "缓存存储任务已入队",
+			"request_id", requestID,
+			"job_id", job.ID,
+			"status", job.Status,
+			"source", input.Source)
+		h.respondWithStatus(c, http.StatusAccepted, job, "缓存存储任务已接受")
+		return
 	}
 
 	// 调用 Eino Runnable
@@ -191,7 +330,7 @@ func (h *CacheHandler) StoreCache(c *gin.Context) {
 			"request_id", requestID,
 			"duration_ms", duration,
 			"error", err.Error())
-		h.respondWithError(c, status.ErrCodeInternal, "缓存存储失败", err.Error())
+		h.respondWithError(c, graphErrorCode(err), "缓存存储失败", err.Error())
 		return
 	}
 
@@ -199,7 +338,9 @@ func (h *CacheHandler) StoreCache(c *gin.Context) {
 		"request_id", requestID,
 		"duration_ms", duration,
 		"success", result.Success,
-		"cache_id", result.CacheID)
+		"cache_id", result.CacheID,
+		"source", input.Source,
+		"status", result.Status)
 
 	// 返回成功响应
 	h.respondWithSuccess(c, result, "缓存存储成功")
@@ -379,6 +520,35 @@ func (h *CacheHandler) GetCacheByID(c *gin.Context) {
 	h.respondWithSuccess(c, cacheItem, "缓存查询成功")
 }
 
+// GetJob 处理查询异步写入任务状态的请求 (GET /v1/cache/jobs/:job_id)。
+// 返回任务状态、尝试次数、最近一次错误以及结束后的存储结果。
+// 参数 c: Gin 上下文对象，用于处理 HTTP 请求和响应。
+func (h *CacheHandler) GetJob(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID := middleware.GetRequestID(c)
+	jobID := c.Param("job_id")
+
+	if h.storeQueue == nil {
+		h.respondWithError(c, status.ErrCodeUnavailable, "异步写入未启用", "")
+		return
+	}
+	if strings.TrimSpace(jobID) == "" {
+		h.respondWithError(c, status.ErrCodeInvalidParam, "任务ID不能为空", "")
+		return
+	}
+
+	job, ok := h.storeQueue.Get(jobID)
+	if !ok {
+		h.logger.InfoContext(ctx, "异步写入任务不存在",
+			"request_id", requestID,
+			"job_id", jobID)
+		h.respondWithError(c, status.ErrCodeNotFound, "任务不存在", "")
+		return
+	}
+
+	h.respondWithSuccess(c, job, "任务查询成功")
+}
+
 // GetCacheStatistics 处理获取缓存统计信息的请求 (GET /v1/cache/statistics)。
 // 返回当前的系统运行状态和统计数据。
 // 参数 c: Gin 上下文对象，用于处理 HTTP 请求和响应。
@@ -393,6 +563,9 @@ func (h *CacheHandler) GetCacheStatistics(c *gin.Context) {
 		"status": "running",
 		"time":   time.Now().Unix(),
 	}
+	for name, collect := range h.statistics {
+		statistics[name] = collect()
+	}
 
 	h.logger.InfoContext(ctx, "缓存统计查询请求处理完成", "request_id", requestID)
 
@@ -422,10 +595,18 @@ func (h *CacheHandler) HealthCheck(c *gin.Context) {
 
 // validateQueryRequest 验证查询请求
 func (h *CacheHandler) validateQueryRequest(req *QueryRequest) error {
-	if strings.TrimSpace(req.Question) == "" {
+	if strings.TrimSpace(req.Question) == "" && !hasUserMessage(req.Messages) {
 		return &ValidationError{Field: "question", Message: "问题不能为空"}
 	}
 
+	if err := validateMessages(req.Messages); err != nil {
+		return err
+	}
+
+	if err := validateGeneration(req.Generation); err != nil {
+		return err
+	}
+
 	if strings.TrimSpace(req.UserType) == "" {
 		return &ValidationError{Field: "user_type", Message: "用户类型不能为空"}
 	}
@@ -463,9 +644,64 @@ func (h *CacheHandler) validateStoreRequest(req *StoreRequest) error {
 		return &ValidationError{Field: "user_type", Message: "用户类型不能为空"}
 	}
 
+	if err := validateMessages(req.Messages); err != nil {
+		return err
+	}
+
+	if err := validateGeneration(req.Generation); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// validateMessages 验证对话消息的角色
+func validateMessages(messages []ChatMessage) error {
+	for _, msg := range messages {
+		switch schema.RoleType(msg.Role) {
+		case schema.System, schema.User, schema.Assistant:
+		default:
+			return &ValidationError{Field: "messages", Message: "消息角色必须为system、user或assistant"}
+		}
+	}
+	return nil
+}
+
+// validateGeneration 验证生成上下文参数
+func validateGeneration(gc *nodes.GenerationContext) error {
+	if gc == nil {
+		return nil
+	}
+	if gc.Temperature != nil && (*gc.Temperature < 0 || *gc.Temperature > 2) {
+		return &ValidationError{Field: "generation.temperature", Message: "温度必须在0-2之间"}
+	}
+	if len(gc.Tools) > 0 && !json.Valid(gc.Tools) {
+		return &ValidationError{Field: "generation.tools", Message: "工具定义必须为合法的JSON"}
+	}
+	return nil
+}
+
+// hasUserMessage 判断对话消息的最后一条是否为非空的用户消息
+func hasUserMessage(messages []ChatMessage) bool {
+	if len(messages) == 0 {
+		return false
+	}
+	last := messages[len(messages)-1]
+	return last.Role == string(schema.User) && strings.TrimSpace(last.Content) != ""
+}
+
+// toSchemaMessages 将请求中的对话消息转换为 Eino 消息
+func toSchemaMessages(messages []ChatMessage) []*schema.Message {
+	if len(messages) == 0 {
+		return nil
+	}
+	result := make([]*schema.Message, len(messages))
+	for i, msg := range messages {
+		result[i] = &schema.Message{Role: schema.RoleType(msg.Role), Content: msg.Content}
+	}
+	return result
+}
+
 // ValidationError 验证错误
 type ValidationError struct {
 	Field   string
@@ -478,8 +714,21 @@ func (e *ValidationError) Error() string {
 
 // 私有方法：响应处理
 
+// graphErrorCode 返回 Graph 执行错误对应的业务状态码（Embedding 提供商均不可用时为服务不可用）
+func graphErrorCode(err error) status.StatusCode {
+	if errors.Is(err, components.ErrEmbeddingUnavailable) {
+		return status.ErrCodeUnavailable
+	}
+	return status.ErrCodeInternal
+}
+
 // respondWithSuccess 返回成功响应
 func (h *CacheHandler) respondWithSuccess(c *gin.Context, data interface{}, message string) {
+	h.respondWithStatus(c, http.StatusOK, data, message)
+}
+
+// respondWithStatus 使用指定的 HTTP 状态码返回成功响应
+func (h *CacheHandler) respondWithStatus(c *gin.Context, httpStatus int, data interface{}, message string) {
 	response := APIResponse{
 		Success:   true,
 		Code:      int(status.CodeOK),
@@ -489,7 +738,7 @@ func (h *CacheHandler) respondWithSuccess(c *gin.Context, data interface{}, mess
 		Timestamp: time.Now().Unix(),
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(httpStatus, response)
 }
 
 // respondWithError 返回错误响应
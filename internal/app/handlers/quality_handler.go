@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"llm-cache/internal/app/middleware"
+	"llm-cache/internal/eino/nodes"
+	"llm-cache/pkg/status"
+)
+
+// maxQualityBatchSize 批量质量检查试运行的最大问答对数量
+const maxQualityBatchSize = 100
+
+// QualityEvaluateRequest 定义质量检查试运行请求的参数结构。
+// 与存储请求使用相同的问答字段，但不会写入缓存。
+type QualityEvaluateRequest struct {
+	Question string         `json:"question"`
+	Answer   string         `json:"answer"`
+	UserType string         `json:"user_type"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// QualityBatchEvaluateRequest 定义批量质量检查试运行请求的参数结构。
+type QualityBatchEvaluateRequest struct {
+	Pairs []QualityEvaluateRequest `json:"pairs" binding:"required"`
+}
+
+// WithQualityEvaluator 设置质量检查试运行使用的检查器（应与存储流程使用同一配置）。
+// 参数 checker: 质量检查器，为 nil 时试运行接口返回服务不可用。
+// 返回: CacheHandler 指针，便于链式调用。
+func (h *CacheHandler) WithQualityEvaluator(checker *nodes.QualityChecker) *CacheHandler {
+	h.qualityChecker = checker
+	return h
+}
+
+// EvaluateQuality 处理质量检查试运行请求 (POST /v1/cache/quality/evaluate)。
+// 仅执行存储流程的质量检查，返回是否通过、综合分数和各项检查明细，不写入缓存。
+// 参数 c: Gin 上下文对象，用于处理 HTTP 请求和响应。
+func (h *CacheHandler) EvaluateQuality(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID := middleware.GetRequestID(c)
+
+	if h.qualityChecker == nil {
+		h.respondWithError(c, status.ErrCodeUnavailable, "质量检查试运行不可用", "")
+		return
+	}
+
+	// 解析请求参数
+	var req QualityEvaluateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.ErrorContext(ctx, "质量检查试运行请求参数解析失败",
+			"request_id", requestID,
+			"error", err.Error())
+		h.respondWithError(c, status.ErrCodeInvalidParam, "请求参数格式错误", err.Error())
+		return
+	}
+	if err := validateQualityPair(&req); err != nil {
+		h.respondWithError(c, status.ErrCodeInvalidParam, "请求参数验证失败", err.Error())
+		return
+	}
+
+	startTime := time.Now()
+	result, err := h.qualityChecker.Evaluate(ctx, req.toCheckInput())
+	duration := time.Since(startTime).Milliseconds()
+	if err != nil {
+		h.logger.ErrorContext(ctx, "质量检查试运行失败",
+			"request_id", requestID,
+			"duration_ms", duration,
+			"error", err.Error())
+		h.respondWithError(c, status.ErrCodeInternal, "质量检查试运行失败", err.Error())
+		return
+	}
+
+	h.logger.InfoContext(ctx, "质量检查试运行完成",
+		"request_id", requestID,
+		"duration_ms", duration,
+		"passed", result.Passed,
+		"score", result.Score,
+		"rejected_by", result.RejectedBy)
+
+	h.respondWithSuccess(c, result, "质量检查试运行成功")
+}
+
+// EvaluateQualityBatch 处理批量质量检查试运行请求 (POST /v1/cache/quality/evaluate/batch)。
+// 返回每个问答对的检查结果，以及按拒绝项汇总的数量。
+// 参数 c: Gin 上下文对象，用于处理 HTTP 请求和响应。
+func (h *CacheHandler) EvaluateQualityBatch(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID := middleware.GetRequestID(c)
+
+	if h.qualityChecker == nil {
+		h.respondWithError(c, status.ErrCodeUnavailable, "质量检查试运行不可用", "")
+		return
+	}
+
+	// 解析请求参数
+	var req QualityBatchEvaluateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.ErrorContext(ctx, "批量质量检查试运行请求参数解析失败",
+			"request_id", requestID,
+			"error", err.Error())
+		h.respondWithError(c, status.ErrCodeInvalidParam, "请求参数格式错误", err.Error())
+		return
+	}
+	if len(req.Pairs) == 0 || len(req.Pairs) > maxQualityBatchSize {
+		h.respondWithError(c, status.ErrCodeInvalidParam, "请求参数验证失败",
+			fmt.Sprintf("pairs 数量必须在 1 到 %d 之间", maxQualityBatchSize))
+		return
+	}
+
+	inputs := make([]*nodes.QualityCheckInput, len(req.Pairs))
+	for i := range req.Pairs {
+		if err := validateQualityPair(&req.Pairs[i]); err != nil {
+			h.respondWithError(c, status.ErrCodeInvalidParam, "请求参数验证失败", fmt.Sprintf("pairs[%d]: %v", i, err))
+			return
+		}
+		inputs[i] = req.Pairs[i].toCheckInput()
+	}
+
+	startTime := time.Now()
+	result, err := h.qualityChecker.EvaluateBatch(ctx, inputs)
+	duration := time.Since(startTime).Milliseconds()
+	if err != nil {
+		h.logger.ErrorContext(ctx, "批量质量检查试运行失败",
+			"request_id", requestID,
+			"pair_count", len(inputs),
+			"duration_ms", duration,
+			"error", err.Error())
+		h.respondWithError(c, status.ErrCodeInternal, "批量质量检查试运行失败", err.Error())
+		return
+	}
+
+	h.logger.InfoContext(ctx, "批量质量检查试运行完成",
+		"request_id", requestID,
+		"pair_count", result.Total,
+		"passed", result.Passed,
+		"rejected", result.Rejected,
+		"duration_ms", duration)
+
+	h.respondWithSuccess(c, result, "批量质量检查试运行成功")
+}
+
+// toCheckInput 转换为质量检查输入
+func (r *QualityEvaluateRequest) toCheckInput() *nodes.QualityCheckInput {
+	return &nodes.QualityCheckInput{
+		Question: r.Question,
+		Answer:   r.Answer,
+		UserType: r.UserType,
+		Metadata: r.Metadata,
+	}
+}
+
+// validateQualityPair 验证试运行的问答对（不限制长度，便于调整长度阈值）
+func validateQualityPair(req *QualityEvaluateRequest) error {
+	if strings.TrimSpace(req.Question) == "" {
+		return &ValidationError{Field: "question", Message: "问题不能为空"}
+	}
+	if strings.TrimSpace(req.Answer) == "" {
+		return &ValidationError{Field: "answer", Message: "答案不能为空"}
+	}
+	return nil
+}
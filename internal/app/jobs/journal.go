@@ -0,0 +1,169 @@
+// Package jobs 提供异步写入队列及其本地持久化日志
+package jobs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"llm-cache/internal/eino/flows"
+)
+
+// journalFile 日志文件名
+const journalFile = "store.journal"
+
+// 日志记录类型
+const (
+	opEnqueue = "enqueue" // 任务入队（包含完整的存储输入）
+	opFinish  = "finish"  // 任务结束（成功、被拒绝或放弃）
+)
+
+// record 定义日志中的一条记录（JSON Lines 格式）。
+// enqueue 记录保存存储输入和认证后的来源（CacheStoreInput 不序列化来源字段）。
+type record struct {
+	Op     string                 `json:"op"`
+	Job    Job                    `json:"job"`
+	Input  *flows.CacheStoreInput `json:"input,omitempty"`
+	Source string                 `json:"source,omitempty"`
+	Trust  string                 `json:"trust,omitempty"`
+}
+
+// Journal 实现追加写入的本地任务日志。
+// 每条记录写入后立即 fsync，保证已返回任务 ID 的请求在进程崩溃后仍可重放。
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// OpenJournal 打开（或创建）指定目录下的任务日志。
+// 参数 dir: 日志目录，不存在时自动创建。
+// 返回: Journal 指针或错误。
+func OpenJournal(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create journal dir: %w", err)
+	}
+	j := &Journal{path: filepath.Join(dir, journalFile)}
+	if err := j.open(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Append 追加一条记录并同步到磁盘
+func (j *Journal) Append(rec *record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal journal record: %w", err)
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(data); err != nil {
+		return fmt.Errorf("write journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("sync journal: %w", err)
+	}
+	return nil
+}
+
+// Replay 按写入顺序读取全部记录。
+// 进程崩溃可能留下不完整的最后一行，该行会被忽略。
+func (j *Journal) Replay() ([]*record, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.Open(j.path)
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	defer file.Close()
+
+	var records []*record
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// 没有换行符结尾的行是未写完的记录
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read journal: %w", err)
+		}
+
+		rec := &record{}
+		if err := json.Unmarshal(line, rec); err != nil {
+			return nil, fmt.Errorf("unmarshal journal record: %w", err)
+		}
+		records = append(records, rec)
+	}
+}
+
+// Rewrite 使用给定记录原子地替换日志内容（用于压缩已完成的任务）
+func (j *Journal) Rewrite(records []*record) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("create journal: %w", err)
+	}
+	writer := bufio.NewWriter(tmp)
+	for _, rec := range records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("marshal journal record: %w", err)
+		}
+		writer.Write(data)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write journal: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync journal: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close journal: %w", err)
+	}
+
+	if err := j.file.Close(); err != nil {
+		return fmt.Errorf("close journal: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		// 替换失败时继续追加到原日志
+		if openErr := j.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("replace journal: %w", err)
+	}
+	return j.open()
+}
+
+// Close 关闭日志文件
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// open 以追加模式打开日志文件（调用方需持有锁或在初始化时调用）
+func (j *Journal) open() error {
+	file, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open journal: %w", err)
+	}
+	j.file = file
+	return nil
+}
@@ -0,0 +1,452 @@
+// Package jobs 提供异步写入队列及其本地持久化日志
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"llm-cache/internal/eino/components"
+	einoconfig "llm-cache/internal/eino/config"
+	"llm-cache/internal/eino/flows"
+	"llm-cache/internal/eino/nodes"
+	"llm-cache/pkg/logger"
+)
+
+// 任务状态
+const (
+	StatusQueued    = "queued"    // 等待写入
+	StatusRunning   = "running"   // 正在写入（失败后回到 queued 等待重试）
+	StatusSucceeded = "succeeded" // 写入成功
+	StatusRejected  = "rejected"  // 被存储流程拒绝（质量检查、PII 等），不再重试
+	StatusFailed    = "failed"    // 非临时性错误，或达到最大尝试次数后放弃
+)
+
+// ErrQueueFull 队列已满，调用方应稍后重试或改用同步写入
+var ErrQueueFull = errors.New("store queue is full")
+
+// compactInterval 定期压缩日志（移除过期的已完成任务）的间隔
+const compactInterval = time.Hour
+
+// Job 定义一个异步写入任务的状态。
+type Job struct {
+	ID        string                  `json:"job_id"`
+	Status    string                  `json:"status"`
+	Attempts  int                     `json:"attempts"`
+	LastError string                  `json:"last_error,omitempty"`
+	Result    *flows.CacheStoreOutput `json:"result,omitempty"`
+	CreatedAt int64                   `json:"created_at"`
+	UpdatedAt int64                   `json:"updated_at"`
+}
+
+// StoreFunc 定义执行一次存储的函数（通常为 Store Graph 的 Invoke）。
+type StoreFunc func(ctx context.Context, input *flows.CacheStoreInput) (*flows.CacheStoreOutput, error)
+
+// StoreQueue 实现带本地持久化日志的异步写入队列（write-behind）。
+// 请求先追加到日志再进入内存队列，由 worker 调用存储流程；临时故障按指数退避重新调度（不占用 worker），
+// 被存储流程拒绝、非临时性错误或达到最大尝试次数时结束任务。重启后重放日志中未结束的任务。
+type StoreQueue struct {
+	cfg     *einoconfig.AsyncStoreConfig
+	store   StoreFunc
+	journal *Journal
+	logger  logger.Logger
+	pii     *nodes.PIIScanner
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	pending map[string]*record
+	queue   chan string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewStoreQueue 创建异步写入队列并重放日志。
+// 参数 cfg: 异步写入配置。
+// 参数 store: 存储函数。
+// 参数 log: 日志记录器。
+// 返回: StoreQueue 指针，日志无法打开或重放失败时返回错误。
+func NewStoreQueue(cfg *einoconfig.AsyncStoreConfig, store StoreFunc, log logger.Logger) (*StoreQueue, error) {
+	journal, err := OpenJournal(cfg.JournalDir)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &StoreQueue{
+		cfg:     cfg,
+		store:   store,
+		journal: journal,
+		logger:  log,
+		jobs:    make(map[string]*Job),
+		pending: make(map[string]*record),
+	}
+	if err := q.replay(); err != nil {
+		journal.Close()
+		return nil, err
+	}
+
+	q.queue = make(chan string, max(cfg.QueueSize, len(q.pending)))
+	for _, id := range q.pendingIDs() {
+		q.queue <- id
+	}
+	return q, nil
+}
+
+// WithPII 设置写入日志前的 PII 扫描（应与存储流程使用同一扫描器）。
+// 设置后日志中只保存脱敏后的问题和答案，需要拒绝的请求直接结束为 rejected，不写入日志。
+// 参数 scanner: PII 扫描器。
+// 返回: StoreQueue 指针，便于链式调用。
+func (q *StoreQueue) WithPII(scanner *nodes.PIIScanner) *StoreQueue {
+	q.pii = scanner
+	return q
+}
+
+// Start 启动写入 worker 和定期日志压缩
+func (q *StoreQueue) Start(ctx context.Context) {
+	q.ctx, q.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	workers := max(q.cfg.Workers, 1)
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+
+	q.wg.Add(1)
+	go q.compactLoop()
+
+	q.logger.InfoContext(ctx, "异步写入队列已启动",
+		"workers", workers,
+		"pending", len(q.queue),
+		"journal_dir", q.cfg.JournalDir)
+}
+
+// Enqueue 将存储请求追加到日志并加入队列。
+// 参数 input: 存储输入（包含认证后的来源）。
+// 返回: 新建任务的状态，队列已满时返回 ErrQueueFull。
+func (q *StoreQueue) Enqueue(input *flows.CacheStoreInput) (*Job, error) {
+	now := time.Now().Unix()
+	if q.pii != nil {
+		scanned, reason := flows.ScanStoreInput(q.pii, input)
+		if reason != "" {
+			return q.reject(reason, now)
+		}
+		input = scanned
+	}
+
+	rec := &record{
+		Op:     opEnqueue,
+		Job:    Job{ID: uuid.New().String(), Status: StatusQueued, CreatedAt: now, UpdatedAt: now},
+		Input:  input,
+		Source: input.Source,
+		Trust:  input.Trust,
+	}
+
+	// 所有向队列的发送都在持锁时进行，检查容量后发送不会阻塞
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.queue) >= cap(q.queue) {
+		return nil, ErrQueueFull
+	}
+	if err := q.journal.Append(rec); err != nil {
+		return nil, err
+	}
+
+	job := rec.Job
+	q.jobs[job.ID] = &job
+	q.pending[job.ID] = rec
+	q.queue <- job.ID
+	return &job, nil
+}
+
+// reject 记录一个未入队即被 PII 扫描拒绝的任务（日志中只写入结束记录，不包含存储输入）
+func (q *StoreQueue) reject(reason string, now int64) (*Job, error) {
+	job := Job{
+		ID:        uuid.New().String(),
+		Status:    StatusRejected,
+		Result:    &flows.CacheStoreOutput{Rejected: true, Reason: reason},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.journal.Append(&record{Op: opFinish, Job: job}); err != nil {
+		return nil, err
+	}
+	stored := job
+	q.jobs[job.ID] = &stored
+	return &job, nil
+}
+
+// Get 查询任务状态（返回副本）
+func (q *StoreQueue) Get(id string) (*Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *job
+	return &copied, true
+}
+
+// Close 停止 worker 并关闭日志。
+// 正在执行的写入被取消，未结束的任务保留在日志中，下次启动时重放。
+func (q *StoreQueue) Close() error {
+	if q.cancel != nil {
+		q.cancel()
+		q.wg.Wait()
+	}
+	return q.journal.Close()
+}
+
+// worker 从队列中取出任务并执行写入
+func (q *StoreQueue) worker() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case id := <-q.queue:
+			q.run(id)
+		}
+	}
+}
+
+// run 执行一次写入尝试。
+// 临时故障按退避间隔重新调度后立即返回，worker 不等待重试；非临时性错误直接结束任务。
+func (q *StoreQueue) run(id string) {
+	q.mu.Lock()
+	rec, ok := q.pending[id]
+	q.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	input := *rec.Input
+	input.Source, input.Trust = rec.Source, rec.Trust
+	attempts := q.update(id, func(job *Job) { job.Status = StatusRunning; job.Attempts++ })
+
+	result, err := q.store(q.ctx, &input)
+	if q.ctx.Err() != nil {
+		// 关闭队列导致的中断不计为失败，任务保留在日志中
+		q.update(id, func(job *Job) { job.Status = StatusQueued })
+		return
+	}
+	if err == nil {
+		status := StatusSucceeded
+		if result.Rejected {
+			status = StatusRejected
+		}
+		q.finish(id, status, result, "")
+		return
+	}
+
+	if !transientError(err) || (q.cfg.MaxAttempts > 0 && attempts >= q.cfg.MaxAttempts) {
+		q.finish(id, StatusFailed, nil, err.Error())
+		return
+	}
+
+	backoff := q.backoff(attempts)
+	q.update(id, func(job *Job) { job.Status = StatusQueued; job.LastError = err.Error() })
+	q.logger.WarnContext(q.ctx, "异步写入失败，等待重试",
+		"job_id", id,
+		"attempts", attempts,
+		"backoff", backoff.String(),
+		"error", err.Error())
+	q.schedule(id, backoff)
+}
+
+// schedule 在 delay 后将任务放回队列（队列已满时再等待一个间隔）
+func (q *StoreQueue) schedule(id string, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		if q.ctx.Err() != nil {
+			return
+		}
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		select {
+		case q.queue <- id:
+		default:
+			q.schedule(id, delay)
+		}
+	})
+}
+
+// backoff 返回第 attempts 次尝试失败后的退避间隔（从 InitialBackoff 开始翻倍，不超过 MaxBackoff）
+func (q *StoreQueue) backoff(attempts int) time.Duration {
+	backoff := q.cfg.InitialBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for i := 1; i < attempts && (q.cfg.MaxBackoff <= 0 || backoff < q.cfg.MaxBackoff); i++ {
+		backoff = nextBackoff(backoff, q.cfg.MaxBackoff)
+	}
+	return backoff
+}
+
+// update 修改任务状态并返回当前尝试次数
+func (q *StoreQueue) update(id string, fn func(job *Job)) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.jobs[id]
+	fn(job)
+	job.UpdatedAt = time.Now().Unix()
+	return job.Attempts
+}
+
+// finish 记录任务结束并从待执行集合中移除
+func (q *StoreQueue) finish(id, status string, result *flows.CacheStoreOutput, lastError string) {
+	q.mu.Lock()
+	job := q.jobs[id]
+	job.Status = status
+	job.Result = result
+	if lastError != "" {
+		job.LastError = lastError
+	}
+	job.UpdatedAt = time.Now().Unix()
+	finished := *job
+	delete(q.pending, id)
+	q.mu.Unlock()
+
+	if err := q.journal.Append(&record{Op: opFinish, Job: finished}); err != nil {
+		// 结束记录写入失败时，重启后任务会被重放（存储流程对同一问答对的重复写入是可接受的）
+		q.logger.ErrorContext(q.ctx, "异步写入任务结束记录写入失败", "job_id", id, "error", err.Error())
+	}
+	q.logger.InfoContext(q.ctx, "异步写入任务结束",
+		"job_id", id,
+		"status", status,
+		"attempts", finished.Attempts)
+}
+
+// replay 从日志恢复任务状态：未结束的任务重新入队，已结束的任务保留状态供查询
+func (q *StoreQueue) replay() error {
+	records, err := q.journal.Replay()
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		job := rec.Job
+		switch rec.Op {
+		case opEnqueue:
+			job.Status = StatusQueued
+			q.jobs[job.ID] = &job
+			q.pending[job.ID] = rec
+		case opFinish:
+			q.jobs[job.ID] = &job
+			delete(q.pending, job.ID)
+		}
+	}
+	return q.compact()
+}
+
+// compactLoop 定期压缩日志
+func (q *StoreQueue) compactLoop() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(compactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.compact(); err != nil {
+				q.logger.ErrorContext(q.ctx, "异步写入日志压缩失败", "error", err.Error())
+			}
+		}
+	}
+}
+
+// compact 移除过期的已完成任务，并用未结束任务和保留的已完成任务重写日志
+func (q *StoreQueue) compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	cutoff := time.Now().Add(-q.cfg.Retention).Unix()
+	records := make([]*record, 0, len(q.jobs))
+	for _, id := range q.pendingIDs() {
+		records = append(records, q.pending[id])
+	}
+	for id, job := range q.jobs {
+		if _, ok := q.pending[id]; ok {
+			continue
+		}
+		if q.cfg.Retention > 0 && job.UpdatedAt < cutoff {
+			delete(q.jobs, id)
+			continue
+		}
+		records = append(records, &record{Op: opFinish, Job: *job})
+	}
+	if err := q.journal.Rewrite(records); err != nil {
+		return fmt.Errorf("compact journal: %w", err)
+	}
+	return nil
+}
+
+// pendingIDs 按创建时间返回未结束任务的 ID（调用方需持有锁或在初始化时调用）
+func (q *StoreQueue) pendingIDs() []string {
+	ids := make([]string, 0, len(q.pending))
+	for id := range q.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := q.jobs[ids[i]], q.jobs[ids[j]]
+		if a.CreatedAt != b.CreatedAt {
+			return a.CreatedAt < b.CreatedAt
+		}
+		return a.ID < b.ID
+	})
+	return ids
+}
+
+// nextBackoff 计算下一次重试的退避间隔（翻倍，不超过上限）
+func nextBackoff(current, limit time.Duration) time.Duration {
+	next := current * 2
+	if limit > 0 && next > limit {
+		return limit
+	}
+	return next
+}
+
+// statusCodePattern 匹配错误信息中的 HTTP 状态码
+var statusCodePattern = regexp.MustCompile(`status code: (\d{3})`)
+
+// transientMessages 视为临时故障的错误信息片段（向量库和 Embedding 服务的连接、超时和限流错误）
+var transientMessages = []string{
+	"timeout", "deadline exceeded", "connection refused", "connection reset", "broken pipe",
+	"unavailable", "too many requests", "rate limit", "resource exhausted", "unexpected eof",
+}
+
+// transientError 判断写入失败是否为临时故障（连接、超时、限流、5xx、Embedding 提供商不可用）。
+// 其他错误（如输入无效、配置错误）重试也不会成功，任务直接结束为 failed。
+func transientError(err error) bool {
+	if errors.Is(err, components.ErrEmbeddingUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	if match := statusCodePattern.FindStringSubmatch(message); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code == 429 || code == 408 || code >= 500
+	}
+	for _, fragment := range transientMessages {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,327 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"llm-cache/internal/eino/components"
+	einoconfig "llm-cache/internal/eino/config"
+	"llm-cache/internal/eino/flows"
+	"llm-cache/internal/eino/nodes"
+	"llm-cache/pkg/logger"
+)
+
+// fakeStore 前 failures 次调用返回 err（默认为临时故障），之后返回 output；问题为 failQuestion 的输入总是失败
+type fakeStore struct {
+	mu           sync.Mutex
+	failures     int
+	err          error
+	failQuestion string
+	output       *flows.CacheStoreOutput
+	calls        int
+	inputs       []flows.CacheStoreInput
+}
+
+func (f *fakeStore) store(ctx context.Context, input *flows.CacheStoreInput) (*flows.CacheStoreOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	f.inputs = append(f.inputs, *input)
+	if f.calls <= f.failures || (f.failQuestion != "" && input.Question == f.failQuestion) {
+		if f.err != nil {
+			return nil, f.err
+		}
+		return nil, errors.New("indexer unavailable")
+	}
+	return f.output, nil
+}
+
+func testAsyncConfig(dir string) *einoconfig.AsyncStoreConfig {
+	return &einoconfig.AsyncStoreConfig{
+		Enabled:        true,
+		JournalDir:     dir,
+		Workers:        1,
+		QueueSize:      10,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Retention:      time.Hour,
+	}
+}
+
+// waitForStatus 等待任务进入结束状态
+func waitForStatus(t *testing.T, q *StoreQueue, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := q.Get(id)
+		if ok && job.Status != StatusQueued && job.Status != StatusRunning {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func TestStoreQueueRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		maxAttempts  int
+		output       *flows.CacheStoreOutput
+		wantStatus   string
+		wantAttempts int
+	}{
+		{"succeeds first time", 0, nil, 0, &flows.CacheStoreOutput{Success: true, CacheID: "c1"}, StatusSucceeded, 1},
+		{"retries until success", 3, nil, 0, &flows.CacheStoreOutput{Success: true, CacheID: "c1"}, StatusSucceeded, 4},
+		{"rejected is terminal", 0, nil, 0, &flows.CacheStoreOutput{Rejected: true, Reason: "low quality"}, StatusRejected, 1},
+		{"gives up after max attempts", 5, nil, 2, &flows.CacheStoreOutput{Success: true}, StatusFailed, 2},
+		{"permanent error is not retried", 5, errors.New("invalid metadata field"), 0, &flows.CacheStoreOutput{Success: true}, StatusFailed, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testAsyncConfig(t.TempDir())
+			cfg.MaxAttempts = tt.maxAttempts
+			fake := &fakeStore{failures: tt.failures, err: tt.err, output: tt.output}
+			q, err := NewStoreQueue(cfg, fake.store, logger.GetDefault())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			q.Start(context.Background())
+			defer q.Close()
+
+			job, err := q.Enqueue(&flows.CacheStoreInput{Question: "q", Answer: "a", UserType: "u"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if job.Status != StatusQueued {
+				t.Errorf("expected status %s, got %s", StatusQueued, job.Status)
+			}
+
+			done := waitForStatus(t, q, job.ID)
+			if done.Status != tt.wantStatus {
+				t.Errorf("expected status %s, got %s", tt.wantStatus, done.Status)
+			}
+			if done.Attempts != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, done.Attempts)
+			}
+			if tt.wantStatus == StatusFailed && done.LastError == "" {
+				t.Errorf("expected last error")
+			}
+			if tt.wantStatus != StatusFailed && done.Result != tt.output {
+				t.Errorf("expected result %v, got %v", tt.output, done.Result)
+			}
+		})
+	}
+}
+
+func TestStoreQueueRetryDoesNotBlockWorkers(t *testing.T) {
+	cfg := testAsyncConfig(t.TempDir())
+	cfg.InitialBackoff = time.Hour
+	fake := &fakeStore{failQuestion: "broken", output: &flows.CacheStoreOutput{Success: true, CacheID: "c1"}}
+	q, err := NewStoreQueue(cfg, fake.store, logger.GetDefault())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q.Start(context.Background())
+	defer q.Close()
+
+	failing, err := q.Enqueue(&flows.CacheStoreInput{Question: "broken", Answer: "a", UserType: "u"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ok, err := q.Enqueue(&flows.CacheStoreInput{Question: "q", Answer: "a", UserType: "u"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 唯一的 worker 不等待失败任务的退避间隔
+	if done := waitForStatus(t, q, ok.ID); done.Status != StatusSucceeded {
+		t.Errorf("expected status %s, got %s", StatusSucceeded, done.Status)
+	}
+	job, _ := q.Get(failing.ID)
+	if job.Status != StatusQueued || job.Attempts != 1 || job.LastError == "" {
+		t.Errorf("expected failing job to wait for retry, got %+v", job)
+	}
+}
+
+func TestStoreQueuePII(t *testing.T) {
+	dir := t.TempDir()
+	scanner, err := nodes.NewPIIScanner(&einoconfig.PIIConfig{
+		Enabled:       true,
+		DefaultAction: nodes.PIIActionMask,
+		Actions:       map[string]string{nodes.PIIIDCard: nodes.PIIActionReject},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fake := &fakeStore{output: &flows.CacheStoreOutput{Success: true, CacheID: "c1"}}
+	q, err := NewStoreQueue(testAsyncConfig(dir), fake.store, logger.GetDefault())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q.WithPII(scanner)
+	q.Start(context.Background())
+	defer q.Close()
+
+	// 需要拒绝的请求不入队，日志中不保存存储输入
+	rejected, err := q.Enqueue(&flows.CacheStoreInput{Question: "我的身份证号是多少", Answer: "11010519491231002X", UserType: "u"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rejected.Status != StatusRejected || rejected.Result == nil || !rejected.Result.Rejected {
+		t.Errorf("expected rejected job, got %+v", rejected)
+	}
+
+	masked, err := q.Enqueue(&flows.CacheStoreInput{Question: "如何联系客服", Answer: "发送邮件到 support@example.com", UserType: "u"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForStatus(t, q, masked.ID)
+
+	data, err := os.ReadFile(filepath.Join(dir, journalFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, raw := range []string{"11010519491231002X", "support@example.com"} {
+		if strings.Contains(string(data), raw) {
+			t.Errorf("expected journal not to contain %q", raw)
+		}
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.calls != 1 {
+		t.Fatalf("expected 1 store call, got %d", fake.calls)
+	}
+	if stored := fake.inputs[0]; strings.Contains(stored.Answer, "support@example.com") || stored.Metadata[nodes.MetaPIITypes] != nodes.PIIEmail {
+		t.Errorf("expected masked input, got %+v", stored)
+	}
+}
+
+func TestTransientError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("embedding: %w", components.ErrEmbeddingUnavailable), true},
+		{fmt.Errorf("store document: %w", context.DeadlineExceeded), true},
+		{errors.New("store document: rpc error: code = Unavailable desc = connection refused"), true},
+		{errors.New("embedding api returned status code: 503: overloaded"), true},
+		{errors.New("embedding api returned status code: 400: bad input"), false},
+		{errors.New("store document: invalid metadata field"), false},
+	}
+	for _, tt := range tests {
+		if got := transientError(tt.err); got != tt.want {
+			t.Errorf("%v: expected %v, got %v", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestStoreQueueReplay(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testAsyncConfig(dir)
+
+	// 不启动 worker，任务停留在日志中
+	q, err := NewStoreQueue(cfg, (&fakeStore{}).store, logger.GetDefault())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	input := &flows.CacheStoreInput{Question: "q", Answer: "a", UserType: "u", Source: "batch", Trust: "low"}
+	job, err := q.Enqueue(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 模拟崩溃时未写完的最后一行
+	file, err := os.OpenFile(filepath.Join(dir, journalFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file.WriteString(`{"op":"enqueue","job":{"job_id":"torn"`)
+	file.Close()
+
+	fake := &fakeStore{output: &flows.CacheStoreOutput{Success: true, CacheID: "c1"}}
+	q, err = NewStoreQueue(cfg, fake.store, logger.GetDefault())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := q.Get("torn"); ok {
+		t.Errorf("expected torn record to be ignored")
+	}
+	q.Start(context.Background())
+
+	done := waitForStatus(t, q, job.ID)
+	if done.Status != StatusSucceeded {
+		t.Errorf("expected status %s, got %s", StatusSucceeded, done.Status)
+	}
+	fake.mu.Lock()
+	replayed := fake.inputs[0]
+	fake.mu.Unlock()
+	if replayed.Source != input.Source || replayed.Trust != input.Trust {
+		t.Errorf("expected source %s/%s, got %s/%s", input.Source, input.Trust, replayed.Source, replayed.Trust)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 已结束的任务重启后不再执行，但仍可查询
+	fake = &fakeStore{}
+	q, err = NewStoreQueue(cfg, fake.store, logger.GetDefault())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer q.Close()
+	if len(q.pending) != 0 {
+		t.Errorf("expected no pending jobs, got %d", len(q.pending))
+	}
+	restored, ok := q.Get(job.ID)
+	if !ok || restored.Status != StatusSucceeded {
+		t.Errorf("expected finished job to be restored, got %v", restored)
+	}
+}
+
+func TestStoreQueueFull(t *testing.T) {
+	cfg := testAsyncConfig(t.TempDir())
+	cfg.QueueSize = 1
+	q, err := NewStoreQueue(cfg, (&fakeStore{}).store, logger.GetDefault())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer q.Close()
+
+	input := &flows.CacheStoreInput{Question: "q", Answer: "a", UserType: "u"}
+	if _, err := q.Enqueue(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := q.Enqueue(input); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected %v, got %v", ErrQueueFull, err)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	tests := []struct {
+		current, limit, want time.Duration
+	}{
+		{time.Second, time.Minute, 2 * time.Second},
+		{40 * time.Second, time.Minute, time.Minute},
+		{time.Second, 0, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := nextBackoff(tt.current, tt.limit); got != tt.want {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	einoconfig "llm-cache/internal/eino/config"
+	"llm-cache/internal/eino/nodes"
+	"llm-cache/pkg/logger"
+	"llm-cache/pkg/status"
+)
+
+// SourceKey 定义了认证后的存储来源在 Gin Context 中的存储键名。
+const SourceKey = "cache_source"
+
+// HMAC 签名认证使用的请求头
+const (
+	HeaderSource    = "X-Cache-Source"
+	HeaderTimestamp = "X-Cache-Timestamp"
+	HeaderSignature = "X-Cache-Signature"
+)
+
+// Source 定义认证后的存储来源。
+type Source struct {
+	ID    string
+	Trust string
+}
+
+// sourceAuth 保存来源凭证的查找表
+type sourceAuth struct {
+	sources        map[string]einoconfig.TrustedSource
+	allowAnonymous bool
+	maxSkew        time.Duration
+	logger         logger.Logger
+}
+
+// NewSourceAuth 创建存储来源认证中间件。
+// 支持两种认证方式：
+//   - Authorization: Bearer <token>，按来源配置的 Token 识别来源；
+//   - X-Cache-Source + X-Cache-Timestamp + X-Cache-Signature，签名为
+//     hex(HMAC-SHA256(secret, timestamp + "\n" + body))，时间戳为 Unix 秒。
+//
+// 认证失败返回 401；未携带凭证且允许匿名时视为低信任来源 anonymous。
+// 参数 cfg: 来源信任配置。
+// 参数 log: 日志记录器。
+// 返回: Gin 中间件，来源配置无效时返回错误。
+func NewSourceAuth(cfg *einoconfig.TrustConfig, log logger.Logger) (gin.HandlerFunc, error) {
+	a := &sourceAuth{
+		sources:        make(map[string]einoconfig.TrustedSource, len(cfg.Sources)),
+		allowAnonymous: cfg.AllowAnonymous,
+		maxSkew:        cfg.MaxClockSkew,
+		logger:         log,
+	}
+	for _, src := range cfg.Sources {
+		if src.ID == "" || src.ID == nodes.AnonymousSource || strings.Contains(src.ID, ",") {
+			return nil, fmt.Errorf("invalid trusted source id %q", src.ID)
+		}
+		if _, ok := a.sources[src.ID]; ok {
+			return nil, fmt.Errorf("duplicate trusted source %s", src.ID)
+		}
+		if src.Token == "" && src.Secret == "" {
+			return nil, fmt.Errorf("trusted source %s has neither token nor secret", src.ID)
+		}
+		switch src.Trust {
+		case "":
+			src.Trust = nodes.TrustLow
+		case nodes.TrustHigh, nodes.TrustLow:
+		default:
+			return nil, fmt.Errorf("trusted source %s has invalid trust %q", src.ID, src.Trust)
+		}
+		a.sources[src.ID] = src
+	}
+	return a.handle, nil
+}
+
+// GetSource 从 Gin 上下文中获取认证后的存储来源。
+// 如果未经过来源认证，返回 nil。
+func GetSource(c *gin.Context) *Source {
+	if source, exists := c.Get(SourceKey); exists {
+		return source.(*Source)
+	}
+	return nil
+}
+
+// Sign 计算请求体的 HMAC-SHA256 签名（供客户端和测试使用）。
+// 参数 secret: 来源的签名密钥。
+// 参数 timestamp: 请求时间戳（Unix 秒）。
+// 参数 body: 请求体。
+// 返回: 十六进制编码的签名。
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// handle 认证请求并将来源写入上下文
+func (a *sourceAuth) handle(c *gin.Context) {
+	source, err := a.authenticate(c)
+	if err != nil {
+		a.logger.WarnContext(c.Request.Context(), "存储来源认证失败",
+			"request_id", GetRequestID(c),
+			"source", c.GetHeader(HeaderSource),
+			"error", err.Error())
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success":    false,
+			"code":       int(status.ErrCodeUnauthorized),
+			"message":    "存储来源认证失败",
+			"data":       gin.H{"message": err.Error(), "code": status.ErrCodeUnauthorized.String()},
+			"request_id": GetRequestID(c),
+			"timestamp":  time.Now().Unix(),
+		})
+		return
+	}
+
+	c.Set(SourceKey, source)
+	c.Next()
+}
+
+// authenticate 按 Bearer Token、HMAC 签名、匿名的顺序识别来源
+func (a *sourceAuth) authenticate(c *gin.Context) (*Source, error) {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok {
+			return nil, fmt.Errorf("unsupported authorization scheme")
+		}
+		for _, src := range a.sources {
+			if src.Token != "" && subtle.ConstantTimeCompare([]byte(src.Token), []byte(token)) == 1 {
+				return &Source{ID: src.ID, Trust: src.Trust}, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	if id := c.GetHeader(HeaderSource); id != "" {
+		return a.verifySignature(c, id)
+	}
+
+	if a.allowAnonymous {
+		return &Source{ID: nodes.AnonymousSource, Trust: nodes.TrustLow}, nil
+	}
+	return nil, fmt.Errorf("missing credentials")
+}
+
+// verifySignature 验证 HMAC 签名和时间戳，并恢复请求体供后续处理器读取
+func (a *sourceAuth) verifySignature(c *gin.Context, id string) (*Source, error) {
+	src, ok := a.sources[id]
+	if !ok || src.Secret == "" {
+		return nil, fmt.Errorf("unknown source %q", id)
+	}
+
+	timestamp, err := strconv.ParseInt(c.GetHeader(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp")
+	}
+	if skew := time.Since(time.Unix(timestamp, 0)); a.maxSkew > 0 && (skew > a.maxSkew || skew < -a.maxSkew) {
+		return nil, fmt.Errorf("timestamp outside allowed skew")
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	expected := Sign(src.Secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(c.GetHeader(HeaderSignature)))) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	return &Source{ID: src.ID, Trust: src.Trust}, nil
+}
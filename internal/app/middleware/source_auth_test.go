@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	einoconfig "llm-cache/internal/eino/config"
+	"llm-cache/internal/eino/nodes"
+	"llm-cache/pkg/logger"
+)
+
+func TestSourceAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &einoconfig.TrustConfig{
+		Sources: []einoconfig.TrustedSource{
+			{ID: "gateway", Token: "gw-token", Trust: nodes.TrustHigh},
+			{ID: "batch", Secret: "batch-secret"},
+		},
+		MaxClockSkew: time.Minute,
+	}
+	body := `{"question":"q","answer":"a","user_type":"u"}`
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name           string
+		allowAnonymous bool
+		headers        map[string]string
+		wantCode       int
+		wantSource     string
+		wantTrust      string
+	}{
+		{"bearer token", false, map[string]string{"Authorization": "Bearer gw-token"}, http.StatusOK, "gateway", nodes.TrustHigh},
+		{"invalid token", false, map[string]string{"Authorization": "Bearer wrong"}, http.StatusUnauthorized, "", ""},
+		{"valid signature", false, map[string]string{
+			HeaderSource:    "batch",
+			HeaderTimestamp: strconv.FormatInt(now, 10),
+			HeaderSignature: Sign("batch-secret", now, []byte(body)),
+		}, http.StatusOK, "batch", nodes.TrustLow},
+		{"tampered body signature", false, map[string]string{
+			HeaderSource:    "batch",
+			HeaderTimestamp: strconv.FormatInt(now, 10),
+			HeaderSignature: Sign("batch-secret", now, []byte(body+" ")),
+		}, http.StatusUnauthorized, "", ""},
+		{"stale timestamp", false, map[string]string{
+			HeaderSource:    "batch",
+			HeaderTimestamp: strconv.FormatInt(now-3600, 10),
+			HeaderSignature: Sign("batch-secret", now-3600, []byte(body)),
+		}, http.StatusUnauthorized, "", ""},
+		{"token source cannot sign", false, map[string]string{
+			HeaderSource:    "gateway",
+			HeaderTimestamp: strconv.FormatInt(now, 10),
+			HeaderSignature: Sign("", now, []byte(body)),
+		}, http.StatusUnauthorized, "", ""},
+		{"missing credentials", false, nil, http.StatusUnauthorized, "", ""},
+		{"anonymous allowed", true, nil, http.StatusOK, nodes.AnonymousSource, nodes.TrustLow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.AllowAnonymous = tt.allowAnonymous
+			auth, err := NewSourceAuth(cfg, logger.GetDefault())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var source *Source
+			var received string
+			engine := gin.New()
+			engine.POST("/store", auth, func(c *gin.Context) {
+				source = GetSource(c)
+				data, _ := io.ReadAll(c.Request.Body)
+				received = string(data)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/store", strings.NewReader(body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %v, got %v", tt.wantCode, w.Code)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if source == nil || source.ID != tt.wantSource || source.Trust != tt.wantTrust {
+				t.Errorf("expected %v/%v, got %+v", tt.wantSource, tt.wantTrust, source)
+			}
+			if received != body {
+				t.Errorf("expected body %q, got %q", body, received)
+			}
+		})
+	}
+}
+
+func TestNewSourceAuthValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []einoconfig.TrustedSource
+	}{
+		{"missing id", []einoconfig.TrustedSource{{Token: "t"}}},
+		{"reserved id", []einoconfig.TrustedSource{{ID: nodes.AnonymousSource, Token: "t"}}},
+		{"duplicate id", []einoconfig.TrustedSource{{ID: "a", Token: "t"}, {ID: "a", Token: "u"}}},
+		{"no credentials", []einoconfig.TrustedSource{{ID: "a"}}},
+		{"invalid trust", []einoconfig.TrustedSource{{ID: "a", Token: "t", Trust: "admin"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSourceAuth(&einoconfig.TrustConfig{Sources: tt.sources}, logger.GetDefault()); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
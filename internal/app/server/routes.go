@@ -12,8 +12,9 @@ import (
 // 它负责加载中间件，定义 API 版本分组，并将 URL 路径映射到相应的处理函数。
 // 参数 engine: Gin 引擎实例。
 // 参数 cacheHandler: 业务逻辑处理器。
+// 参数 storeAuth: 存储接口的来源认证中间件（为 nil 时不认证）。
 // 参数 log: 日志记录器。
-func SetupRoutes(engine *gin.Engine, cacheHandler *handlers.CacheHandler, log logger.Logger) {
+func SetupRoutes(engine *gin.Engine, cacheHandler *handlers.CacheHandler, storeAuth gin.HandlerFunc, log logger.Logger) {
 	// 应用全局中间件
 	setupMiddleware(engine, log)
 
@@ -24,8 +25,18 @@ func SetupRoutes(engine *gin.Engine, cacheHandler *handlers.CacheHandler, log lo
 
 	// 查询缓存 - POST方法，支持复杂查询条件
 	cache.POST("/search", cacheHandler.QueryCache)
-	// 存储缓存 - 将问答对存入语义缓存
-	cache.POST("/store", cacheHandler.StoreCache)
+	// 存储缓存 - 将问答对存入语义缓存（启用来源信任时先认证存储来源）
+	if storeAuth != nil {
+		cache.POST("/store", storeAuth, cacheHandler.StoreCache)
+	} else {
+		cache.POST("/store", cacheHandler.StoreCache)
+	}
+	// 质量检查试运行 - 仅执行存储流程的质量检查，不写入缓存
+	cache.POST("/quality/evaluate", cacheHandler.EvaluateQuality)
+	// 批量质量检查试运行 - 按拒绝项汇总
+	cache.POST("/quality/evaluate/batch", cacheHandler.EvaluateQualityBatch)
+	// 查询异步写入任务状态 - 异步写入时存储接口返回 202 和任务ID
+	cache.GET("/jobs/:job_id", cacheHandler.GetJob)
 	// 根据ID获取缓存项 - 支持查询参数：user_type, include_statistics
 	cache.GET("/:cache_id", cacheHandler.GetCacheByID)
 	// 删除单个缓存项 - 支持查询参数：user_type, force
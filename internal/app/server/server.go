@@ -18,6 +18,7 @@ type Server struct {
 	httpServer   *http.Server           // HTTP服务器实例
 	engine       *gin.Engine            // Gin引擎
 	cacheHandler *handlers.CacheHandler // 缓存处理器
+	storeAuth    gin.HandlerFunc        // 存储来源认证中间件（可选）
 	logger       logger.Logger          // 日志器
 }
 
@@ -46,13 +47,21 @@ func NewServer(config *configs.ServerConfig, cacheHandler *handlers.CacheHandler
 	}
 }
 
+// WithStoreAuth 设置存储接口的来源认证中间件。
+// 参数 auth: 来源认证中间件，为 nil 时存储接口不做认证。
+// 返回: Server 指针，便于链式调用。
+func (s *Server) WithStoreAuth(auth gin.HandlerFunc) *Server {
+	s.storeAuth = auth
+	return s
+}
+
 // Start 启动 HTTP 服务器并开始监听请求（异步执行）。
 // 它会在后台 goroutine 中运行 ListenAndServe。
 // 参数 ctx: 上下文对象。
 // 参数 errChan: 用于接收服务器运行时错误的通道。调用者应监听此通道。
 func (s *Server) Start(ctx context.Context, errChan chan<- error) {
 	// 设置路由
-	SetupRoutes(s.engine, s.cacheHandler, s.logger)
+	SetupRoutes(s.engine, s.cacheHandler, s.storeAuth, s.logger)
 
 	// 创建HTTP服务器
 	s.httpServer = &http.Server{
@@ -0,0 +1,82 @@
+// Package components 提供 Eino 组件的工厂函数
+package components
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	openaiacl "github.com/cloudwego/eino-ext/libs/acl/openai"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// NewChatModel 根据配置创建并返回一个 Eino ChatModel 实例。
+// 支持 openai（OpenAI 兼容接口，含 Azure）和 stub（本地桩，不发起网络请求）两种提供商。
+// 参数 ctx: 上下文对象。
+// 参数 cfg: ChatModel 配置。
+// 返回: 初始化后的 ChatModel 实例，如果提供商不支持或初始化失败则返回错误。
+func NewChatModel(ctx context.Context, cfg *config.ChatModelConfig) (model.BaseChatModel, error) {
+	switch cfg.Provider {
+	case "openai":
+		return newOpenAIChatModel(ctx, cfg)
+	case "stub":
+		return &stubChatModel{response: cfg.StubResponse}, nil
+	default:
+		return nil, fmt.Errorf("unsupported chat model provider: %s", cfg.Provider)
+	}
+}
+
+// newOpenAIChatModel 创建 OpenAI 兼容的 ChatModel
+func newOpenAIChatModel(ctx context.Context, cfg *config.ChatModelConfig) (model.BaseChatModel, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("chat model is required")
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return openaiacl.NewClient(ctx, &openaiacl.Config{
+		APIKey:      cfg.APIKey,
+		BaseURL:     cfg.BaseURL,
+		ByAzure:     cfg.ByAzure,
+		APIVersion:  cfg.APIVersion,
+		Model:       cfg.Model,
+		Temperature: cfg.Temperature,
+		MaxTokens:   cfg.MaxTokens,
+		HTTPClient:  &http.Client{Timeout: timeout},
+	})
+}
+
+// stubChatModel 本地桩 ChatModel（用于测试和离线调试）。
+// 返回固定回复；未配置固定回复时回显最后一条用户消息。
+type stubChatModel struct {
+	response string
+}
+
+// Generate 返回桩回复
+func (m *stubChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if m.response != "" {
+		return schema.AssistantMessage(m.response, nil), nil
+	}
+	for i := len(input) - 1; i >= 0; i-- {
+		if input[i].Role == schema.User {
+			return schema.AssistantMessage(input[i].Content, nil), nil
+		}
+	}
+	return schema.AssistantMessage("", nil), nil
+}
+
+// Stream 以单个分片的流返回桩回复
+func (m *stubChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
@@ -0,0 +1,124 @@
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// newChatCompletionStub 创建模拟 OpenAI Chat Completions API 的本地服务，回复固定内容
+func newChatCompletionStub(t *testing.T, reply string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"model":   req.Model,
+			"choices": []map[string]any{{"index": 0, "finish_reason": "stop", "message": map[string]any{"role": "assistant", "content": reply}}},
+			"usage":   map[string]any{"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2},
+		})
+	}))
+}
+
+func TestOpenAIChatModel(t *testing.T) {
+	server := newChatCompletionStub(t, "adapted answer")
+	defer server.Close()
+
+	chatModel, err := NewChatModel(context.Background(), &config.ChatModelConfig{
+		Provider: "openai",
+		BaseURL:  server.URL,
+		APIKey:   "test-key",
+		Model:    "gpt-test",
+		Timeout:  5,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg, err := chatModel.Generate(context.Background(), []*schema.Message{schema.UserMessage("hello")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Content != "adapted answer" {
+		t.Errorf("expected %q, got %q", "adapted answer", msg.Content)
+	}
+}
+
+func TestStubChatModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected string
+	}{
+		{"fixed response", "fixed", "fixed"},
+		{"echo last user message", "", "second"},
+	}
+
+	input := []*schema.Message{
+		schema.SystemMessage("system"),
+		schema.UserMessage("first"),
+		schema.AssistantMessage("reply", nil),
+		schema.UserMessage("second"),
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatModel, err := NewChatModel(context.Background(), &config.ChatModelConfig{Provider: "stub", StubResponse: tt.response})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			msg, err := chatModel.Generate(context.Background(), input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msg.Content != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, msg.Content)
+			}
+		})
+	}
+}
+
+func TestNewChatModelErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.ChatModelConfig
+	}{
+		{"unsupported provider", &config.ChatModelConfig{Provider: "unknown"}},
+		{"openai without model", &config.ChatModelConfig{Provider: "openai"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewChatModel(context.Background(), tt.cfg); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
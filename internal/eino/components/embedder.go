@@ -4,6 +4,8 @@ package components
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	openaiembed "github.com/cloudwego/eino-ext/components/embedding/openai"
@@ -16,8 +18,53 @@ import (
 // 支持 OpenAI, ARK, Ollama, Dashscope, Qianfan, Tencentcloud 等多种提供商。
 // 参数 ctx: 上下文对象。
 // 参数 cfg: Embedder 配置，包含提供商类型、API 密钥、模型名称等。
+// 按配置依次包装重试与故障转移（cfg.Resilience）、合批（cfg.Batching）和向量结果缓存（cfg.Cache），
+// 缓存命中的文本不进入合批窗口，一个批次只经过一次重试与故障转移。
 // 返回: 初始化后的 Embedder 实例，如果提供商不支持或初始化失败则返回错误。
 func NewEmbedder(ctx context.Context, cfg *config.EmbedderConfig) (embedding.Embedder, error) {
+	embedder, err := newProviderEmbedder(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Resilience.Enabled {
+		if embedder, err = NewResilientEmbedder(ctx, embedder, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Batching.Enabled {
+		embedder = NewBatchingEmbedder(embedder, &cfg.Batching)
+	}
+	if !cfg.Cache.Enabled {
+		return embedder, nil
+	}
+	return NewCachedEmbedder(ctx, embedder, cfg)
+}
+
+// EmbedderStatistics 收集 Embedder 包装链中各层的指标采集函数。
+// 参数 embedder: NewEmbedder 返回的 Embedder。
+// 返回: 指标名到采集函数的 Map（embedding_cache、embedding_batching、embedding_providers）。
+func EmbedderStatistics(embedder embedding.Embedder) map[string]func() map[string]interface{} {
+	statistics := make(map[string]func() map[string]interface{})
+	for embedder != nil {
+		switch e := embedder.(type) {
+		case *CachedEmbedder:
+			statistics["embedding_cache"] = e.GetMetrics
+		case *BatchingEmbedder:
+			statistics["embedding_batching"] = e.GetMetrics
+		case *ResilientEmbedder:
+			statistics["embedding_providers"] = e.GetMetrics
+		}
+		unwrapper, ok := embedder.(interface{ Unwrap() embedding.Embedder })
+		if !ok {
+			break
+		}
+		embedder = unwrapper.Unwrap()
+	}
+	return statistics
+}
+
+// newProviderEmbedder 根据提供商类型创建 Embedder
+func newProviderEmbedder(ctx context.Context, cfg *config.EmbedderConfig) (embedding.Embedder, error) {
 	timeout := time.Duration(cfg.Timeout) * time.Second
 
 	switch cfg.Provider {
@@ -30,9 +77,9 @@ func NewEmbedder(ctx context.Context, cfg *config.EmbedderConfig) (embedding.Emb
 	case "dashscope":
 		return newDashscopeEmbedder(ctx, cfg, timeout)
 	case "qianfan":
-		return newQianfanEmbedder(ctx, cfg)
+		return newQianfanEmbedder(ctx, cfg, timeout)
 	case "tencentcloud":
-		return newTencentcloudEmbedder(ctx, cfg)
+		return newTencentcloudEmbedder(ctx, cfg, timeout)
 	default:
 		return nil, fmt.Errorf("unsupported embedding provider: %s", cfg.Provider)
 	}
@@ -65,92 +112,136 @@ func newOpenAIEmbedder(ctx context.Context, cfg *config.EmbedderConfig, timeout
 	return openaiembed.NewEmbedder(ctx, embedCfg)
 }
 
-// newARKEmbedder 创建 ARK (火山引擎) Embedder
-// 注意：需要添加 github.com/cloudwego/eino-ext/components/embedding/ark 依赖
+// defaultEmbeddingHTTPTimeout 未配置 timeout 时 HTTP 提供商的请求超时
+const defaultEmbeddingHTTPTimeout = 30 * time.Second
+
+// 各提供商单次请求的文本数上限（超过时分批调用）
+const (
+	dashscopeMaxBatchSize    = 10
+	qianfanMaxBatchSize      = 16
+	tencentcloudMaxBatchSize = 200
+)
+
+// newARKEmbedder 创建 ARK (火山引擎方舟) Embedder。
+// 使用 api_key 鉴权；未配置 api_key 时使用 access_key/secret_key 签名获取接入点（model 为接入点 ID）的临时 API Key。
 func newARKEmbedder(ctx context.Context, cfg *config.EmbedderConfig, timeout time.Duration) (embedding.Embedder, error) {
-	// ARK Embedder 需要额外导入，这里返回错误提示
-	// 如果需要使用 ARK，请添加对应依赖并取消注释以下代码
-	/*
-		import arkembed "github.com/cloudwego/eino-ext/components/embedding/ark"
-
-		return arkembed.NewEmbedder(ctx, &arkembed.EmbeddingConfig{
-			APIKey:     cfg.APIKey,
-			AccessKey:  cfg.AccessKey,
-			SecretKey:  cfg.SecretKey,
-			Model:      cfg.Model,
-			BaseURL:    cfg.BaseURL,
-			Region:     cfg.Region,
-			Timeout:    &timeout,
-			RetryTimes: cfg.RetryTimes,
-		})
-	*/
-	return nil, fmt.Errorf("ARK embedding provider is not enabled. Please add github.com/cloudwego/eino-ext/components/embedding/ark dependency")
+	region := cfg.Region
+	if region == "" {
+		region = "cn-beijing"
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://ark.%s.volces.com/api/v3", region)
+	}
+
+	client := newEmbeddingHTTPClient(timeout)
+	embedder := &compatibleEmbedder{
+		client:     client,
+		endpoint:   strings.TrimRight(baseURL, "/") + "/embeddings",
+		model:      cfg.Model,
+		dimensions: cfg.Dimensions,
+	}
+	switch {
+	case cfg.APIKey != "":
+		embedder.authorize = bearerAuthorizer(cfg.APIKey)
+	case cfg.AccessKey != "" && cfg.SecretKey != "":
+		embedder.authorize = newARKKeySource(client, arkOpenAPIURL, cfg.AccessKey, cfg.SecretKey, region, cfg.Model).authorize
+	default:
+		return nil, fmt.Errorf("ark embedding provider requires api_key or access_key/secret_key")
+	}
+	return embedder, nil
 }
 
-// newOllamaEmbedder 创建 Ollama Embedder
-// 注意：需要添加 github.com/cloudwego/eino-ext/components/embedding/ollama 依赖
+// newOllamaEmbedder 创建 Ollama Embedder（base_url 默认为 http://localhost:11434，api_key 可选，用于带鉴权的反向代理）
 func newOllamaEmbedder(ctx context.Context, cfg *config.EmbedderConfig, timeout time.Duration) (embedding.Embedder, error) {
-	// Ollama Embedder 需要额外导入
-	/*
-		import ollamaembed "github.com/cloudwego/eino-ext/components/embedding/ollama"
-
-		return ollamaembed.NewEmbedder(ctx, &ollamaembed.EmbeddingConfig{
-			BaseURL: cfg.BaseURL,
-			Model:   cfg.Model,
-			Timeout: timeout,
-		})
-	*/
-	return nil, fmt.Errorf("Ollama embedding provider is not enabled. Please add github.com/cloudwego/eino-ext/components/embedding/ollama dependency")
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	return &ollamaEmbedder{
+		client:     newEmbeddingHTTPClient(timeout),
+		endpoint:   strings.TrimRight(baseURL, "/") + "/api/embed",
+		model:      cfg.Model,
+		dimensions: cfg.Dimensions,
+		authorize:  bearerAuthorizer(cfg.APIKey),
+	}, nil
 }
 
-// newDashscopeEmbedder 创建 Dashscope (阿里云) Embedder
-// 注意：需要添加 github.com/cloudwego/eino-ext/components/embedding/dashscope 依赖
+// newDashscopeEmbedder 创建 Dashscope (阿里云百炼) Embedder。
+// 使用 compatible-mode 接口，region 为 intl 或 ap-* 时使用国际站地址。
 func newDashscopeEmbedder(ctx context.Context, cfg *config.EmbedderConfig, timeout time.Duration) (embedding.Embedder, error) {
-	// Dashscope Embedder 需要额外导入
-	/*
-		import dashscopeembed "github.com/cloudwego/eino-ext/components/embedding/dashscope"
-
-		return dashscopeembed.NewEmbedder(ctx, &dashscopeembed.EmbeddingConfig{
-			APIKey:     cfg.APIKey,
-			Model:      cfg.Model,
-			Timeout:    timeout,
-			Dimensions: cfg.Dimensions,
-		})
-	*/
-	return nil, fmt.Errorf("Dashscope embedding provider is not enabled. Please add github.com/cloudwego/eino-ext/components/embedding/dashscope dependency")
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("dashscope embedding provider requires api_key")
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = "https://dashscope.aliyuncs.com/compatible-mode/v1"
+		if cfg.Region == "intl" || strings.HasPrefix(cfg.Region, "ap-") {
+			baseURL = "https://dashscope-intl.aliyuncs.com/compatible-mode/v1"
+		}
+	}
+	return &compatibleEmbedder{
+		client:     newEmbeddingHTTPClient(timeout),
+		endpoint:   strings.TrimRight(baseURL, "/") + "/embeddings",
+		model:      cfg.Model,
+		dimensions: cfg.Dimensions,
+		batchSize:  dashscopeMaxBatchSize,
+		authorize:  bearerAuthorizer(cfg.APIKey),
+	}, nil
 }
 
-// newQianfanEmbedder 创建 Qianfan (百度千帆) Embedder
-// 注意：需要添加 github.com/cloudwego/eino-ext/components/embedding/qianfan 依赖
-func newQianfanEmbedder(ctx context.Context, cfg *config.EmbedderConfig) (embedding.Embedder, error) {
-	// Qianfan Embedder 需要额外导入
-	/*
-		import qianfanembed "github.com/cloudwego/eino-ext/components/embedding/qianfan"
-
-		// Qianfan 使用单例配置
-		qcfg := qianfanembed.GetQianfanSingletonConfig()
-		qcfg.AccessKey = cfg.AccessKey
-		qcfg.SecretKey = cfg.SecretKey
-
-		return qianfanembed.NewEmbedder(ctx, &qianfanembed.EmbeddingConfig{
-			Model: cfg.Model,
-		})
-	*/
-	return nil, fmt.Errorf("Qianfan embedding provider is not enabled. Please add github.com/cloudwego/eino-ext/components/embedding/qianfan dependency")
+// newQianfanEmbedder 创建 Qianfan (百度千帆) Embedder。
+// 使用 v2 接口；使用 api_key 鉴权，未配置 api_key 时使用 access_key/secret_key 进行 bce-auth-v1 签名。
+func newQianfanEmbedder(ctx context.Context, cfg *config.EmbedderConfig, timeout time.Duration) (embedding.Embedder, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = "https://qianfan.baidubce.com/v2"
+	}
+	embedder := &compatibleEmbedder{
+		client:     newEmbeddingHTTPClient(timeout),
+		endpoint:   strings.TrimRight(baseURL, "/") + "/embeddings",
+		model:      cfg.Model,
+		dimensions: cfg.Dimensions,
+		batchSize:  qianfanMaxBatchSize,
+	}
+	switch {
+	case cfg.APIKey != "":
+		embedder.authorize = bearerAuthorizer(cfg.APIKey)
+	case cfg.AccessKey != "" && cfg.SecretKey != "":
+		embedder.authorize = bceAuthorizer(cfg.AccessKey, cfg.SecretKey)
+	default:
+		return nil, fmt.Errorf("qianfan embedding provider requires api_key or access_key/secret_key")
+	}
+	return embedder, nil
 }
 
-// newTencentcloudEmbedder 创建 Tencentcloud (腾讯云) Embedder
-// 注意：需要添加 github.com/cloudwego/eino-ext/components/embedding/tencentcloud 依赖
-func newTencentcloudEmbedder(ctx context.Context, cfg *config.EmbedderConfig) (embedding.Embedder, error) {
-	// Tencentcloud Embedder 需要额外导入
-	/*
-		import tencentcloudembed "github.com/cloudwego/eino-ext/components/embedding/tencentcloud"
-
-		return tencentcloudembed.NewEmbedder(ctx, &tencentcloudembed.EmbeddingConfig{
-			SecretID:  cfg.SecretID,
-			SecretKey: cfg.SecretKey,
-			Region:    cfg.Region,
-		})
-	*/
-	return nil, fmt.Errorf("Tencentcloud embedding provider is not enabled. Please add github.com/cloudwego/eino-ext/components/embedding/tencentcloud dependency")
+// newTencentcloudEmbedder 创建 Tencentcloud (腾讯云混元) Embedder，使用 secret_id/secret_key 进行 TC3-HMAC-SHA256 签名
+func newTencentcloudEmbedder(ctx context.Context, cfg *config.EmbedderConfig, timeout time.Duration) (embedding.Embedder, error) {
+	if cfg.SecretID == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("tencentcloud embedding provider requires secret_id and secret_key")
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = tencentcloudEmbeddingURL
+	}
+	return &tencentcloudEmbedder{
+		client:     newEmbeddingHTTPClient(timeout),
+		endpoint:   strings.TrimRight(baseURL, "/") + "/",
+		region:     cfg.Region,
+		dimensions: cfg.Dimensions,
+		batchSize:  tencentcloudMaxBatchSize,
+		signer: &tc3Signer{
+			secretID:  cfg.SecretID,
+			secretKey: cfg.SecretKey,
+			service:   tencentcloudEmbeddingService,
+		},
+	}, nil
+}
+
+// newEmbeddingHTTPClient 创建 HTTP 提供商使用的客户端（timeout 不大于 0 时使用默认超时）
+func newEmbeddingHTTPClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = defaultEmbeddingHTTPTimeout
+	}
+	return &http.Client{Timeout: timeout}
 }
@@ -0,0 +1,273 @@
+// Package components 提供 Eino 组件的工厂函数
+package components
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/volcengine/volc-sdk-golang/base"
+)
+
+// ARK OpenAPI 临时 API Key 配置
+const (
+	arkOpenAPIURL       = "https://open.volcengineapi.com"
+	arkOpenAPIVersion   = "2024-01-01"
+	arkAPIKeyDuration   = 7 * 24 * time.Hour
+	arkAPIKeyRefreshGap = time.Hour
+)
+
+// 腾讯云混元 GetEmbedding 接口配置
+const (
+	tencentcloudEmbeddingURL     = "https://hunyuan.tencentcloudapi.com"
+	tencentcloudEmbeddingService = "hunyuan"
+	tencentcloudEmbeddingAction  = "GetEmbedding"
+	tencentcloudEmbeddingVersion = "2023-09-01"
+)
+
+// bceAuthExpiration 千帆 bce-auth-v1 签名的有效期（秒）
+const bceAuthExpiration = 1800
+
+// arkKeySource 使用 AK/SK 调用 ARK OpenAPI GetApiKey 获取推理接入点的临时 API Key，过期前自动刷新
+type arkKeySource struct {
+	client      *http.Client
+	endpoint    string
+	credentials base.Credentials
+	resourceID  string
+
+	mu        sync.Mutex
+	apiKey    string
+	expiresAt time.Time
+}
+
+// arkGetAPIKeyResponse GetApiKey 响应体
+type arkGetAPIKeyResponse struct {
+	ResponseMetadata struct {
+		RequestID string `json:"RequestId"`
+		Error     *struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+	} `json:"ResponseMetadata"`
+	Result struct {
+		APIKey      string `json:"ApiKey"`
+		ExpiredTime int64  `json:"ExpiredTime"`
+	} `json:"Result"`
+}
+
+// newARKKeySource 创建 ARK 临时 API Key 来源
+func newARKKeySource(client *http.Client, endpoint, accessKey, secretKey, region, resourceID string) *arkKeySource {
+	return &arkKeySource{
+		client:   client,
+		endpoint: endpoint,
+		credentials: base.Credentials{
+			AccessKeyID:     accessKey,
+			SecretAccessKey: secretKey,
+			Service:         "ark",
+			Region:          region,
+		},
+		resourceID: resourceID,
+	}
+}
+
+// authorize 使用临时 API Key 作为 Bearer Token
+func (s *arkKeySource) authorize(ctx context.Context, req *http.Request, body []byte) error {
+	apiKey, err := s.key(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	return nil
+}
+
+// key 返回未过期的临时 API Key，临近过期时重新获取
+func (s *arkKeySource) key(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.apiKey != "" && time.Until(s.expiresAt) > arkAPIKeyRefreshGap {
+		return s.apiKey, nil
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"DurationSeconds": int64(arkAPIKeyDuration.Seconds()),
+		"ResourceType":    "endpoint",
+		"ResourceIds":     []string{s.resourceID},
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal ark get api key request: %w", err)
+	}
+	query := url.Values{"Action": {"GetApiKey"}, "Version": {arkOpenAPIVersion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("create ark get api key request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.credentials.Sign(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("call ark get api key: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read ark get api key response: %w", err)
+	}
+
+	var result arkGetAPIKeyResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("ark get api key returned status code: %d: %s", resp.StatusCode, string(respBody))
+	}
+	if apiErr := result.ResponseMetadata.Error; apiErr != nil {
+		return "", fmt.Errorf("ark get api key error %s: %s (request_id=%s)", apiErr.Code, apiErr.Message, result.ResponseMetadata.RequestID)
+	}
+	if resp.StatusCode != http.StatusOK || result.Result.APIKey == "" {
+		return "", fmt.Errorf("ark get api key returned status code: %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	s.apiKey = result.Result.APIKey
+	s.expiresAt = time.Unix(result.Result.ExpiredTime, 0)
+	return s.apiKey, nil
+}
+
+// bceAuthorizer 返回使用百度智能云 bce-auth-v1 签名（AK/SK）的 requestAuthorizer
+func bceAuthorizer(accessKey, secretKey string) requestAuthorizer {
+	return func(ctx context.Context, req *http.Request, body []byte) error {
+		timestamp := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+		req.Header.Set("X-Bce-Date", timestamp)
+		req.Header.Set("Authorization", bceAuthorization(req, accessKey, secretKey, timestamp))
+		return nil
+	}
+}
+
+// bceAuthorization 计算 bce-auth-v1 Authorization 头（签名 host 和 x-bce-date）
+func bceAuthorization(req *http.Request, accessKey, secretKey, timestamp string) string {
+	authPrefix := fmt.Sprintf("bce-auth-v1/%s/%s/%d", accessKey, timestamp, bceAuthExpiration)
+	signingKey := hex.EncodeToString(hmacSHA256([]byte(secretKey), authPrefix))
+
+	// 规范请求：METHOD\nURI\nQUERY\nHEADERS
+	headers := map[string]string{
+		"host":       req.URL.Host,
+		"x-bce-date": timestamp,
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := make([]string, len(names))
+	for i, name := range names {
+		canonicalHeaders[i] = name + ":" + bceURIEncode(strings.TrimSpace(headers[name]), true)
+	}
+
+	query := req.URL.Query()
+	queryKeys := make([]string, 0, len(query))
+	for key := range query {
+		queryKeys = append(queryKeys, key)
+	}
+	sort.Strings(queryKeys)
+	canonicalQuery := make([]string, 0, len(queryKeys))
+	for _, key := range queryKeys {
+		canonicalQuery = append(canonicalQuery, bceURIEncode(key, true)+"="+bceURIEncode(query.Get(key), true))
+	}
+
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		bceURIEncode(path, false),
+		strings.Join(canonicalQuery, "&"),
+		strings.Join(canonicalHeaders, "\n"),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256([]byte(signingKey), canonicalRequest))
+	return authPrefix + "/" + strings.Join(names, ";") + "/" + signature
+}
+
+// bceURIEncode 按 RFC 3986 编码（保留非保留字符，encodeSlash 为 false 时保留 /）
+func bceURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// tc3Signer 计算腾讯云 API 3.0 的 TC3-HMAC-SHA256 签名
+type tc3Signer struct {
+	secretID  string
+	secretKey string
+	service   string
+}
+
+// authorization 计算 Authorization 头（签名 content-type、host 和 x-tc-action）
+func (s *tc3Signer) authorization(req *http.Request, body []byte, timestamp int64) string {
+	date := time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+	signedHeaders := "content-type;host;x-tc-action"
+	canonicalHeaders := "content-type:" + req.Header.Get("Content-Type") + "\n" +
+		"host:" + req.URL.Host + "\n" +
+		"x-tc-action:" + strings.ToLower(req.Header.Get("X-TC-Action")) + "\n"
+
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	credentialScope := date + "/" + s.service + "/tc3_request"
+	stringToSign := strings.Join([]string{
+		"TC3-HMAC-SHA256",
+		fmt.Sprintf("%d", timestamp),
+		credentialScope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	secretDate := hmacSHA256([]byte("TC3"+s.secretKey), date)
+	secretService := hmacSHA256(secretDate, s.service)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	return fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.secretID, credentialScope, signedHeaders, signature)
+}
+
+// hmacSHA256 计算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sha256Hex 计算 SHA-256 的十六进制摘要
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,206 @@
+// Package components 提供 Eino 组件的工厂函数
+package components
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+
+	"llm-cache/internal/eino/config"
+)
+
+// BatchingEmbedder 将并发的 Embedding 请求合并为一次下层调用。
+// 批次中第一个请求到达后开始计时，时间窗口结束或文本数达到上限时发送；
+// 每个调用方得到自己的向量，下层错误返回给批次中的所有调用方。
+// 调用方取消时立即返回，只有批次中所有调用方都取消时才取消下层调用。
+// 携带 Option 的请求和超过批次上限的请求直接调用下层 Embedder。
+type BatchingEmbedder struct {
+	embedder     embedding.Embedder
+	window       time.Duration
+	maxBatchSize int
+
+	mu      sync.Mutex
+	pending *embeddingBatch
+
+	requests    atomic.Int64
+	batches     atomic.Int64
+	batchedText atomic.Int64
+}
+
+// embeddingBatch 表示正在收集的批次
+type embeddingBatch struct {
+	requests []*embeddingRequest
+	size     int
+	timer    *time.Timer
+}
+
+// embeddingRequest 表示批次中一个调用方的请求
+type embeddingRequest struct {
+	ctx    context.Context
+	texts  []string
+	result chan embeddingResult
+}
+
+// embeddingResult 表示返回给调用方的结果
+type embeddingResult struct {
+	vectors [][]float64
+	err     error
+}
+
+// NewBatchingEmbedder 创建合批 Embedder。
+// 参数 embedder: 下层 Embedder。
+// 参数 cfg: 合批配置（窗口不大于 0 时使用 10ms，批次上限不大于 0 时使用 32）。
+// 返回: BatchingEmbedder 指针。
+func NewBatchingEmbedder(embedder embedding.Embedder, cfg *config.EmbeddingBatchingConfig) *BatchingEmbedder {
+	window := cfg.Window
+	if window <= 0 {
+		window = 10 * time.Millisecond
+	}
+	maxBatchSize := cfg.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = 32
+	}
+	return &BatchingEmbedder{
+		embedder:     embedder,
+		window:       window,
+		maxBatchSize: maxBatchSize,
+	}
+}
+
+// EmbedStrings 将请求加入当前批次并等待结果。
+func (e *BatchingEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	if len(texts) == 0 {
+		return [][]float64{}, nil
+	}
+	if len(opts) > 0 || len(texts) >= e.maxBatchSize {
+		return e.embedder.EmbedStrings(ctx, texts, opts...)
+	}
+	e.requests.Add(1)
+
+	req := &embeddingRequest{ctx: ctx, texts: texts, result: make(chan embeddingResult, 1)}
+	e.enqueue(req)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-req.result:
+		return res.vectors, res.err
+	}
+}
+
+// GetMetrics 获取合批统计。
+// 返回一个包含请求数、下层调用批次数、合批的文本数和平均批次大小的 Map。
+func (e *BatchingEmbedder) GetMetrics() map[string]interface{} {
+	batches, texts := e.batches.Load(), e.batchedText.Load()
+	avg := 0.0
+	if batches > 0 {
+		avg = float64(texts) / float64(batches)
+	}
+	return map[string]interface{}{
+		"requests":       e.requests.Load(),
+		"batches":        batches,
+		"batched_texts":  texts,
+		"avg_batch_size": avg,
+	}
+}
+
+// Unwrap 返回下层 Embedder
+func (e *BatchingEmbedder) Unwrap() embedding.Embedder {
+	return e.embedder
+}
+
+// enqueue 将请求加入当前批次，批次已满时先发送当前批次
+func (e *BatchingEmbedder) enqueue(req *embeddingRequest) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.pending != nil && e.pending.size+len(req.texts) > e.maxBatchSize {
+		e.flushLocked()
+	}
+	if e.pending == nil {
+		batch := &embeddingBatch{}
+		batch.timer = time.AfterFunc(e.window, func() { e.flush(batch) })
+		e.pending = batch
+	}
+	e.pending.requests = append(e.pending.requests, req)
+	e.pending.size += len(req.texts)
+	if e.pending.size >= e.maxBatchSize {
+		e.flushLocked()
+	}
+}
+
+// flush 时间窗口结束时发送批次（批次已因达到上限发送时忽略）
+func (e *BatchingEmbedder) flush(batch *embeddingBatch) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.pending == batch {
+		e.flushLocked()
+	}
+}
+
+// flushLocked 发送当前批次（调用方需持有锁）
+func (e *BatchingEmbedder) flushLocked() {
+	batch := e.pending
+	e.pending = nil
+	batch.timer.Stop()
+	go e.run(batch.requests)
+}
+
+// run 合并批次中未取消的请求，调用下层 Embedder 并分发结果
+func (e *BatchingEmbedder) run(requests []*embeddingRequest) {
+	// 1. 跳过已取消的调用方
+	active := requests[:0]
+	var texts []string
+	for _, req := range requests {
+		if req.ctx.Err() != nil {
+			continue
+		}
+		active = append(active, req)
+		texts = append(texts, req.texts...)
+	}
+	if len(active) == 0 {
+		return
+	}
+	e.batches.Add(1)
+	e.batchedText.Add(int64(len(texts)))
+
+	// 2. 所有调用方都取消时才取消下层调用（保留第一个调用方上下文中的值）
+	ctx, cancel := context.WithCancel(context.WithoutCancel(active[0].ctx))
+	defer cancel()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for _, req := range active {
+			select {
+			case <-req.ctx.Done():
+			case <-done:
+				return
+			}
+		}
+		cancel()
+	}()
+
+	ctx, source := withEmbeddingSource(ctx)
+	vectors, err := e.embedder.EmbedStrings(ctx, texts)
+	if err == nil && len(vectors) != len(texts) {
+		err = fmt.Errorf("embedder returned %d vectors for %d texts", len(vectors), len(texts))
+	}
+
+	// 3. 按请求顺序切分结果（备用提供商应答时标记到每个调用方的上下文）
+	offset := 0
+	for _, req := range active {
+		if err != nil {
+			req.result <- embeddingResult{err: err}
+			continue
+		}
+		if source.fallback.Load() {
+			markEmbeddingFallback(req.ctx)
+		}
+		req.result <- embeddingResult{vectors: vectors[offset : offset+len(req.texts) : offset+len(req.texts)]}
+		offset += len(req.texts)
+	}
+}
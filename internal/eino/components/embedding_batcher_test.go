@@ -0,0 +1,128 @@
+package components
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+
+	"llm-cache/internal/eino/config"
+)
+
+// batchRecordingEmbedder 记录每次调用的批次大小，可选地返回错误或阻塞直到上下文取消
+type batchRecordingEmbedder struct {
+	mu      sync.Mutex
+	batches []int
+	err     error
+	block   bool
+}
+
+func (e *batchRecordingEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	e.mu.Lock()
+	e.batches = append(e.batches, len(texts))
+	e.mu.Unlock()
+	if e.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float64{float64(len(text))}
+	}
+	return vectors, nil
+}
+
+func (e *batchRecordingEmbedder) calls() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]int(nil), e.batches...)
+}
+
+// embedConcurrently 并发发起 n 个单文本请求，第 i 个请求的文本长度为 i+1
+func embedConcurrently(ctx context.Context, embedder embedding.Embedder, n int) ([][][]float64, []error) {
+	results := make([][][]float64, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			text := string(make([]byte, i+1))
+			results[i], errs[i] = embedder.EmbedStrings(ctx, []string{text})
+		}(i)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+func TestBatchingEmbedder(t *testing.T) {
+	inner := &batchRecordingEmbedder{}
+	batcher := NewBatchingEmbedder(inner, &config.EmbeddingBatchingConfig{Window: 50 * time.Millisecond, MaxBatchSize: 4})
+
+	results, errs := embedConcurrently(context.Background(), batcher, 8)
+	for i := range results {
+		if errs[i] != nil {
+			t.Fatalf("unexpected error: %v", errs[i])
+		}
+		if len(results[i]) != 1 || results[i][0][0] != float64(i+1) {
+			t.Errorf("request %d: expected its own vector, got %v", i, results[i])
+		}
+	}
+
+	// 达到批次上限时立即发送，不等待时间窗口
+	calls := inner.calls()
+	if len(calls) != 2 || calls[0] != 4 || calls[1] != 4 {
+		t.Errorf("expected two batches of 4, got %v", calls)
+	}
+	if metrics := batcher.GetMetrics(); metrics["requests"] != int64(8) || metrics["batches"] != int64(2) {
+		t.Errorf("unexpected metrics: %v", metrics)
+	}
+
+	// 携带 Option 的请求直接调用下层 Embedder
+	if _, err := batcher.EmbedStrings(context.Background(), []string{"a"}, embedding.WithModel("m")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls := inner.calls(); len(calls) != 3 || calls[2] != 1 {
+		t.Errorf("expected direct call, got %v", calls)
+	}
+}
+
+func TestBatchingEmbedderError(t *testing.T) {
+	inner := &batchRecordingEmbedder{err: errors.New("rate limited")}
+	batcher := NewBatchingEmbedder(inner, &config.EmbeddingBatchingConfig{Window: 20 * time.Millisecond, MaxBatchSize: 10})
+
+	_, errs := embedConcurrently(context.Background(), batcher, 3)
+	for i, err := range errs {
+		if err == nil || err.Error() != "rate limited" {
+			t.Errorf("request %d: expected error, got %v", i, err)
+		}
+	}
+	if calls := inner.calls(); len(calls) != 1 || calls[0] != 3 {
+		t.Errorf("expected one batch of 3, got %v", calls)
+	}
+}
+
+func TestBatchingEmbedderCancel(t *testing.T) {
+	inner := &batchRecordingEmbedder{block: true}
+	batcher := NewBatchingEmbedder(inner, &config.EmbeddingBatchingConfig{Window: 5 * time.Millisecond, MaxBatchSize: 10})
+
+	// 所有调用方取消后下层调用被取消，调用方立即返回
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, errs := embedConcurrently(ctx, batcher, 2)
+	for i, err := range errs {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("request %d: expected deadline exceeded, got %v", i, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected callers to return on cancellation, took %v", elapsed)
+	}
+}
@@ -0,0 +1,389 @@
+// Package components 提供 Eino 组件的工厂函数
+package components
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/text/unicode/norm"
+
+	"llm-cache/internal/eino/config"
+)
+
+// EmbeddingStore 定义向量结果缓存的二级存储后端（本地磁盘或 Redis）。
+// Get 未找到时返回 ok=false 且 err=nil。
+type EmbeddingStore interface {
+	Get(ctx context.Context, key string) (vector []float64, ok bool, err error)
+	Set(ctx context.Context, key string, vector []float64) error
+}
+
+// CachedEmbedder 为任意 Embedder 增加向量结果缓存。
+// 缓存键为模型名和规范化文本的 SHA-256，先查进程内 LRU，再查二级存储，
+// 仅对未命中的文本调用下层 Embedder，结果写回两级缓存。
+// 由备用提供商（故障转移）应答的向量不写入缓存，避免以主模型的缓存键保存其他模型的向量。
+type CachedEmbedder struct {
+	embedder embedding.Embedder
+	provider string
+	model    string
+	lru      *vectorLRU
+	store    EmbeddingStore
+
+	hits        atomic.Int64
+	storeHits   atomic.Int64
+	misses      atomic.Int64
+	storeErrors atomic.Int64
+	uncached    atomic.Int64
+}
+
+// NewCachedEmbedder 根据配置创建带缓存的 Embedder。
+// 参数 ctx: 上下文对象。
+// 参数 embedder: 下层 Embedder。
+// 参数 cfg: Embedder 配置（模型名参与缓存键，Cache 字段为缓存配置）。
+// 返回: CachedEmbedder 指针，二级存储后端不支持或初始化失败时返回错误。
+func NewCachedEmbedder(ctx context.Context, embedder embedding.Embedder, cfg *config.EmbedderConfig) (*CachedEmbedder, error) {
+	store, err := newEmbeddingStore(ctx, &cfg.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	model := cfg.Provider + ":" + cfg.Model
+	if cfg.Dimensions != nil {
+		// 同一模型不同维度的向量不可混用
+		model += ":" + strconv.Itoa(*cfg.Dimensions)
+	}
+	return &CachedEmbedder{
+		embedder: embedder,
+		provider: cfg.Provider,
+		model:    model,
+		lru:      newVectorLRU(cfg.Cache.MaxEntries),
+		store:    store,
+	}, nil
+}
+
+// EmbedStrings 返回文本向量，已缓存的文本不再调用下层 Embedder。
+// 同一批次中规范化后相同的文本只计算一次。
+func (e *CachedEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	model := e.model
+	if options := embedding.GetCommonOptions(&embedding.Options{}, opts...); options.Model != nil {
+		model = e.provider + ":" + *options.Model
+	}
+
+	// 1. 依次查询进程内 LRU 和二级存储
+	vectors := make([][]float64, len(texts))
+	keys := make([]string, len(texts))
+	missing := make(map[string][]int)
+	var missTexts []string
+	for i, text := range texts {
+		keys[i] = embeddingCacheKey(model, text)
+		if vector, ok := e.lookup(ctx, keys[i]); ok {
+			vectors[i] = vector
+			continue
+		}
+		if _, ok := missing[keys[i]]; !ok {
+			missTexts = append(missTexts, text)
+		}
+		missing[keys[i]] = append(missing[keys[i]], i)
+	}
+	if len(missTexts) == 0 {
+		return vectors, nil
+	}
+	e.misses.Add(int64(len(missTexts)))
+
+	// 2. 未命中的文本批量调用下层 Embedder
+	embedCtx, source := withEmbeddingSource(ctx)
+	embedded, err := e.embedder.EmbedStrings(embedCtx, missTexts, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missTexts) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(embedded), len(missTexts))
+	}
+
+	// 3. 写回缓存并填充结果（备用提供商的向量不写回）
+	fallback := source.fallback.Load()
+	if fallback {
+		e.uncached.Add(int64(len(missTexts)))
+	}
+	for i, text := range missTexts {
+		key := embeddingCacheKey(model, text)
+		if fallback {
+			for _, idx := range missing[key] {
+				vectors[idx] = append([]float64(nil), embedded[i]...)
+			}
+			continue
+		}
+		e.lru.Add(key, embedded[i])
+		if e.store != nil {
+			if err := e.store.Set(ctx, key, embedded[i]); err != nil {
+				e.storeErrors.Add(1)
+			}
+		}
+		for _, idx := range missing[key] {
+			vectors[idx] = append([]float64(nil), embedded[i]...)
+		}
+	}
+	return vectors, nil
+}
+
+// GetMetrics 获取缓存命中统计。
+// 返回一个包含进程内命中、二级存储命中、未命中、命中率、条目数、二级存储错误数和未缓存的备用提供商向量数的 Map。
+func (e *CachedEmbedder) GetMetrics() map[string]interface{} {
+	hits, storeHits, misses := e.hits.Load(), e.storeHits.Load(), e.misses.Load()
+	hitRate := 0.0
+	if total := hits + storeHits + misses; total > 0 {
+		hitRate = float64(hits+storeHits) / float64(total)
+	}
+	return map[string]interface{}{
+		"hits":         hits,
+		"store_hits":   storeHits,
+		"misses":       misses,
+		"hit_rate":     hitRate,
+		"entries":      e.lru.Len(),
+		"store_errors": e.storeErrors.Load(),
+		"uncached":     e.uncached.Load(),
+	}
+}
+
+// Unwrap 返回下层 Embedder
+func (e *CachedEmbedder) Unwrap() embedding.Embedder {
+	return e.embedder
+}
+
+// lookup 查询两级缓存，二级存储命中时回填进程内 LRU
+func (e *CachedEmbedder) lookup(ctx context.Context, key string) ([]float64, bool) {
+	if vector, ok := e.lru.Get(key); ok {
+		e.hits.Add(1)
+		return vector, true
+	}
+	if e.store == nil {
+		return nil, false
+	}
+	vector, ok, err := e.store.Get(ctx, key)
+	if err != nil {
+		// 二级存储不可用时退化为调用下层 Embedder
+		e.storeErrors.Add(1)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	e.storeHits.Add(1)
+	e.lru.Add(key, vector)
+	return vector, true
+}
+
+// embeddingCacheKey 计算缓存键：模型名与规范化文本（NFKC、合并空白）的 SHA-256
+func embeddingCacheKey(model, text string) string {
+	normalized := strings.Join(strings.Fields(norm.NFKC.String(text)), " ")
+	sum := sha256.Sum256([]byte(model + "\x00" + normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// newEmbeddingStore 根据配置创建二级存储后端（memory 时返回 nil）
+func newEmbeddingStore(ctx context.Context, cfg *config.EmbeddingCacheConfig) (EmbeddingStore, error) {
+	switch cfg.Backend {
+	case "memory", "":
+		return nil, nil
+	case "disk":
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create embedding cache dir: %w", err)
+		}
+		return &diskEmbeddingStore{dir: cfg.Dir, ttl: cfg.TTL}, nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		if err := client.Ping(ctx).Err(); err != nil {
+			return nil, fmt.Errorf("connect embedding cache redis: %w", err)
+		}
+		return &redisEmbeddingStore{client: client, prefix: cfg.Redis.KeyPrefix, ttl: cfg.TTL}, nil
+	default:
+		return nil, fmt.Errorf("unsupported embedding cache backend: %s", cfg.Backend)
+	}
+}
+
+// diskEmbeddingStore 将向量以二进制文件保存在本地目录（按键前两位分目录）
+type diskEmbeddingStore struct {
+	dir string
+	ttl time.Duration
+}
+
+// Get 读取向量文件，过期的文件视为未命中
+func (s *diskEmbeddingStore) Get(ctx context.Context, key string) ([]float64, bool, error) {
+	path := s.path(key)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if s.ttl > 0 && time.Since(info.ModTime()) > s.ttl {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	vector, err := decodeVector(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return vector, true, nil
+}
+
+// Set 先写临时文件再重命名，避免并发读取到不完整的向量
+func (s *diskEmbeddingStore) Set(ctx context.Context, key string, vector []float64) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(encodeVector(vector)); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// path 返回键对应的文件路径
+func (s *diskEmbeddingStore) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key+".bin")
+}
+
+// redisEmbeddingStore 将向量以二进制字符串保存在 Redis
+type redisEmbeddingStore struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// Get 读取向量，键不存在时视为未命中
+func (s *redisEmbeddingStore) Get(ctx context.Context, key string) ([]float64, bool, error) {
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	vector, err := decodeVector(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return vector, true, nil
+}
+
+// Set 写入向量（TTL 为 0 时不过期）
+func (s *redisEmbeddingStore) Set(ctx context.Context, key string, vector []float64) error {
+	return s.client.Set(ctx, s.prefix+key, encodeVector(vector), s.ttl).Err()
+}
+
+// encodeVector 将向量编码为小端序 float64 序列
+func encodeVector(vector []float64) []byte {
+	data := make([]byte, 8*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint64(data[8*i:], math.Float64bits(v))
+	}
+	return data
+}
+
+// decodeVector 解码 encodeVector 编码的向量
+func decodeVector(data []byte) ([]float64, error) {
+	if len(data)%8 != 0 {
+		return nil, fmt.Errorf("invalid embedding cache entry length: %d", len(data))
+	}
+	vector := make([]float64, len(data)/8)
+	for i := range vector {
+		vector[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[8*i:]))
+	}
+	return vector, nil
+}
+
+// vectorLRU 实现并发安全的定长 LRU（返回向量副本，避免调用方修改缓存内容）
+type vectorLRU struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+// vectorLRUEntry LRU 链表节点
+type vectorLRUEntry struct {
+	key    string
+	vector []float64
+}
+
+// newVectorLRU 创建 LRU（容量不大于 0 时使用 10000）
+func newVectorLRU(capacity int) *vectorLRU {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &vectorLRU{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get 查询向量并标记为最近使用
+func (c *vectorLRU) Get(key string) ([]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return append([]float64(nil), elem.Value.(*vectorLRUEntry).vector...), true
+}
+
+// Add 写入向量，超出容量时淘汰最久未使用的条目
+func (c *vectorLRU) Add(key string, vector []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	vector = append([]float64(nil), vector...)
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*vectorLRUEntry).vector = vector
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&vectorLRUEntry{key: key, vector: vector})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*vectorLRUEntry).key)
+	}
+}
+
+// Len 返回当前条目数
+func (c *vectorLRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
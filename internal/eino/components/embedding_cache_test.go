@@ -0,0 +1,173 @@
+package components
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+
+	"llm-cache/internal/eino/config"
+)
+
+// countingEmbedder 记录调用的文本，按文本长度生成向量
+type countingEmbedder struct {
+	texts []string
+}
+
+func (e *countingEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	e.texts = append(e.texts, texts...)
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float64{float64(len(text)), 1}
+	}
+	return vectors, nil
+}
+
+func TestCachedEmbedder(t *testing.T) {
+	inner := &countingEmbedder{}
+	cfg := &config.EmbedderConfig{Provider: "openai", Model: "m", Cache: config.EmbeddingCacheConfig{Enabled: true, MaxEntries: 2}}
+	cached, err := NewCachedEmbedder(context.Background(), inner, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	// 同一批次中规范化后相同的文本只调用一次
+	vectors, err := cached.EmbedStrings(ctx, []string{"what is go", "what  is go ", "hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vectors) != 3 || vectors[0][0] != vectors[1][0] {
+		t.Errorf("unexpected vectors: %v", vectors)
+	}
+	if len(inner.texts) != 2 {
+		t.Errorf("expected 2 embedded texts, got %v", inner.texts)
+	}
+
+	// 再次查询命中缓存，返回副本
+	vectors[0][0] = -1
+	again, err := cached.EmbedStrings(ctx, []string{"what is go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inner.texts) != 2 {
+		t.Errorf("expected cache hit, got %v", inner.texts)
+	}
+	if again[0][0] != float64(len("what is go")) {
+		t.Errorf("expected cached vector to be unchanged, got %v", again[0])
+	}
+
+	// 不同模型不共享缓存
+	if _, err := cached.EmbedStrings(ctx, []string{"what is go"}, embedding.WithModel("other")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inner.texts) != 3 {
+		t.Errorf("expected model override to miss, got %v", inner.texts)
+	}
+
+	// 超出容量时淘汰最久未使用的条目
+	if _, err := cached.EmbedStrings(ctx, []string{"hello"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inner.texts) != 4 {
+		t.Errorf("expected evicted entry to miss, got %v", inner.texts)
+	}
+
+	metrics := cached.GetMetrics()
+	if metrics["hits"] != int64(1) || metrics["misses"] != int64(4) || metrics["entries"] != 2 {
+		t.Errorf("unexpected metrics: %v", metrics)
+	}
+}
+
+func TestCachedEmbedderDiskStore(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.EmbedderConfig{Provider: "openai", Model: "m", Cache: config.EmbeddingCacheConfig{Enabled: true, Backend: "disk", Dir: dir}}
+	ctx := context.Background()
+
+	first, err := NewCachedEmbedder(ctx, &countingEmbedder{}, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := first.EmbedStrings(ctx, []string{"what is go"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 新实例（模拟重启）从磁盘读取
+	inner := &countingEmbedder{}
+	second, err := NewCachedEmbedder(ctx, inner, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vectors, err := second.EmbedStrings(ctx, []string{"what is go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inner.texts) != 0 {
+		t.Errorf("expected disk hit, got %v", inner.texts)
+	}
+	if vectors[0][0] != float64(len("what is go")) || vectors[0][1] != 1 {
+		t.Errorf("unexpected vector: %v", vectors[0])
+	}
+	if second.GetMetrics()["store_hits"] != int64(1) {
+		t.Errorf("unexpected metrics: %v", second.GetMetrics())
+	}
+}
+
+func TestCachedEmbedderFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		batching bool
+	}{
+		{"direct", false},
+		{"batching", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &flakyEmbedder{failures: 100, err: errors.New("connection refused")}
+			fallback := &countingEmbedder{}
+			var inner embedding.Embedder = testResilientEmbedder(primary, fallback)
+			if tt.batching {
+				inner = NewBatchingEmbedder(inner, &config.EmbeddingBatchingConfig{Window: time.Millisecond, MaxBatchSize: 10})
+			}
+			cfg := &config.EmbedderConfig{Provider: "openai", Model: "m", Cache: config.EmbeddingCacheConfig{Enabled: true}}
+			cached, err := NewCachedEmbedder(context.Background(), inner, cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ctx := context.Background()
+
+			// 主提供商失败，备用提供商应答的向量返回给调用方但不写入缓存
+			vectors, err := cached.EmbedStrings(ctx, []string{"what is go"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if vectors[0][0] != float64(len("what is go")) {
+				t.Errorf("expected fallback vector, got %v", vectors[0])
+			}
+
+			// 主提供商恢复后，同一文本返回主提供商的向量
+			primary.failures = 0
+			vectors, err = cached.EmbedStrings(ctx, []string{"what is go"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if vectors[0][0] != 1 || vectors[0][1] != 2 {
+				t.Errorf("expected primary vector, got %v", vectors[0])
+			}
+
+			metrics := cached.GetMetrics()
+			if metrics["hits"] != int64(0) || metrics["uncached"] != int64(1) || metrics["entries"] != 1 {
+				t.Errorf("unexpected metrics: %v", metrics)
+			}
+		})
+	}
+}
+
+func TestNewEmbeddingStoreUnsupported(t *testing.T) {
+	if _, err := newEmbeddingStore(context.Background(), &config.EmbeddingCacheConfig{Backend: "memcached"}); err == nil {
+		t.Errorf("expected error for unsupported backend")
+	}
+}
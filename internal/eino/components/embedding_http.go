@@ -0,0 +1,261 @@
+// Package components 提供 Eino 组件的工厂函数
+package components
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// requestAuthorizer 在发送前为请求添加鉴权信息（body 为已序列化的请求体，供签名使用）
+type requestAuthorizer func(ctx context.Context, req *http.Request, body []byte) error
+
+// bearerAuthorizer 返回使用 Bearer Token 鉴权的 requestAuthorizer（apiKey 为空时不添加）
+func bearerAuthorizer(apiKey string) requestAuthorizer {
+	return func(ctx context.Context, req *http.Request, body []byte) error {
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		return nil
+	}
+}
+
+// compatibleEmbedder 调用 OpenAI 兼容的 Embeddings API（POST {base_url}/embeddings）。
+// ARK、Dashscope（compatible-mode）和千帆 v2 均提供该接口，仅鉴权方式和单次文本数上限不同。
+type compatibleEmbedder struct {
+	client     *http.Client
+	endpoint   string
+	model      string
+	dimensions *int
+	batchSize  int
+	authorize  requestAuthorizer
+}
+
+// compatibleEmbeddingRequest OpenAI 兼容 Embeddings API 请求体
+type compatibleEmbeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     *int     `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+// compatibleEmbeddingResponse OpenAI 兼容 Embeddings API 响应体
+type compatibleEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// EmbedStrings 按单次文本数上限分批调用 Embeddings API
+func (e *compatibleEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	model := e.model
+	if options := embedding.GetCommonOptions(&embedding.Options{}, opts...); options.Model != nil {
+		model = *options.Model
+	}
+
+	return embedInBatches(texts, e.batchSize, func(batch []string) ([][]float64, error) {
+		var resp compatibleEmbeddingResponse
+		reqBody := compatibleEmbeddingRequest{Model: model, Input: batch, Dimensions: e.dimensions, EncodingFormat: "float"}
+		if err := postEmbeddingJSON(ctx, e.client, e.endpoint, reqBody, e.authorize, &resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Data) != len(batch) {
+			return nil, fmt.Errorf("embedding api returned %d vectors for %d texts", len(resp.Data), len(batch))
+		}
+		sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+		vectors := make([][]float64, len(resp.Data))
+		for i, item := range resp.Data {
+			vectors[i] = item.Embedding
+		}
+		return vectors, nil
+	})
+}
+
+// ollamaEmbedder 调用 Ollama 原生 Embed API（POST {base_url}/api/embed）
+type ollamaEmbedder struct {
+	client     *http.Client
+	endpoint   string
+	model      string
+	dimensions *int
+	authorize  requestAuthorizer
+}
+
+// ollamaEmbedRequest Ollama Embed API 请求体
+type ollamaEmbedRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions *int     `json:"dimensions,omitempty"`
+}
+
+// ollamaEmbedResponse Ollama Embed API 响应体
+type ollamaEmbedResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+// EmbedStrings 调用 Ollama Embed API
+func (e *ollamaEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	model := e.model
+	if options := embedding.GetCommonOptions(&embedding.Options{}, opts...); options.Model != nil {
+		model = *options.Model
+	}
+
+	var resp ollamaEmbedResponse
+	reqBody := ollamaEmbedRequest{Model: model, Input: texts, Dimensions: e.dimensions}
+	if err := postEmbeddingJSON(ctx, e.client, e.endpoint, reqBody, e.authorize, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("embedding api returned %d vectors for %d texts", len(resp.Embeddings), len(texts))
+	}
+	return resp.Embeddings, nil
+}
+
+// tencentcloudEmbedder 调用腾讯云混元 GetEmbedding 接口（TC3-HMAC-SHA256 签名）
+// 混元 Embedding 维度固定且不可配置，配置了 dimensions 时校验返回向量的维度。
+type tencentcloudEmbedder struct {
+	client     *http.Client
+	endpoint   string
+	region     string
+	dimensions *int
+	batchSize  int
+	signer     *tc3Signer
+}
+
+// tencentcloudEmbeddingRequest GetEmbedding 请求体
+type tencentcloudEmbeddingRequest struct {
+	InputList []string `json:"InputList"`
+}
+
+// tencentcloudEmbeddingResponse GetEmbedding 响应体（业务错误在 Response.Error 中返回，HTTP 状态码为 200）
+type tencentcloudEmbeddingResponse struct {
+	Response struct {
+		Data []struct {
+			Index     int       `json:"Index"`
+			Embedding []float64 `json:"Embedding"`
+		} `json:"Data"`
+		Error *struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+		RequestID string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+// EmbedStrings 按单次文本数上限分批调用 GetEmbedding
+func (e *tencentcloudEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	return embedInBatches(texts, e.batchSize, func(batch []string) ([][]float64, error) {
+		var resp tencentcloudEmbeddingResponse
+		if err := postEmbeddingJSON(ctx, e.client, e.endpoint, tencentcloudEmbeddingRequest{InputList: batch}, e.authorize, &resp); err != nil {
+			return nil, err
+		}
+		if apiErr := resp.Response.Error; apiErr != nil {
+			if status := tencentcloudErrorStatus(apiErr.Code); status != 0 {
+				return nil, fmt.Errorf("tencentcloud embedding error %s: %s (status code: %d, request_id=%s)", apiErr.Code, apiErr.Message, status, resp.Response.RequestID)
+			}
+			return nil, fmt.Errorf("tencentcloud embedding error %s: %s (request_id=%s)", apiErr.Code, apiErr.Message, resp.Response.RequestID)
+		}
+		data := resp.Response.Data
+		if len(data) != len(batch) {
+			return nil, fmt.Errorf("embedding api returned %d vectors for %d texts", len(data), len(batch))
+		}
+		sort.Slice(data, func(i, j int) bool { return data[i].Index < data[j].Index })
+		vectors := make([][]float64, len(data))
+		for i, item := range data {
+			if e.dimensions != nil && len(item.Embedding) != *e.dimensions {
+				return nil, fmt.Errorf("tencentcloud embedding returned %d dimensions, configured %d (dimensions is fixed by the model)", len(item.Embedding), *e.dimensions)
+			}
+			vectors[i] = item.Embedding
+		}
+		return vectors, nil
+	})
+}
+
+// tencentcloudErrorStatus 将混元的限流和服务端错误码映射为等价的 HTTP 状态码，
+// 使其与非 200 响应一样被识别为可重试错误；其他错误码返回 0。
+func tencentcloudErrorStatus(code string) int {
+	switch {
+	case strings.HasPrefix(code, "RequestLimitExceeded"), strings.HasPrefix(code, "LimitExceeded"):
+		return http.StatusTooManyRequests
+	case strings.HasPrefix(code, "InternalError"), strings.HasPrefix(code, "ResourceUnavailable"):
+		return http.StatusServiceUnavailable
+	default:
+		return 0
+	}
+}
+
+// authorize 添加混元公共请求头并签名
+func (e *tencentcloudEmbedder) authorize(ctx context.Context, req *http.Request, body []byte) error {
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("X-TC-Action", tencentcloudEmbeddingAction)
+	req.Header.Set("X-TC-Version", tencentcloudEmbeddingVersion)
+	req.Header.Set("X-TC-Timestamp", strconv.FormatInt(timestamp, 10))
+	if e.region != "" {
+		req.Header.Set("X-TC-Region", e.region)
+	}
+	req.Header.Set("Authorization", e.signer.authorization(req, body, timestamp))
+	return nil
+}
+
+// embedInBatches 按 batchSize 切分文本并依次调用 embed（batchSize 不大于 0 时不切分）
+func embedInBatches(texts []string, batchSize int, embed func(batch []string) ([][]float64, error)) ([][]float64, error) {
+	if batchSize <= 0 || len(texts) <= batchSize {
+		return embed(texts)
+	}
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+		batch, err := embed(texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// postEmbeddingJSON 发送 JSON 请求并解析响应。
+// 非 200 响应的错误信息包含 "status code: N"，供重试策略判断是否为临时故障。
+func postEmbeddingJSON(ctx context.Context, client *http.Client, endpoint string, reqBody any, authorize requestAuthorizer, out any) error {
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("marshal embedding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := authorize(ctx, req, body); err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("call embedding api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read embedding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("embedding api returned status code: %d: %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("unmarshal embedding response: %w", err)
+	}
+	return nil
+}
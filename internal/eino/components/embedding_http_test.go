@@ -0,0 +1,461 @@
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"llm-cache/internal/eino/config"
+)
+
+// embeddingStandIn 模拟提供商 HTTP 接口，记录请求并按 handle 返回响应
+type embeddingStandIn struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	handle   func(w http.ResponseWriter, r *http.Request, body []byte)
+}
+
+func newEmbeddingStandIn(t *testing.T, handle func(w http.ResponseWriter, r *http.Request, body []byte)) (*embeddingStandIn, *httptest.Server) {
+	s := &embeddingStandIn{handle: handle}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		s.mu.Unlock()
+		s.handle(w, r, body)
+	}))
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+// compatibleHandler 按 OpenAI 兼容格式逆序返回向量（向量值为文本长度）
+func compatibleHandler(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req compatibleEmbeddingRequest
+	_ = json.Unmarshal(body, &req)
+	data := make([]map[string]any, 0, len(req.Input))
+	for i := len(req.Input) - 1; i >= 0; i-- {
+		data = append(data, map[string]any{"index": i, "embedding": []float64{float64(len(req.Input[i]))}})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func testTexts(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i+1)
+	}
+	return texts
+}
+
+func checkLengthVectors(t *testing.T, vectors [][]float64, n int) {
+	t.Helper()
+	if len(vectors) != n {
+		t.Fatalf("expected %d vectors, got %d", n, len(vectors))
+	}
+	for i, vector := range vectors {
+		if len(vector) != 1 || vector[0] != float64(i+1) {
+			t.Errorf("vector %d: expected [%d], got %v", i, i+1, vector)
+		}
+	}
+}
+
+func TestCompatibleProviders(t *testing.T) {
+	dims := 256
+	tests := []struct {
+		name      string
+		cfg       config.EmbedderConfig
+		texts     int
+		wantBatch []int
+		wantPath  string
+		wantAuth  string
+		wantDims  bool
+	}{
+		{
+			name:      "ark api key",
+			cfg:       config.EmbedderConfig{Provider: "ark", APIKey: "ark-key", Model: "ep-1"},
+			texts:     3,
+			wantBatch: []int{3},
+			wantPath:  "/api/v3/embeddings",
+			wantAuth:  "Bearer ark-key",
+		},
+		{
+			name:      "dashscope batches of 10",
+			cfg:       config.EmbedderConfig{Provider: "dashscope", APIKey: "sk-ds", Model: "text-embedding-v3", Dimensions: &dims},
+			texts:     23,
+			wantBatch: []int{10, 10, 3},
+			wantPath:  "/compatible-mode/v1/embeddings",
+			wantAuth:  "Bearer sk-ds",
+			wantDims:  true,
+		},
+		{
+			name:      "qianfan batches of 16",
+			cfg:       config.EmbedderConfig{Provider: "qianfan", APIKey: "bce-v3/key", Model: "embedding-v1", Dimensions: &dims},
+			texts:     17,
+			wantBatch: []int{16, 1},
+			wantPath:  "/v2/embeddings",
+			wantAuth:  "Bearer bce-v3/key",
+			wantDims:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn, server := newEmbeddingStandIn(t, compatibleHandler)
+			cfg := tt.cfg
+			cfg.BaseURL = server.URL + strings.TrimSuffix(tt.wantPath, "/embeddings")
+
+			embedder, err := NewEmbedder(context.Background(), &cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			vectors, err := embedder.EmbedStrings(context.Background(), testTexts(tt.texts))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkLengthVectors(t, vectors, tt.texts)
+
+			if len(standIn.requests) != len(tt.wantBatch) {
+				t.Fatalf("expected %d requests, got %d", len(tt.wantBatch), len(standIn.requests))
+			}
+			for i, r := range standIn.requests {
+				var req compatibleEmbeddingRequest
+				if err := json.Unmarshal(standIn.bodies[i], &req); err != nil {
+					t.Fatalf("unexpected request body: %v", err)
+				}
+				if len(req.Input) != tt.wantBatch[i] {
+					t.Errorf("request %d: expected %d texts, got %d", i, tt.wantBatch[i], len(req.Input))
+				}
+				if req.Model != cfg.Model || req.EncodingFormat != "float" {
+					t.Errorf("request %d: unexpected body %s", i, standIn.bodies[i])
+				}
+				if (req.Dimensions != nil) != tt.wantDims {
+					t.Errorf("request %d: expected dimensions=%v, got %v", i, tt.wantDims, req.Dimensions)
+				}
+				if r.URL.Path != tt.wantPath {
+					t.Errorf("expected %v, got %v", tt.wantPath, r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != tt.wantAuth {
+					t.Errorf("expected %v, got %v", tt.wantAuth, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOllamaEmbedder(t *testing.T) {
+	standIn, server := newEmbeddingStandIn(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		var req ollamaEmbedRequest
+		_ = json.Unmarshal(body, &req)
+		embeddings := make([][]float64, len(req.Input))
+		for i, text := range req.Input {
+			embeddings[i] = []float64{float64(len(text))}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"model": req.Model, "embeddings": embeddings})
+	})
+
+	embedder, err := NewEmbedder(context.Background(), &config.EmbedderConfig{Provider: "ollama", BaseURL: server.URL + "/", Model: "bge-m3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vectors, err := embedder.EmbedStrings(context.Background(), testTexts(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkLengthVectors(t, vectors, 3)
+
+	r := standIn.requests[0]
+	if r.URL.Path != "/api/embed" {
+		t.Errorf("expected /api/embed, got %v", r.URL.Path)
+	}
+	if got := r.Header.Get("Authorization"); got != "" {
+		t.Errorf("expected no authorization header, got %v", got)
+	}
+	if body := string(standIn.bodies[0]); !strings.Contains(body, `"model":"bge-m3"`) || strings.Contains(body, "dimensions") {
+		t.Errorf("unexpected request body: %s", body)
+	}
+}
+
+func TestARKKeySource(t *testing.T) {
+	var keyCalls int
+	openAPI, openAPIServer := newEmbeddingStandIn(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		keyCalls++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"ResponseMetadata": map[string]any{"RequestId": "req-1"},
+			"Result":           map[string]any{"ApiKey": fmt.Sprintf("temp-key-%d", keyCalls), "ExpiredTime": time.Now().Add(24 * time.Hour).Unix()},
+		})
+	})
+	ark, arkServer := newEmbeddingStandIn(t, compatibleHandler)
+
+	client := &http.Client{Timeout: time.Second}
+	embedder := &compatibleEmbedder{
+		client:    client,
+		endpoint:  arkServer.URL + "/api/v3/embeddings",
+		model:     "ep-1",
+		authorize: newARKKeySource(client, openAPIServer.URL, "AK", "SK", "cn-beijing", "ep-1").authorize,
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := embedder.EmbedStrings(context.Background(), []string{"q"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// 临时 API Key 在有效期内复用
+	if keyCalls != 1 {
+		t.Errorf("expected 1 GetApiKey call, got %d", keyCalls)
+	}
+	r := openAPI.requests[0]
+	if r.URL.Query().Get("Action") != "GetApiKey" || r.URL.Query().Get("Version") != arkOpenAPIVersion {
+		t.Errorf("unexpected query: %v", r.URL.RawQuery)
+	}
+	if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "HMAC-SHA256 Credential=AK/") || !strings.Contains(auth, "/cn-beijing/ark/request") {
+		t.Errorf("unexpected signature: %v", auth)
+	}
+	var req struct {
+		ResourceType string   `json:"ResourceType"`
+		ResourceIds  []string `json:"ResourceIds"`
+	}
+	if err := json.Unmarshal(openAPI.bodies[0], &req); err != nil || req.ResourceType != "endpoint" || len(req.ResourceIds) != 1 || req.ResourceIds[0] != "ep-1" {
+		t.Errorf("unexpected request body: %s", openAPI.bodies[0])
+	}
+	for _, r := range ark.requests {
+		if got := r.Header.Get("Authorization"); got != "Bearer temp-key-1" {
+			t.Errorf("expected %v, got %v", "Bearer temp-key-1", got)
+		}
+	}
+}
+
+func TestARKKeySourceError(t *testing.T) {
+	_, server := newEmbeddingStandIn(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `{"ResponseMetadata":{"RequestId":"req-2","Error":{"Code":"AccessDenied","Message":"denied"}}}`)
+	})
+	source := newARKKeySource(http.DefaultClient, server.URL, "AK", "SK", "cn-beijing", "ep-1")
+	if _, err := source.key(context.Background()); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("expected AccessDenied error, got %v", err)
+	}
+}
+
+func TestQianfanBCEAuthorization(t *testing.T) {
+	standIn, server := newEmbeddingStandIn(t, compatibleHandler)
+	embedder, err := NewEmbedder(context.Background(), &config.EmbedderConfig{
+		Provider: "qianfan", BaseURL: server.URL + "/v2", Model: "embedding-v1", AccessKey: "AK", SecretKey: "SK",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := embedder.EmbedStrings(context.Background(), []string{"q"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := standIn.requests[0]
+	timestamp := r.Header.Get("X-Bce-Date")
+	auth := r.Header.Get("Authorization")
+	parts := strings.Split(auth, "/")
+	if len(parts) != 6 || parts[0] != "bce-auth-v1" || parts[1] != "AK" || parts[2] != timestamp || parts[3] != "1800" || parts[4] != "host;x-bce-date" {
+		t.Fatalf("unexpected authorization: %v", auth)
+	}
+
+	// 服务端按相同规则重新计算签名
+	verify, _ := http.NewRequest(http.MethodPost, "http://"+r.Host+r.URL.RequestURI(), nil)
+	if want := bceAuthorization(verify, "AK", "SK", timestamp); auth != want {
+		t.Errorf("expected %v, got %v", want, auth)
+	}
+	if want := bceAuthorization(verify, "AK", "other", timestamp); auth == want {
+		t.Errorf("expected signature to depend on secret key")
+	}
+}
+
+func TestBCEURIEncode(t *testing.T) {
+	tests := []struct {
+		in          string
+		encodeSlash bool
+		want        string
+	}{
+		{"/v2/embeddings", false, "/v2/embeddings"},
+		{"/v2/embeddings", true, "%2Fv2%2Fembeddings"},
+		{"a b~c", true, "a%20b~c"},
+		{"127.0.0.1:8080", true, "127.0.0.1%3A8080"},
+	}
+	for _, tt := range tests {
+		if got := bceURIEncode(tt.in, tt.encodeSlash); got != tt.want {
+			t.Errorf("expected %v, got %v", tt.want, got)
+		}
+	}
+}
+
+func TestTencentcloudEmbedder(t *testing.T) {
+	standIn, server := newEmbeddingStandIn(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		var req tencentcloudEmbeddingRequest
+		_ = json.Unmarshal(body, &req)
+		data := make([]map[string]any, 0, len(req.InputList))
+		for i := len(req.InputList) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{"Index": i, "Embedding": []float64{float64(len(req.InputList[i]))}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"Response": map[string]any{"Data": data, "RequestId": "req-3"}})
+	})
+
+	embedder, err := NewEmbedder(context.Background(), &config.EmbedderConfig{
+		Provider: "tencentcloud", BaseURL: server.URL, SecretID: "AKID", SecretKey: "secret", Region: "ap-shanghai",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vectors, err := embedder.EmbedStrings(context.Background(), testTexts(201))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkLengthVectors(t, vectors, 201)
+	if len(standIn.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(standIn.requests))
+	}
+
+	r := standIn.requests[0]
+	headers := map[string]string{
+		"X-TC-Action":  tencentcloudEmbeddingAction,
+		"X-TC-Version": tencentcloudEmbeddingVersion,
+		"X-TC-Region":  "ap-shanghai",
+		"Content-Type": "application/json; charset=utf-8",
+	}
+	for name, want := range headers {
+		if got := r.Header.Get(name); got != want {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+
+	// 服务端按相同规则重新计算签名
+	timestamp, err := strconv.ParseInt(r.Header.Get("X-TC-Timestamp"), 10, 64)
+	if err != nil {
+		t.Fatalf("unexpected timestamp: %v", err)
+	}
+	verify, _ := http.NewRequest(http.MethodPost, "http://"+r.Host+r.URL.RequestURI(), nil)
+	verify.Header = r.Header.Clone()
+	signer := &tc3Signer{secretID: "AKID", secretKey: "secret", service: tencentcloudEmbeddingService}
+	if want := signer.authorization(verify, standIn.bodies[0], timestamp); r.Header.Get("Authorization") != want {
+		t.Errorf("expected %v, got %v", want, r.Header.Get("Authorization"))
+	}
+	date := time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+	if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "TC3-HMAC-SHA256 Credential=AKID/"+date+"/hunyuan/tc3_request, SignedHeaders=content-type;host;x-tc-action, Signature=") {
+		t.Errorf("unexpected authorization: %v", auth)
+	}
+}
+
+func TestEmbeddingProviderErrors(t *testing.T) {
+	dims := 1024
+	tests := []struct {
+		name          string
+		cfg           config.EmbedderConfig
+		status        int
+		body          string
+		wantErr       string
+		wantRetryable bool
+	}{
+		{
+			name:          "rate limited",
+			cfg:           config.EmbedderConfig{Provider: "dashscope", APIKey: "sk"},
+			status:        http.StatusTooManyRequests,
+			body:          `{"error":{"message":"rate limited"}}`,
+			wantErr:       "status code: 429",
+			wantRetryable: true,
+		},
+		{
+			name:    "unauthorized",
+			cfg:     config.EmbedderConfig{Provider: "ollama"},
+			status:  http.StatusUnauthorized,
+			body:    `{"error":"unauthorized"}`,
+			wantErr: "status code: 401",
+		},
+		{
+			name:    "tencentcloud business error",
+			cfg:     config.EmbedderConfig{Provider: "tencentcloud", SecretID: "AKID", SecretKey: "secret"},
+			status:  http.StatusOK,
+			body:    `{"Response":{"Error":{"Code":"AuthFailure.SignatureFailure","Message":"bad signature"},"RequestId":"req-4"}}`,
+			wantErr: "AuthFailure.SignatureFailure",
+		},
+		{
+			name:          "tencentcloud rate limited",
+			cfg:           config.EmbedderConfig{Provider: "tencentcloud", SecretID: "AKID", SecretKey: "secret"},
+			status:        http.StatusOK,
+			body:          `{"Response":{"Error":{"Code":"RequestLimitExceeded","Message":"request limit exceeded"},"RequestId":"req-5"}}`,
+			wantErr:       "RequestLimitExceeded",
+			wantRetryable: true,
+		},
+		{
+			name:          "tencentcloud internal error",
+			cfg:           config.EmbedderConfig{Provider: "tencentcloud", SecretID: "AKID", SecretKey: "secret"},
+			status:        http.StatusOK,
+			body:          `{"Response":{"Error":{"Code":"InternalError","Message":"internal error"},"RequestId":"req-6"}}`,
+			wantErr:       "InternalError",
+			wantRetryable: true,
+		},
+		{
+			name:    "tencentcloud dimension mismatch",
+			cfg:     config.EmbedderConfig{Provider: "tencentcloud", SecretID: "AKID", SecretKey: "secret", Dimensions: &dims},
+			status:  http.StatusOK,
+			body:    `{"Response":{"Data":[{"Index":0,"Embedding":[0.1,0.2]}],"RequestId":"req-7"}}`,
+			wantErr: "returned 2 dimensions, configured 1024",
+		},
+		{
+			name:    "vector count mismatch",
+			cfg:     config.EmbedderConfig{Provider: "qianfan", APIKey: "key"},
+			status:  http.StatusOK,
+			body:    `{"data":[]}`,
+			wantErr: "returned 0 vectors for 1 texts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, server := newEmbeddingStandIn(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			})
+			cfg := tt.cfg
+			cfg.BaseURL = server.URL
+			embedder, err := NewEmbedder(context.Background(), &cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err = embedder.EmbedStrings(context.Background(), []string{"q"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if got := retryableEmbeddingError(err); got != tt.wantRetryable {
+				t.Errorf("expected retryable=%v, got %v", tt.wantRetryable, got)
+			}
+		})
+	}
+}
+
+func TestEmbeddingProviderCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.EmbedderConfig
+		wantErr bool
+	}{
+		{"ark without credentials", config.EmbedderConfig{Provider: "ark", Model: "ep-1"}, true},
+		{"ark with access key", config.EmbedderConfig{Provider: "ark", Model: "ep-1", AccessKey: "AK", SecretKey: "SK"}, false},
+		{"dashscope without api key", config.EmbedderConfig{Provider: "dashscope"}, true},
+		{"qianfan without credentials", config.EmbedderConfig{Provider: "qianfan"}, true},
+		{"qianfan with access key only", config.EmbedderConfig{Provider: "qianfan", AccessKey: "AK"}, true},
+		{"tencentcloud without secret id", config.EmbedderConfig{Provider: "tencentcloud", SecretKey: "secret"}, true},
+		{"ollama without credentials", config.EmbedderConfig{Provider: "ollama"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEmbedder(context.Background(), &tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected err=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+// Package components 提供 Eino 组件的工厂函数
+package components
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+
+	"llm-cache/internal/eino/config"
+)
+
+// ErrEmbeddingUnavailable 所有 Embedding 提供商均失败或处于熔断状态
+var ErrEmbeddingUnavailable = errors.New("embedding providers unavailable")
+
+// errCircuitOpen 提供商处于熔断状态
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+// embeddingSourceKey 上下文中应答来源记录的键
+type embeddingSourceKey struct{}
+
+// embeddingSource 记录一次 EmbedStrings 调用的向量是否来自备用提供商。
+// 备用提供商的向量空间与主提供商不同，缓存层据此跳过写入，避免以主模型的缓存键保存。
+type embeddingSource struct {
+	fallback atomic.Bool
+}
+
+// withEmbeddingSource 返回带有新应答来源记录的上下文
+func withEmbeddingSource(ctx context.Context) (context.Context, *embeddingSource) {
+	source := &embeddingSource{}
+	return context.WithValue(ctx, embeddingSourceKey{}, source), source
+}
+
+// markEmbeddingFallback 在上下文的应答来源记录中标记向量来自备用提供商
+func markEmbeddingFallback(ctx context.Context) {
+	if source, ok := ctx.Value(embeddingSourceKey{}).(*embeddingSource); ok {
+		source.fallback.Store(true)
+	}
+}
+
+// dimensionProbeText 启动时探测向量维度使用的文本
+const dimensionProbeText = "dimension probe"
+
+// 熔断器状态
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+// ResilientEmbedder 为 Embedder 增加重试、熔断和故障转移。
+// 每个提供商对可重试错误（限流、5xx、超时、连接错误）按指数退避加抖动重试，
+// 重试耗尽的连续失败达到阈值后熔断；主提供商失败或熔断时按顺序尝试备用提供商。
+// 备用提供商应答时在上下文的应答来源记录中标记，缓存层不缓存这些向量。
+type ResilientEmbedder struct {
+	providers      []*resilientProvider
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// resilientProvider 表示一个带熔断器的提供商
+type resilientProvider struct {
+	name       string
+	embedder   embedding.Embedder
+	retryTimes int
+
+	mu               sync.Mutex
+	state            string
+	failures         int
+	openedAt         time.Time
+	failureThreshold int
+	openDuration     time.Duration
+
+	calls     int64
+	retries   int64
+	errors    int64
+	rejected  int64
+	failovers int64
+}
+
+// NewResilientEmbedder 根据配置创建带重试、熔断和故障转移的 Embedder。
+// 备用提供商的向量维度在创建时校验：两侧都配置了 dimensions 时直接比较，否则各调用一次提供商探测。
+// 参数 ctx: 上下文对象。
+// 参数 embedder: 主提供商的 Embedder。
+// 参数 cfg: 主提供商的 Embedder 配置（Resilience 字段为重试、熔断和备用提供商配置）。
+// 返回: ResilientEmbedder 指针，备用提供商初始化失败或维度不一致时返回错误。
+func NewResilientEmbedder(ctx context.Context, embedder embedding.Embedder, cfg *config.EmbedderConfig) (*ResilientEmbedder, error) {
+	rcfg := &cfg.Resilience
+	e := &ResilientEmbedder{
+		initialBackoff: rcfg.InitialBackoff,
+		maxBackoff:     rcfg.MaxBackoff,
+	}
+	if e.initialBackoff <= 0 {
+		e.initialBackoff = 200 * time.Millisecond
+	}
+	retryTimes := 2
+	if cfg.RetryTimes != nil {
+		retryTimes = *cfg.RetryTimes
+	}
+	e.providers = append(e.providers, newResilientProvider(embedder, cfg, rcfg, retryTimes))
+
+	var dimensions int
+	for i := range rcfg.Fallbacks {
+		fallbackCfg := &rcfg.Fallbacks[i]
+		fallback, err := newProviderEmbedder(ctx, fallbackCfg)
+		if err != nil {
+			return nil, fmt.Errorf("fallback embedder %d (%s): %w", i, fallbackCfg.Provider, err)
+		}
+
+		// 校验备用提供商与主提供商的向量维度
+		if dimensions == 0 {
+			if dimensions, err = embeddingDimensions(ctx, embedder, cfg); err != nil {
+				return nil, err
+			}
+		}
+		fallbackDimensions, err := embeddingDimensions(ctx, fallback, fallbackCfg)
+		if err != nil {
+			return nil, err
+		}
+		if fallbackDimensions != dimensions {
+			return nil, fmt.Errorf("fallback embedder %d (%s:%s) produces %d dimensions, primary produces %d",
+				i, fallbackCfg.Provider, fallbackCfg.Model, fallbackDimensions, dimensions)
+		}
+
+		// 备用提供商未设置重试次数时沿用主提供商的设置
+		fallbackRetryTimes := retryTimes
+		if fallbackCfg.RetryTimes != nil {
+			fallbackRetryTimes = *fallbackCfg.RetryTimes
+		}
+		e.providers = append(e.providers, newResilientProvider(fallback, fallbackCfg, rcfg, fallbackRetryTimes))
+	}
+	return e, nil
+}
+
+// EmbedStrings 按顺序调用提供商，返回第一个成功的结果。
+// 调用方上下文取消时立即返回；所有提供商失败时返回包装了 ErrEmbeddingUnavailable 的错误。
+func (e *ResilientEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	var errs []error
+	for i, provider := range e.providers {
+		if i > 0 {
+			e.providers[i-1].recordFailover()
+		}
+		vectors, err := e.invoke(ctx, provider, texts, opts...)
+		if err == nil {
+			if i > 0 {
+				markEmbeddingFallback(ctx)
+			}
+			return vectors, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.name, err))
+	}
+	return nil, fmt.Errorf("%w: %w", ErrEmbeddingUnavailable, errors.Join(errs...))
+}
+
+// GetMetrics 获取各提供商的调用统计和熔断状态。
+// 返回一个以提供商名称为键的 Map，包含调用数、重试数、失败数、熔断拒绝数、故障转移数和熔断器状态。
+func (e *ResilientEmbedder) GetMetrics() map[string]interface{} {
+	metrics := make(map[string]interface{}, len(e.providers))
+	for i, provider := range e.providers {
+		provider.mu.Lock()
+		metrics[fmt.Sprintf("%d:%s", i, provider.name)] = map[string]interface{}{
+			"calls":     provider.calls,
+			"retries":   provider.retries,
+			"errors":    provider.errors,
+			"rejected":  provider.rejected,
+			"failovers": provider.failovers,
+			"state":     provider.state,
+		}
+		provider.mu.Unlock()
+	}
+	return metrics
+}
+
+// invoke 在熔断器允许时调用提供商，对可重试错误按退避间隔重试
+func (e *ResilientEmbedder) invoke(ctx context.Context, provider *resilientProvider, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	if !provider.allow() {
+		return nil, errCircuitOpen
+	}
+
+	backoff := e.initialBackoff
+	for attempt := 0; ; attempt++ {
+		vectors, err := provider.embedder.EmbedStrings(ctx, texts, opts...)
+		if err == nil {
+			provider.recordSuccess()
+			return vectors, nil
+		}
+		if ctx.Err() != nil {
+			// 调用方取消不计入熔断
+			provider.release()
+			return nil, err
+		}
+		if !retryableEmbeddingError(err) {
+			provider.recordError(false)
+			return nil, err
+		}
+		if attempt >= provider.retryTimes {
+			provider.recordError(true)
+			return nil, err
+		}
+
+		provider.recordRetry()
+		select {
+		case <-ctx.Done():
+			provider.release()
+			return nil, ctx.Err()
+		case <-time.After(jitter(backoff)):
+		}
+		backoff = nextEmbeddingBackoff(backoff, e.maxBackoff)
+	}
+}
+
+// newResilientProvider 创建带熔断器的提供商
+func newResilientProvider(embedder embedding.Embedder, cfg *config.EmbedderConfig, rcfg *config.EmbeddingResilienceConfig, retryTimes int) *resilientProvider {
+	failureThreshold := rcfg.FailureThreshold
+	if failureThreshold <= 0 {
+		failureThreshold = 5
+	}
+	openDuration := rcfg.OpenDuration
+	if openDuration <= 0 {
+		openDuration = 30 * time.Second
+	}
+	return &resilientProvider{
+		name:             cfg.Provider + ":" + cfg.Model,
+		embedder:         embedder,
+		retryTimes:       retryTimes,
+		state:            breakerClosed,
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+	}
+}
+
+// allow 判断熔断器是否放行本次调用（熔断到期后只放行一次试探调用）
+func (p *resilientProvider) allow() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch p.state {
+	case breakerOpen:
+		if time.Since(p.openedAt) < p.openDuration {
+			p.rejected++
+			return false
+		}
+		p.state = breakerHalfOpen
+	case breakerHalfOpen:
+		// 试探调用进行中
+		p.rejected++
+		return false
+	}
+	p.calls++
+	return true
+}
+
+// recordSuccess 记录成功调用并关闭熔断器
+func (p *resilientProvider) recordSuccess() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = breakerClosed
+	p.failures = 0
+}
+
+// recordError 记录失败调用；可重试错误（提供商故障）计入熔断。
+// 试探调用返回不可重试错误时提供商已恢复响应，关闭熔断器并清零连续失败次数。
+func (p *resilientProvider) recordError(providerFault bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errors++
+	if !providerFault {
+		if p.state == breakerHalfOpen {
+			p.state = breakerClosed
+			p.failures = 0
+		}
+		return
+	}
+	p.failures++
+	if p.state == breakerHalfOpen || p.failures >= p.failureThreshold {
+		p.state = breakerOpen
+		p.openedAt = time.Now()
+	}
+}
+
+// release 调用方取消时释放试探调用的名额
+func (p *resilientProvider) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == breakerHalfOpen {
+		p.state = breakerOpen
+	}
+}
+
+// recordRetry 记录一次重试
+func (p *resilientProvider) recordRetry() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retries++
+}
+
+// recordFailover 记录一次转移到下一个提供商
+func (p *resilientProvider) recordFailover() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failovers++
+}
+
+// statusCodePattern 匹配错误信息中的 HTTP 状态码（如 go-openai 的 "status code: 429"）
+var statusCodePattern = regexp.MustCompile(`status code: (\d{3})`)
+
+// retryableMessages 视为临时故障的错误信息片段
+var retryableMessages = []string{
+	"rate limit", "too many requests", "timeout", "deadline exceeded",
+	"connection reset", "connection refused", "temporarily unavailable", "unexpected eof",
+}
+
+// retryableEmbeddingError 判断错误是否为可重试的临时故障（限流、5xx、超时、连接错误）
+func retryableEmbeddingError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	if match := statusCodePattern.FindStringSubmatch(message); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code == 429 || code == 408 || code >= 500
+	}
+	for _, fragment := range retryableMessages {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddingDimensions 返回提供商的向量维度（优先使用配置值，否则调用一次提供商探测）
+func embeddingDimensions(ctx context.Context, embedder embedding.Embedder, cfg *config.EmbedderConfig) (int, error) {
+	if cfg.Dimensions != nil {
+		return *cfg.Dimensions, nil
+	}
+	vectors, err := embedder.EmbedStrings(ctx, []string{dimensionProbeText})
+	if err != nil {
+		return 0, fmt.Errorf("probe %s:%s dimensions (set dimensions to skip the probe): %w", cfg.Provider, cfg.Model, err)
+	}
+	if len(vectors) != 1 || len(vectors[0]) == 0 {
+		return 0, fmt.Errorf("probe %s:%s dimensions: empty embedding", cfg.Provider, cfg.Model)
+	}
+	return len(vectors[0]), nil
+}
+
+// jitter 返回 [d/2, d) 之间的随机间隔
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
+
+// nextEmbeddingBackoff 计算下一次重试的退避间隔（翻倍，不超过上限）
+func nextEmbeddingBackoff(current, limit time.Duration) time.Duration {
+	next := current * 2
+	if limit > 0 && next > limit {
+		return limit
+	}
+	return next
+}
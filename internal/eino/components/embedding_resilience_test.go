@@ -0,0 +1,187 @@
+package components
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+
+	"llm-cache/internal/eino/config"
+)
+
+// flakyEmbedder 前 failures 次调用返回 err，之后返回固定向量
+type flakyEmbedder struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (e *flakyEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	e.calls++
+	if e.calls <= e.failures {
+		return nil, e.err
+	}
+	vectors := make([][]float64, len(texts))
+	for i := range texts {
+		vectors[i] = []float64{1, 2}
+	}
+	return vectors, nil
+}
+
+func testResilientEmbedder(embedders ...embedding.Embedder) *ResilientEmbedder {
+	rcfg := &config.EmbeddingResilienceConfig{FailureThreshold: 2, OpenDuration: time.Hour}
+	e := &ResilientEmbedder{initialBackoff: time.Millisecond, maxBackoff: 2 * time.Millisecond}
+	for i, embedder := range embedders {
+		cfg := &config.EmbedderConfig{Provider: fmt.Sprintf("p%d", i)}
+		e.providers = append(e.providers, newResilientProvider(embedder, cfg, rcfg, 2))
+	}
+	return e
+}
+
+func TestResilientEmbedderRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"retries rate limit", 2, errors.New("error, status code: 429, status: Too Many Requests"), 3, false},
+		{"gives up after retries", 5, errors.New("error, status code: 503, status: Service Unavailable"), 3, true},
+		{"does not retry bad request", 5, errors.New("error, status code: 400, status: Bad Request"), 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &flakyEmbedder{failures: tt.failures, err: tt.err}
+			e := testResilientEmbedder(inner)
+			_, err := e.EmbedStrings(context.Background(), []string{"q"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected err=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr && !errors.Is(err, ErrEmbeddingUnavailable) {
+				t.Errorf("expected %v, got %v", ErrEmbeddingUnavailable, err)
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, inner.calls)
+			}
+		})
+	}
+}
+
+func TestResilientEmbedderFailover(t *testing.T) {
+	primary := &flakyEmbedder{failures: 100, err: errors.New("connection refused")}
+	fallback := &flakyEmbedder{}
+	e := testResilientEmbedder(primary, fallback)
+
+	// 连续两次重试耗尽后熔断，之后直接转移到备用提供商
+	for i := 0; i < 3; i++ {
+		vectors, err := e.EmbedStrings(context.Background(), []string{"q"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(vectors) != 1 || len(vectors[0]) != 2 {
+			t.Errorf("unexpected vectors: %v", vectors)
+		}
+	}
+	if primary.calls != 6 {
+		t.Errorf("expected primary to be skipped after opening, got %d calls", primary.calls)
+	}
+	if fallback.calls != 3 {
+		t.Errorf("expected 3 fallback calls, got %d", fallback.calls)
+	}
+
+	metrics := e.GetMetrics()["0:p0:"].(map[string]interface{})
+	if metrics["state"] != breakerOpen || metrics["rejected"] != int64(1) || metrics["failovers"] != int64(3) {
+		t.Errorf("unexpected metrics: %v", metrics)
+	}
+}
+
+func TestResilientEmbedderCancel(t *testing.T) {
+	primary := &flakyEmbedder{failures: 100, err: errors.New("timeout")}
+	fallback := &flakyEmbedder{}
+	e := testResilientEmbedder(primary, fallback)
+	e.initialBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := e.EmbedStrings(ctx, []string{"q"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if fallback.calls != 0 {
+		t.Errorf("expected no failover after cancellation, got %d calls", fallback.calls)
+	}
+}
+
+func TestResilientProviderHalfOpenRecovery(t *testing.T) {
+	p := testResilientEmbedder(&flakyEmbedder{}).providers[0]
+	p.recordError(true)
+	p.recordError(true)
+	if p.state != breakerOpen {
+		t.Fatalf("expected %v, got %v", breakerOpen, p.state)
+	}
+
+	// 熔断到期后的试探调用返回不可重试错误：熔断器关闭且连续失败次数清零
+	p.openedAt = time.Now().Add(-2 * p.openDuration)
+	if !p.allow() {
+		t.Fatalf("expected probe call to be allowed")
+	}
+	p.recordError(false)
+	if p.state != breakerClosed || p.failures != 0 {
+		t.Fatalf("expected closed breaker without failures, got state=%v failures=%d", p.state, p.failures)
+	}
+
+	// 之后单次可重试错误不会立即重新熔断
+	p.recordError(true)
+	if p.state != breakerClosed {
+		t.Errorf("expected %v, got %v", breakerClosed, p.state)
+	}
+}
+
+func TestNewResilientEmbedderDimensions(t *testing.T) {
+	dims := func(n int) *int { return &n }
+	cfg := &config.EmbedderConfig{
+		Provider:   "openai",
+		Model:      "text-embedding-3-small",
+		Dimensions: dims(1536),
+		Resilience: config.EmbeddingResilienceConfig{
+			Enabled:   true,
+			Fallbacks: []config.EmbedderConfig{{Provider: "openai", Model: "text-embedding-3-large", Dimensions: dims(3072)}},
+		},
+	}
+	if _, err := NewResilientEmbedder(context.Background(), &flakyEmbedder{}, cfg); err == nil {
+		t.Errorf("expected dimension mismatch error")
+	}
+
+	cfg.Resilience.Fallbacks[0].Dimensions = dims(1536)
+	e, err := NewResilientEmbedder(context.Background(), &flakyEmbedder{}, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(e.providers) != 2 {
+		t.Errorf("expected 2 providers, got %d", len(e.providers))
+	}
+}
+
+func TestRetryableEmbeddingError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("error, status code: 429, status: Too Many Requests"), true},
+		{errors.New("error, status code: 502, status: Bad Gateway"), true},
+		{errors.New("error, status code: 401, status: Unauthorized"), false},
+		{fmt.Errorf("post: %w", context.DeadlineExceeded), true},
+		{context.Canceled, false},
+		{errors.New("read tcp: connection reset by peer"), true},
+		{errors.New("invalid input"), false},
+	}
+	for _, tt := range tests {
+		if got := retryableEmbeddingError(tt.err); got != tt.want {
+			t.Errorf("%v: expected %v, got %v", tt.err, tt.want, got)
+		}
+	}
+}
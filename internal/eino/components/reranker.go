@@ -0,0 +1,259 @@
+// Package components 提供 Eino 组件的工厂函数
+package components
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// RerankScoreKey 重排序分数在文档元数据中的键名
+const RerankScoreKey = "rerank_score"
+
+// Reranker 定义重排序组件接口。
+// 实现需为每个返回的文档写入 MetaData[RerankScoreKey]，并按分数从高到低返回。
+type Reranker interface {
+	Rerank(ctx context.Context, query string, docs []*schema.Document) ([]*schema.Document, error)
+}
+
+// NewReranker 根据配置创建并返回一个 Reranker 实例。
+// 支持 http（Cohere/Jina 风格 Rerank API）和 lexical（内置词重叠）两种提供商。
+// 参数 ctx: 上下文对象。
+// 参数 cfg: Reranker 配置。
+// 返回: 初始化后的 Reranker 实例，如果提供商不支持则返回错误。
+func NewReranker(ctx context.Context, cfg *config.RerankerConfig) (Reranker, error) {
+	switch cfg.Provider {
+	case "http":
+		return newHTTPReranker(cfg)
+	case "lexical", "":
+		return &lexicalReranker{topN: cfg.TopN}, nil
+	default:
+		return nil, fmt.Errorf("unsupported reranker provider: %s", cfg.Provider)
+	}
+}
+
+// rerankDocumentText 提取用于重排序的文档文本（优先使用 Content，其次为 question 元数据）
+func rerankDocumentText(doc *schema.Document) string {
+	if doc.Content != "" {
+		return doc.Content
+	}
+	if question, ok := doc.MetaData["question"].(string); ok {
+		return question
+	}
+	return ""
+}
+
+// setRerankScore 将重排序分数写入文档元数据
+func setRerankScore(doc *schema.Document, score float64) {
+	if doc.MetaData == nil {
+		doc.MetaData = make(map[string]any)
+	}
+	doc.MetaData[RerankScoreKey] = score
+}
+
+// sortByRerankScore 按重排序分数降序排序，并按 topN 截断
+func sortByRerankScore(docs []*schema.Document, topN int) []*schema.Document {
+	sort.SliceStable(docs, func(i, j int) bool {
+		si, _ := docs[i].MetaData[RerankScoreKey].(float64)
+		sj, _ := docs[j].MetaData[RerankScoreKey].(float64)
+		return si > sj
+	})
+	if topN > 0 && len(docs) > topN {
+		docs = docs[:topN]
+	}
+	return docs
+}
+
+// httpReranker 调用 Cohere/Jina 风格的 Rerank API（POST {base_url}/rerank）
+type httpReranker struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+	model    string
+	topN     int
+}
+
+// httpRerankRequest Rerank API 请求体
+type httpRerankRequest struct {
+	Model           string   `json:"model,omitempty"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n,omitempty"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
+
+// httpRerankResponse Rerank API 响应体
+type httpRerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// newHTTPReranker 创建 HTTP Rerank API 客户端
+func newHTTPReranker(cfg *config.RerankerConfig) (*httpReranker, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("reranker base_url is required for http provider")
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &httpReranker{
+		client:   &http.Client{Timeout: timeout},
+		endpoint: strings.TrimRight(cfg.BaseURL, "/") + "/rerank",
+		apiKey:   cfg.APIKey,
+		model:    cfg.Model,
+		topN:     cfg.TopN,
+	}, nil
+}
+
+// Rerank 调用远程 Rerank API 对候选文档重新打分
+func (r *httpReranker) Rerank(ctx context.Context, query string, docs []*schema.Document) ([]*schema.Document, error) {
+	if len(docs) == 0 {
+		return docs, nil
+	}
+
+	reqBody := httpRerankRequest{
+		Model:     r.model,
+		Query:     query,
+		Documents: make([]string, len(docs)),
+		TopN:      r.topN,
+	}
+	for i, doc := range docs {
+		reqBody.Documents[i] = rerankDocumentText(doc)
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal rerank request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create rerank request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("call rerank api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read rerank response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank api returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result httpRerankResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal rerank response: %w", err)
+	}
+
+	// 仅保留 API 返回的文档（top_n 之外的文档视为被过滤）
+	reranked := make([]*schema.Document, 0, len(result.Results))
+	for _, item := range result.Results {
+		if item.Index < 0 || item.Index >= len(docs) {
+			return nil, fmt.Errorf("rerank api returned invalid index %d", item.Index)
+		}
+		doc := docs[item.Index]
+		setRerankScore(doc, item.RelevanceScore)
+		reranked = append(reranked, doc)
+	}
+
+	return sortByRerankScore(reranked, r.topN), nil
+}
+
+// lexicalReranker 内置词重叠重排序器。
+// 使用 Dice 系数衡量查询与文档的词项重叠程度，中文按相邻字二元组切分。
+type lexicalReranker struct {
+	topN int
+}
+
+// Rerank 基于词项重叠对候选文档重新打分
+func (r *lexicalReranker) Rerank(ctx context.Context, query string, docs []*schema.Document) ([]*schema.Document, error) {
+	queryTerms := lexicalTerms(query)
+	for _, doc := range docs {
+		setRerankScore(doc, diceCoefficient(queryTerms, lexicalTerms(rerankDocumentText(doc))))
+	}
+	return sortByRerankScore(docs, r.topN), nil
+}
+
+// lexicalTerms 将文本切分为词项集合。
+// 字母数字按连续片段切分并转小写；汉字按相邻二元组切分（单字片段保留单字）。
+func lexicalTerms(text string) map[string]struct{} {
+	terms := make(map[string]struct{})
+	var word []rune
+	var han []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			terms[strings.ToLower(string(word))] = struct{}{}
+			word = word[:0]
+		}
+	}
+	flushHan := func() {
+		switch {
+		case len(han) == 1:
+			terms[string(han)] = struct{}{}
+		case len(han) > 1:
+			for i := 0; i+1 < len(han); i++ {
+				terms[string(han[i:i+2])] = struct{}{}
+			}
+		}
+		han = han[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+
+	return terms
+}
+
+// diceCoefficient 计算两个词项集合的 Dice 系数（0-1）
+func diceCoefficient(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	overlap := 0
+	for term := range a {
+		if _, ok := b[term]; ok {
+			overlap++
+		}
+	}
+	return 2 * float64(overlap) / float64(len(a)+len(b))
+}
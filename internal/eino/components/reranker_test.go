@@ -0,0 +1,149 @@
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// newRerankStub 创建模拟 Cohere/Jina Rerank API 的本地服务，按给定分数返回结果
+func newRerankStub(t *testing.T, scores []float64) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rerank" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req httpRerankRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		type item struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		}
+		results := make([]item, 0, len(req.Documents))
+		for i := range req.Documents {
+			results = append(results, item{Index: i, RelevanceScore: scores[i]})
+		}
+		if req.TopN > 0 && len(results) > req.TopN {
+			results = results[:req.TopN]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": results})
+	}))
+}
+
+func TestHTTPReranker(t *testing.T) {
+	server := newRerankStub(t, []float64{0.2, 0.9, 0.5})
+	defer server.Close()
+
+	reranker, err := NewReranker(context.Background(), &config.RerankerConfig{
+		Provider: "http",
+		BaseURL:  server.URL,
+		APIKey:   "test-key",
+		Model:    "rerank-v1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	docs := []*schema.Document{
+		{ID: "a", Content: "如何重置密码"},
+		{ID: "b", Content: "如何修改密码"},
+		{ID: "c", MetaData: map[string]any{"question": "忘记密码怎么办"}},
+	}
+	result, err := reranker.Rerank(context.Background(), "怎么修改密码", docs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantOrder := []string{"b", "c", "a"}
+	for i, id := range wantOrder {
+		if result[i].ID != id {
+			t.Errorf("expected %s at position %d, got %s", id, i, result[i].ID)
+		}
+	}
+	if result[0].MetaData[RerankScoreKey] != 0.9 {
+		t.Errorf("expected rerank score 0.9, got %v", result[0].MetaData[RerankScoreKey])
+	}
+}
+
+func TestHTTPReranker_Error(t *testing.T) {
+	server := newRerankStub(t, nil)
+	defer server.Close()
+
+	reranker, err := NewReranker(context.Background(), &config.RerankerConfig{
+		Provider: "http",
+		BaseURL:  server.URL,
+		APIKey:   "wrong-key",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = reranker.Rerank(context.Background(), "query", []*schema.Document{{ID: "a", Content: "text"}})
+	if err == nil {
+		t.Error("expected error for unauthorized request")
+	}
+}
+
+func TestLexicalReranker(t *testing.T) {
+	reranker, err := NewReranker(context.Background(), &config.RerankerConfig{Provider: "lexical", TopN: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	docs := []*schema.Document{
+		{ID: "unrelated", Content: "今天天气怎么样"},
+		{ID: "exact", Content: "How to reset my password?"},
+		{ID: "partial", Content: "reset the router"},
+	}
+	result, err := reranker.Rerank(context.Background(), "how to reset password", docs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("expected 2 docs after top_n, got %d", len(result))
+	}
+	if result[0].ID != "exact" || result[1].ID != "partial" {
+		t.Errorf("expected [exact partial], got [%s %s]", result[0].ID, result[1].ID)
+	}
+}
+
+func TestLexicalTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Reset PASSWORD", want: []string{"reset", "password"}},
+		{text: "修改密码", want: []string{"修改", "改密", "密码"}},
+		{text: "iPhone 15 怎么用", want: []string{"iphone", "15", "怎么", "么用"}},
+	}
+
+	for _, tt := range tests {
+		terms := lexicalTerms(tt.text)
+		if len(terms) != len(tt.want) {
+			t.Errorf("text %q: expected %d terms, got %d", tt.text, len(tt.want), len(terms))
+			continue
+		}
+		for _, term := range tt.want {
+			if _, ok := terms[term]; !ok {
+				t.Errorf("text %q: expected term %q", tt.text, term)
+			}
+		}
+	}
+}
@@ -3,8 +3,12 @@ package components
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"math"
+	"slices"
+	"strings"
 
 	es8retriever "github.com/cloudwego/eino-ext/components/retriever/es8"
 	milvusretriever "github.com/cloudwego/eino-ext/components/retriever/milvus"
@@ -18,6 +22,7 @@ import (
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
 	milvusClient "github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 	qdrantClient "github.com/qdrant/go-client/qdrant"
 	"github.com/redis/go-redis/v9"
 
@@ -31,20 +36,33 @@ import (
 // 参数 embedder: 用于生成查询向量的 Embedder 实例，在检索过程中需要使用它来向量化查询文本。
 // 返回: 初始化后的 Retriever 实例，如果后端不支持或初始化失败则返回错误。
 func NewRetriever(ctx context.Context, cfg *config.RetrieverConfig, embedder embedding.Embedder) (retriever.Retriever, error) {
+	// 解析后端分数的度量类型，用于统一换算为余弦相似度
+	metric, err := resolveScoreMetric(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var inner retriever.Retriever
 	switch cfg.Provider {
 	case "qdrant":
-		return newQdrantRetriever(ctx, cfg, embedder)
+		inner, err = newQdrantRetriever(ctx, cfg, embedder)
 	case "milvus":
-		return newMilvusRetriever(ctx, cfg, embedder)
+		inner, err = newMilvusRetriever(ctx, cfg, embedder, metric)
 	case "redis":
-		return newRedisRetriever(ctx, cfg, embedder)
+		inner, err = newRedisRetriever(ctx, cfg, embedder)
 	case "es8":
-		return newES8Retriever(ctx, cfg, embedder)
+		inner, err = newES8Retriever(ctx, cfg, embedder)
 	case "vikingdb":
-		return newVikingDBRetriever(ctx, cfg, embedder)
+		inner, err = newVikingDBRetriever(ctx, cfg, embedder)
 	default:
 		return nil, fmt.Errorf("unsupported retriever provider: %s", cfg.Provider)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 阈值统一由归一化层在换算后应用，各后端不再接收阈值
+	return newScoreNormalizingRetriever(inner, embedder, metric, cfg.ScoreThreshold), nil
 }
 
 // newQdrantRetriever 创建 Qdrant Retriever
@@ -76,17 +94,11 @@ func newQdrantRetriever(ctx context.Context, cfg *config.RetrieverConfig, embedd
 		TopK:       cfg.TopK,
 	}
 
-	// 设置相似度阈值
-	if cfg.ScoreThreshold > 0 {
-		threshold := cfg.ScoreThreshold
-		retrieverCfg.ScoreThreshold = &threshold
-	}
-
 	return qdrantretriever.NewRetriever(ctx, retrieverCfg)
 }
 
 // newMilvusRetriever 创建 Milvus Retriever
-func newMilvusRetriever(ctx context.Context, cfg *config.RetrieverConfig, embedder embedding.Embedder) (retriever.Retriever, error) {
+func newMilvusRetriever(ctx context.Context, cfg *config.RetrieverConfig, embedder embedding.Embedder, metric scoreMetric) (retriever.Retriever, error) {
 	// 创建 Milvus 客户端
 	client, err := milvusClient.NewClient(ctx, milvusClient.Config{
 		Address:  fmt.Sprintf("%s:%d", cfg.Milvus.Host, cfg.Milvus.Port),
@@ -97,46 +109,161 @@ func newMilvusRetriever(ctx context.Context, cfg *config.RetrieverConfig, embedd
 		return nil, fmt.Errorf("failed to create milvus client: %w", err)
 	}
 
+	vectorField := cfg.Milvus.VectorField
+	if vectorField == "" {
+		vectorField = "vector"
+	}
+
 	// 创建 Retriever 配置
 	retrieverCfg := &milvusretriever.RetrieverConfig{
-		Client:         client,
-		Collection:     cfg.Collection,
-		VectorField:    cfg.Milvus.VectorField,
-		OutputFields:   cfg.Milvus.OutputFields,
-		TopK:           cfg.TopK,
-		ScoreThreshold: cfg.ScoreThreshold,
-		Embedding:      embedder,
+		Client:            client,
+		Collection:        cfg.Collection,
+		VectorField:       vectorField,
+		OutputFields:      cfg.Milvus.OutputFields,
+		TopK:              cfg.TopK,
+		Embedding:         embedder,
+		DocumentConverter: milvusDocumentConverter(vectorField),
+	}
+
+	if metric == metricExact {
+		// 二进制向量（HAMMING）模式：返回向量字段，由归一化层直接计算余弦相似度
+		retrieverCfg.MetricType = entity.HAMMING
+		if !slices.Contains(retrieverCfg.OutputFields, vectorField) {
+			retrieverCfg.OutputFields = append(slices.Clone(retrieverCfg.OutputFields), vectorField)
+		}
+	} else {
+		// 浮点向量模式：使用配置的度量类型和 AUTOINDEX 搜索参数
+		retrieverCfg.MetricType = entity.MetricType(strings.ToUpper(cfg.Milvus.MetricType))
+		retrieverCfg.VectorConverter = milvusFloatVectorConverter
+		sp, err := entity.NewIndexAUTOINDEXSearchParam(1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create milvus search param: %w", err)
+		}
+		retrieverCfg.Sp = sp
 	}
 
 	return milvusretriever.NewRetriever(ctx, retrieverCfg)
 }
 
+// milvusFloatVectorConverter 将查询向量转换为 Milvus 浮点向量
+func milvusFloatVectorConverter(ctx context.Context, vectors [][]float64) ([]entity.Vector, error) {
+	result := make([]entity.Vector, 0, len(vectors))
+	for _, vector := range vectors {
+		vec := make([]float32, len(vector))
+		for i, v := range vector {
+			vec[i] = float32(v)
+		}
+		result = append(result, entity.FloatVector(vec))
+	}
+	return result, nil
+}
+
+// milvusDocumentConverter 返回 Milvus 搜索结果转换函数。
+// 与 Eino 默认实现一致地解析 id/content/metadata 字段，并额外写入原始分数和向量字段。
+func milvusDocumentConverter(vectorField string) func(ctx context.Context, result milvusClient.SearchResult) ([]*schema.Document, error) {
+	return func(ctx context.Context, result milvusClient.SearchResult) ([]*schema.Document, error) {
+		docs := make([]*schema.Document, result.IDs.Len())
+		for i := range docs {
+			id, err := result.IDs.GetAsString(i)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get id: %w", err)
+			}
+			docs[i] = &schema.Document{ID: id, MetaData: make(map[string]any)}
+			if i < len(result.Scores) {
+				docs[i].WithScore(float64(result.Scores[i]))
+			}
+		}
+
+		for _, field := range result.Fields {
+			for i, doc := range docs {
+				switch field.Name() {
+				case "id":
+				case "content":
+					content, err := field.GetAsString(i)
+					if err != nil {
+						return nil, fmt.Errorf("failed to get content: %w", err)
+					}
+					doc.Content = content
+				case "metadata":
+					value, err := field.Get(i)
+					if err != nil {
+						return nil, fmt.Errorf("failed to get metadata: %w", err)
+					}
+					if raw, ok := value.([]byte); ok {
+						if err := json.Unmarshal(raw, &doc.MetaData); err != nil {
+							return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+						}
+					}
+				case vectorField:
+					value, err := field.Get(i)
+					if err != nil {
+						return nil, fmt.Errorf("failed to get vector: %w", err)
+					}
+					doc.WithDenseVector(decodeMilvusVector(value))
+				default:
+					value, err := field.GetAsString(i)
+					if err != nil {
+						return nil, fmt.Errorf("failed to get field %s: %w", field.Name(), err)
+					}
+					doc.MetaData[field.Name()] = value
+				}
+			}
+		}
+		return docs, nil
+	}
+}
+
+// decodeMilvusVector 解码 Milvus 返回的向量字段。
+// 二进制向量按 Eino Milvus Indexer 的编码方式视为小端序 float32 字节。
+func decodeMilvusVector(value any) []float64 {
+	switch v := value.(type) {
+	case []float32:
+		vector := make([]float64, len(v))
+		for i, f := range v {
+			vector[i] = float64(f)
+		}
+		return vector
+	case []byte:
+		vector := make([]float64, len(v)/4)
+		for i := range vector {
+			vector[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(v[i*4:])))
+		}
+		return vector
+	default:
+		return nil
+	}
+}
+
 // newRedisRetriever 创建 Redis Retriever
 func newRedisRetriever(ctx context.Context, cfg *config.RetrieverConfig, embedder embedding.Embedder) (retriever.Retriever, error) {
 	// 创建 Redis 客户端
 	// 注意：需要配置 Protocol: 2 和 UnstableResp3: true 以支持 FT.SEARCH
 	rdb := redis.NewClient(&redis.Options{
-		Addr:         cfg.Redis.Addr,
-		Password:     cfg.Redis.Password,
-		DB:           cfg.Redis.DB,
-		Protocol:     2,
+		Addr:          cfg.Redis.Addr,
+		Password:      cfg.Redis.Password,
+		DB:            cfg.Redis.DB,
+		Protocol:      2,
 		UnstableResp3: true,
 	})
 
+	// 返回字段需包含 KNN 距离，供归一化层换算相似度
+	returnFields := cfg.Redis.ReturnFields
+	if len(returnFields) == 0 {
+		returnFields = []string{"content"}
+	}
+	if !slices.Contains(returnFields, redisretriever.SortByDistanceAttributeName) {
+		returnFields = append(slices.Clone(returnFields), redisretriever.SortByDistanceAttributeName)
+	}
+
 	// 创建 Retriever 配置
+	// 注意：不设置 DistanceThreshold（其语义为距离而非相似度），阈值由归一化层统一应用
 	retrieverCfg := &redisretriever.RetrieverConfig{
 		Client:       rdb,
 		Index:        cfg.Redis.Index,
 		VectorField:  cfg.Redis.VectorField,
 		TopK:         cfg.TopK,
 		Embedding:    embedder,
-		ReturnFields: cfg.Redis.ReturnFields,
-	}
-
-	// 设置距离阈值（用于范围搜索）
-	if cfg.ScoreThreshold > 0 {
-		threshold := cfg.ScoreThreshold
-		retrieverCfg.DistanceThreshold = &threshold
+		ReturnFields: returnFields,
 	}
 
 	return redisretriever.NewRetriever(ctx, retrieverCfg)
@@ -155,7 +282,10 @@ func newES8Retriever(ctx context.Context, cfg *config.RetrieverConfig, embedder
 	}
 
 	// 解析搜索模式
-	searchMode := parseES8SearchMode(cfg.ES8.SearchMode, cfg.ES8.VectorField)
+	searchMode, err := parseES8SearchMode(&cfg.ES8)
+	if err != nil {
+		return nil, err
+	}
 
 	// 创建 Retriever 配置
 	retrieverCfg := &es8retriever.RetrieverConfig{
@@ -164,21 +294,40 @@ func newES8Retriever(ctx context.Context, cfg *config.RetrieverConfig, embedder
 		TopK:         cfg.TopK,
 		Embedding:    embedder,
 		SearchMode:   searchMode,
-		ResultParser: defaultES8ResultParser,
-	}
-
-	// 设置相似度阈值
-	if cfg.ScoreThreshold > 0 {
-		threshold := cfg.ScoreThreshold
-		retrieverCfg.ScoreThreshold = &threshold
+		ResultParser: newES8ResultParser(cfg.ES8.VectorField),
 	}
 
 	return es8retriever.NewRetriever(ctx, retrieverCfg)
 }
 
+// RetrieveOptions 定义本项目 Retriever 的实现专用选项。
+// 通过 retriever.GetImplSpecificOptions 读取，用于在单次检索中传递请求级过滤条件。
+type RetrieveOptions struct {
+	// UserType 仅检索该用户类型下的缓存项，为空时不过滤
+	UserType string
+}
+
+// WithUserType 设置检索时的 user_type 过滤条件。
+// 参数 userType: 用户类型标识，为空时不过滤。
+// 返回: 可传递给 Retriever.Retrieve 的选项。
+func WithUserType(userType string) retriever.Option {
+	return retriever.WrapImplSpecificOptFn(func(o *RetrieveOptions) {
+		o.UserType = userType
+	})
+}
+
+// WithQueryVector 使用预先计算的查询向量检索，避免重复调用 Embedding 服务。
+// 参数 vector: 查询文本的向量。
+// 返回: 可传递给 Retriever.Retrieve 的选项。
+func WithQueryVector(vector []float64) retriever.Option {
+	return retriever.WithEmbedding(&staticEmbedder{vector: vector})
+}
+
 // knnSearchMode 实现 es8retriever.SearchMode 接口，用于 KNN 向量搜索
 type knnSearchMode struct {
-	vectorField string
+	vectorField   string
+	userTypeField string
+	numCandidates int
 }
 
 // BuildRequest 构建 ES8 KNN 搜索请求
@@ -188,12 +337,109 @@ func (m *knnSearchMode) BuildRequest(ctx context.Context, conf *es8retriever.Ret
 		Embedding: conf.Embedding,
 	}, opts...)
 
-	if options.Embedding == nil {
+	vector, err := embedES8Query(ctx, options.Embedding, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// 构建 KNN 查询
+	topK := *options.TopK
+	numCandidates := max(m.numCandidates, topK)
+	knn := types.KnnSearch{
+		Field:         m.vectorField,
+		QueryVector:   vector,
+		K:             &topK,
+		NumCandidates: &numCandidates,
+		Filter:        buildES8Filters(m.userTypeField, opts...),
+	}
+
+	return &search.Request{
+		Knn:  []types.KnnSearch{knn},
+		Size: &topK,
+	}, nil
+}
+
+// hybridSearchMode 实现 es8retriever.SearchMode 接口，用于 BM25 + KNN 混合搜索。
+// BM25 匹配存储的问题文本，适合商品 SKU、错误码等关键词型问题；
+// 两路结果按权重线性融合或使用 RRF 融合。
+type hybridSearchMode struct {
+	vectorField   string
+	textField     string
+	userTypeField string
+	numCandidates int
+	hybrid        config.ES8HybridConfig
+}
+
+// BuildRequest 构建 ES8 混合搜索请求
+func (m *hybridSearchMode) BuildRequest(ctx context.Context, conf *es8retriever.RetrieverConfig, query string, opts ...retriever.Option) (*search.Request, error) {
+	options := retriever.GetCommonOptions(&retriever.Options{
+		TopK:      &conf.TopK,
+		Embedding: conf.Embedding,
+	}, opts...)
+
+	vector, err := embedES8Query(ctx, options.Embedding, query)
+	if err != nil {
+		return nil, err
+	}
+
+	topK := *options.TopK
+	numCandidates := max(m.numCandidates, topK)
+	filters := buildES8Filters(m.userTypeField, opts...)
+
+	knn := types.KnnSearch{
+		Field:         m.vectorField,
+		QueryVector:   vector,
+		K:             &topK,
+		NumCandidates: &numCandidates,
+		Filter:        filters,
+	}
+	match := types.MatchQuery{Query: query}
+
+	req := &search.Request{
+		Size: &topK,
+		Query: &types.Query{
+			Bool: &types.BoolQuery{
+				Filter: filters,
+				Must: []types.Query{
+					{Match: map[string]types.MatchQuery{m.textField: match}},
+				},
+			},
+		},
+	}
+
+	switch m.hybrid.Fusion {
+	case "rrf":
+		rrf := &types.RrfRank{}
+		if m.hybrid.RRFRankConstant > 0 {
+			rankConstant := m.hybrid.RRFRankConstant
+			rrf.RankConstant = &rankConstant
+		}
+		if m.hybrid.RRFRankWindowSize > 0 {
+			windowSize := m.hybrid.RRFRankWindowSize
+			rrf.RankWindowSize = &windowSize
+		}
+		req.Rank = &types.RankContainer{Rrf: rrf}
+	default:
+		// 线性加权：ES 会将 KNN 与 query 的分数按 boost 加权求和
+		knnBoost := float32(m.hybrid.KNNWeight)
+		textBoost := float32(m.hybrid.TextWeight)
+		knn.Boost = &knnBoost
+		match.Boost = &textBoost
+		req.Query.Bool.Must[0].Match[m.textField] = match
+	}
+
+	req.Knn = []types.KnnSearch{knn}
+	return req, nil
+}
+
+// embedES8Query 将查询文本向量化为 ES 所需的 float32 向量
+func embedES8Query(ctx context.Context, emb embedding.Embedder, query string) ([]float32, error) {
+	if emb == nil {
 		return nil, fmt.Errorf("embedding is required for KNN search")
 	}
 
 	// 生成查询向量
-	vectors, err := options.Embedding.EmbedStrings(ctx, []string{query})
+	vectors, err := emb.EmbedStrings(ctx, []string{query})
 	if err != nil {
 		return nil, fmt.Errorf("failed to embed query: %w", err)
 	}
@@ -207,67 +453,128 @@ func (m *knnSearchMode) BuildRequest(ctx context.Context, conf *es8retriever.Ret
 	for i, v := range vectors[0] {
 		vector[i] = float32(v)
 	}
+	return vector, nil
+}
 
-	// 构建 KNN 查询
-	topK := conf.TopK
-	knn := types.KnnSearch{
-		Field:       m.vectorField,
-		QueryVector: vector,
-		K:           &topK,
-		NumCandidates: &topK,
+// buildES8Filters 合并调用方传入的 ES 过滤条件与 user_type 过滤条件
+func buildES8Filters(userTypeField string, opts ...retriever.Option) []types.Query {
+	esOpts := retriever.GetImplSpecificOptions(&es8retriever.ImplOptions{}, opts...)
+	ownOpts := retriever.GetImplSpecificOptions(&RetrieveOptions{}, opts...)
+
+	filters := append([]types.Query{}, esOpts.Filters...)
+	if ownOpts.UserType != "" {
+		filters = append(filters, types.Query{
+			Term: map[string]types.TermQuery{
+				userTypeField: {Value: ownOpts.UserType},
+			},
+		})
 	}
-
-	return &search.Request{
-		Knn: []types.KnnSearch{knn},
-	}, nil
+	if len(filters) == 0 {
+		return nil
+	}
+	return filters
 }
 
 // parseES8SearchMode 解析 ES8 搜索模式
-func parseES8SearchMode(mode string, vectorField string) es8retriever.SearchMode {
+func parseES8SearchMode(cfg *config.ES8RetrieverConfig) (es8retriever.SearchMode, error) {
+	vectorField := cfg.VectorField
 	if vectorField == "" {
 		vectorField = "vector"
 	}
-	// 使用自定义的 KNN 搜索模式
-	return &knnSearchMode{vectorField: vectorField}
-}
+	userTypeField := cfg.UserTypeField
+	if userTypeField == "" {
+		userTypeField = "user_type"
+	}
 
-// defaultES8ResultParser 默认的 ES8 结果解析器
-func defaultES8ResultParser(ctx context.Context, hit types.Hit) (*schema.Document, error) {
-	doc := &schema.Document{
-		MetaData: make(map[string]any),
+	switch cfg.SearchMode {
+	case "", "knn":
+		return &knnSearchMode{
+			vectorField:   vectorField,
+			userTypeField: userTypeField,
+			numCandidates: cfg.NumCandidates,
+		}, nil
+	case "hybrid":
+		textField := cfg.TextField
+		if textField == "" {
+			textField = "content"
+		}
+		hybrid := cfg.Hybrid
+		switch hybrid.Fusion {
+		case "", "weighted":
+			hybrid.Fusion = "weighted"
+			if hybrid.KNNWeight <= 0 && hybrid.TextWeight <= 0 {
+				hybrid.KNNWeight, hybrid.TextWeight = 1.0, 1.0
+			}
+		case "rrf":
+		default:
+			return nil, fmt.Errorf("unsupported es8 hybrid fusion: %s", hybrid.Fusion)
+		}
+		return &hybridSearchMode{
+			vectorField:   vectorField,
+			textField:     textField,
+			userTypeField: userTypeField,
+			numCandidates: cfg.NumCandidates,
+			hybrid:        hybrid,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported es8 search mode: %s", cfg.SearchMode)
 	}
+}
 
-	// 解析 ID
-	if hit.Id_ != nil {
-		doc.ID = *hit.Id_
+// newES8ResultParser 返回 ES8 结果解析器。
+// 向量字段解析为文档的稠密向量（供归一化层计算相似度），不写入元数据。
+func newES8ResultParser(vectorField string) func(ctx context.Context, hit types.Hit) (*schema.Document, error) {
+	if vectorField == "" {
+		vectorField = "vector"
 	}
+	return func(ctx context.Context, hit types.Hit) (*schema.Document, error) {
+		doc := &schema.Document{
+			MetaData: make(map[string]any),
+		}
 
-	// 解析 Source (json.RawMessage 类型)
-	if len(hit.Source_) > 0 {
-		var source map[string]any
-		if err := json.Unmarshal(hit.Source_, &source); err == nil {
-			// 尝试解析 content 字段
-			if content, ok := source["content"]; ok {
-				if str, ok := content.(string); ok {
-					doc.Content = str
+		// 解析 ID
+		if hit.Id_ != nil {
+			doc.ID = *hit.Id_
+		}
+
+		// 解析 Source (json.RawMessage 类型)
+		if len(hit.Source_) > 0 {
+			var source map[string]any
+			if err := json.Unmarshal(hit.Source_, &source); err == nil {
+				// 尝试解析 content 字段
+				if content, ok := source["content"]; ok {
+					if str, ok := content.(string); ok {
+						doc.Content = str
+					}
+				}
+
+				// 解析向量字段
+				if values, ok := source[vectorField].([]any); ok {
+					vector := make([]float64, 0, len(values))
+					for _, v := range values {
+						if f, ok := v.(float64); ok {
+							vector = append(vector, f)
+						}
+					}
+					doc.WithDenseVector(vector)
 				}
-			}
 
-			// 将其他字段作为元数据
-			for k, v := range source {
-				if k != "content" && k != "vector" {
-					doc.MetaData[k] = v
+				// 将其他字段作为元数据
+				for k, v := range source {
+					if k != "content" && k != vectorField {
+						doc.MetaData[k] = v
+					}
 				}
 			}
 		}
-	}
 
-	// 解析分数
-	if hit.Score_ != nil {
-		doc.MetaData["_score"] = float64(*hit.Score_)
-	}
+		// 解析分数
+		if hit.Score_ != nil {
+			doc.MetaData["_score"] = float64(*hit.Score_)
+		}
 
-	return doc, nil
+		return doc, nil
+	}
 }
 
 // newVikingDBRetriever 创建 VikingDB Retriever
@@ -292,12 +599,6 @@ func newVikingDBRetriever(ctx context.Context, cfg *config.RetrieverConfig, embe
 		retrieverCfg.TopK = &topK
 	}
 
-	// 设置相似度阈值
-	if cfg.ScoreThreshold > 0 {
-		threshold := cfg.ScoreThreshold
-		retrieverCfg.ScoreThreshold = &threshold
-	}
-
 	// 配置 Embedding
 	if !cfg.VikingDB.WithMultiModal {
 		if cfg.VikingDB.UseBuiltinEmbedding {
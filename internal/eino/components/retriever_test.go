@@ -0,0 +1,127 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	es8retriever "github.com/cloudwego/eino-ext/components/retriever/es8"
+	"github.com/cloudwego/eino/components/embedding"
+
+	"llm-cache/internal/eino/config"
+)
+
+// fakeEmbedder 返回固定向量的测试 Embedder
+type fakeEmbedder struct {
+	vector []float64
+}
+
+func (e *fakeEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i := range texts {
+		vectors[i] = e.vector
+	}
+	return vectors, nil
+}
+
+func TestParseES8SearchMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.ES8RetrieverConfig
+		wantErr bool
+		hybrid  bool
+	}{
+		{name: "default knn", cfg: config.ES8RetrieverConfig{}},
+		{name: "explicit knn", cfg: config.ES8RetrieverConfig{SearchMode: "knn"}},
+		{name: "hybrid", cfg: config.ES8RetrieverConfig{SearchMode: "hybrid"}, hybrid: true},
+		{name: "hybrid rrf", cfg: config.ES8RetrieverConfig{SearchMode: "hybrid", Hybrid: config.ES8HybridConfig{Fusion: "rrf"}}, hybrid: true},
+		{name: "unknown fusion", cfg: config.ES8RetrieverConfig{SearchMode: "hybrid", Hybrid: config.ES8HybridConfig{Fusion: "max"}}, wantErr: true},
+		{name: "unknown mode", cfg: config.ES8RetrieverConfig{SearchMode: "sparse"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := parseES8SearchMode(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected err=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if _, ok := mode.(*hybridSearchMode); ok != tt.hybrid {
+				t.Errorf("expected hybrid=%v, got %T", tt.hybrid, mode)
+			}
+		})
+	}
+}
+
+func TestHybridSearchMode_Weighted(t *testing.T) {
+	mode, err := parseES8SearchMode(&config.ES8RetrieverConfig{
+		SearchMode: "hybrid",
+		Hybrid:     config.ES8HybridConfig{KNNWeight: 0.7, TextWeight: 0.3},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	conf := &es8retriever.RetrieverConfig{TopK: 5, Embedding: &fakeEmbedder{vector: []float64{0.1, 0.2}}}
+	req, err := mode.BuildRequest(context.Background(), conf, "error code E1001", WithUserType("vip"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(req.Knn) != 1 || req.Knn[0].Boost == nil || *req.Knn[0].Boost != float32(0.7) {
+		t.Fatalf("expected knn boost 0.7, got %+v", req.Knn)
+	}
+	if *req.Knn[0].K != 5 || *req.Size != 5 {
+		t.Errorf("expected k and size 5, got k=%d size=%d", *req.Knn[0].K, *req.Size)
+	}
+
+	match, ok := req.Query.Bool.Must[0].Match["content"]
+	if !ok || match.Query != "error code E1001" {
+		t.Fatalf("expected BM25 match on content, got %+v", req.Query.Bool.Must)
+	}
+	if match.Boost == nil || *match.Boost != float32(0.3) {
+		t.Errorf("expected text boost 0.3, got %v", match.Boost)
+	}
+	if req.Rank != nil {
+		t.Errorf("expected no rrf rank for weighted fusion")
+	}
+
+	if len(req.Knn[0].Filter) != 1 || len(req.Query.Bool.Filter) != 1 {
+		t.Fatalf("expected user_type filter on both knn and query")
+	}
+	term, ok := req.Query.Bool.Filter[0].Term["user_type"]
+	if !ok || term.Value != "vip" {
+		t.Errorf("expected user_type term filter, got %+v", req.Query.Bool.Filter[0])
+	}
+}
+
+func TestHybridSearchMode_RRF(t *testing.T) {
+	mode, err := parseES8SearchMode(&config.ES8RetrieverConfig{
+		SearchMode: "hybrid",
+		TextField:  "question",
+		Hybrid:     config.ES8HybridConfig{Fusion: "rrf", RRFRankConstant: 60},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	conf := &es8retriever.RetrieverConfig{TopK: 3, Embedding: &fakeEmbedder{vector: []float64{0.5}}}
+	req, err := mode.BuildRequest(context.Background(), conf, "SKU-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.Rank == nil || req.Rank.Rrf == nil || *req.Rank.Rrf.RankConstant != 60 {
+		t.Fatalf("expected rrf rank with constant 60, got %+v", req.Rank)
+	}
+	if _, ok := req.Query.Bool.Must[0].Match["question"]; !ok {
+		t.Errorf("expected match on configured text field")
+	}
+	if req.Knn[0].Boost != nil {
+		t.Errorf("expected no knn boost for rrf fusion")
+	}
+	if req.Query.Bool.Filter != nil {
+		t.Errorf("expected no filters without user_type, got %+v", req.Query.Bool.Filter)
+	}
+}
@@ -0,0 +1,295 @@
+// Package components 提供 Eino 组件的工厂函数
+package components
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// scoreMetric 描述向量后端返回的原始分数的含义。
+// 所有换算均假设向量已做 L2 归一化（主流 Embedding 模型的默认输出）。
+type scoreMetric string
+
+const (
+	// metricCosine 原始值即余弦相似度（Qdrant Cosine/Dot、Milvus COSINE/IP、VikingDB）
+	metricCosine scoreMetric = "cosine"
+	// metricCosineDistance 原始值为 1 - cos（Redis COSINE/IP）
+	metricCosineDistance scoreMetric = "cosine_distance"
+	// metricL2 原始值为欧氏距离（Qdrant Euclid）
+	metricL2 scoreMetric = "l2"
+	// metricL2Squared 原始值为欧氏距离的平方（Milvus L2、Redis L2、VikingDB L2）
+	metricL2Squared scoreMetric = "l2_squared"
+	// metricESCosine 原始值为 (1 + cos) / 2（ES cosine、dot_product）
+	metricESCosine scoreMetric = "es_cosine"
+	// metricESL2 原始值为 1 / (1 + l2²)（ES l2_norm）
+	metricESL2 scoreMetric = "es_l2_norm"
+	// metricESMaxInnerProduct 原始值为 ES max_inner_product 的分数变换结果
+	metricESMaxInnerProduct scoreMetric = "es_max_inner_product"
+	// metricExact 原始值不可换算（如混合检索的融合分数），需用文档向量与查询向量直接计算
+	metricExact scoreMetric = "exact"
+)
+
+// normalizeScore 将原始分数换算为 0-1 之间的余弦相似度。
+// 余弦值为负时截断为 0。
+func normalizeScore(metric scoreMetric, raw float64) float64 {
+	var cos float64
+	switch metric {
+	case metricCosine:
+		cos = raw
+	case metricCosineDistance:
+		cos = 1 - raw
+	case metricL2:
+		cos = 1 - raw*raw/2
+	case metricL2Squared:
+		cos = 1 - raw/2
+	case metricESCosine:
+		cos = 2*raw - 1
+	case metricESL2:
+		if raw <= 0 {
+			return 0
+		}
+		cos = 1 - (1/raw-1)/2
+	case metricESMaxInnerProduct:
+		// ES: ip < 0 时 score = 1 / (1 - ip)，否则 score = ip + 1
+		if raw < 1 {
+			if raw <= 0 {
+				return 0
+			}
+			cos = 1 - 1/raw
+		} else {
+			cos = raw - 1
+		}
+	default:
+		cos = raw
+	}
+	return clampScore(cos)
+}
+
+// clampScore 将分数截断到 [0, 1] 区间
+func clampScore(score float64) float64 {
+	if math.IsNaN(score) || score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
+
+// CosineSimilarity 计算两个向量的余弦相似度，维度不一致或零向量时返回 0
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// resolveScoreMetric 根据 Retriever 配置推断后端原始分数的含义。
+// 无法换算为余弦相似度的度量类型返回错误。
+func resolveScoreMetric(cfg *config.RetrieverConfig) (scoreMetric, error) {
+	switch cfg.Provider {
+	case "qdrant":
+		switch strings.ToLower(cfg.Qdrant.Distance) {
+		case "", "cosine", "dot":
+			return metricCosine, nil
+		case "euclid", "euclidean":
+			return metricL2, nil
+		}
+		return "", fmt.Errorf("qdrant distance %q cannot be normalized to cosine similarity", cfg.Qdrant.Distance)
+
+	case "milvus":
+		switch strings.ToUpper(cfg.Milvus.MetricType) {
+		case "COSINE", "IP":
+			return metricCosine, nil
+		case "L2":
+			return metricL2Squared, nil
+		case "", "HAMMING":
+			// 默认 Milvus 集合以二进制向量存储 float32 原始字节，HAMMING 距离无语义，需直接计算
+			return metricExact, nil
+		}
+		return "", fmt.Errorf("milvus metric type %q cannot be normalized to cosine similarity", cfg.Milvus.MetricType)
+
+	case "redis":
+		switch strings.ToUpper(cfg.Redis.DistanceMetric) {
+		case "", "COSINE", "IP":
+			return metricCosineDistance, nil
+		case "L2":
+			return metricL2Squared, nil
+		}
+		return "", fmt.Errorf("redis distance metric %q cannot be normalized to cosine similarity", cfg.Redis.DistanceMetric)
+
+	case "es8":
+		if cfg.ES8.SearchMode == "hybrid" {
+			return metricExact, nil
+		}
+		switch strings.ToLower(cfg.ES8.Similarity) {
+		case "", "cosine", "dot_product":
+			return metricESCosine, nil
+		case "l2_norm":
+			return metricESL2, nil
+		case "max_inner_product":
+			return metricESMaxInnerProduct, nil
+		}
+		return "", fmt.Errorf("es8 similarity %q cannot be normalized to cosine similarity", cfg.ES8.Similarity)
+
+	case "vikingdb":
+		switch strings.ToLower(cfg.VikingDB.Distance) {
+		case "", "cosine", "ip":
+			return metricCosine, nil
+		case "l2":
+			return metricL2Squared, nil
+		}
+		return "", fmt.Errorf("vikingdb distance %q cannot be normalized to cosine similarity", cfg.VikingDB.Distance)
+
+	default:
+		return "", fmt.Errorf("unsupported retriever provider: %s", cfg.Provider)
+	}
+}
+
+// scoreNormalizingRetriever 包装底层 Retriever，将各后端的原始分数统一换算为 0-1 余弦相似度。
+// 换算后的分数写入 MetaData["score"]（同时覆盖 _score），原始值保留在 MetaData["raw_score"]。
+// 相似度阈值统一在换算后应用，底层后端不再接收阈值参数。
+type scoreNormalizingRetriever struct {
+	inner     retriever.Retriever
+	embedder  embedding.Embedder
+	metric    scoreMetric
+	threshold *float64
+}
+
+// newScoreNormalizingRetriever 创建分数归一化 Retriever
+func newScoreNormalizingRetriever(inner retriever.Retriever, embedder embedding.Embedder, metric scoreMetric, threshold float64) *scoreNormalizingRetriever {
+	r := &scoreNormalizingRetriever{
+		inner:    inner,
+		embedder: embedder,
+		metric:   metric,
+	}
+	if threshold > 0 {
+		r.threshold = &threshold
+	}
+	return r
+}
+
+// Retrieve 执行检索并归一化分数，过滤低于阈值的文档
+func (r *scoreNormalizingRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	options := retriever.GetCommonOptions(&retriever.Options{
+		ScoreThreshold: r.threshold,
+		Embedding:      r.embedder,
+	}, opts...)
+	innerOpts := stripScoreThreshold(opts)
+
+	// 直接计算模式下预先向量化查询，并通过固定向量的 Embedder 复用给底层 Retriever
+	var queryVector []float64
+	if r.metric == metricExact {
+		if options.Embedding == nil {
+			return nil, fmt.Errorf("embedding is required for exact score normalization")
+		}
+		vectors, err := options.Embedding.EmbedStrings(ctx, []string{query})
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+		if len(vectors) == 0 {
+			return nil, fmt.Errorf("embedding returned empty vector")
+		}
+		queryVector = vectors[0]
+		innerOpts = append(innerOpts, retriever.WithEmbedding(&staticEmbedder{vector: queryVector}))
+	}
+
+	docs, err := r.inner.Retrieve(ctx, query, innerOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc.MetaData == nil {
+			doc.MetaData = make(map[string]any)
+		}
+
+		raw, _ := rawDocScore(doc)
+		var score float64
+		if r.metric == metricExact {
+			score = clampScore(CosineSimilarity(queryVector, doc.DenseVector()))
+		} else {
+			score = normalizeScore(r.metric, raw)
+		}
+
+		if options.ScoreThreshold != nil && score < *options.ScoreThreshold {
+			continue
+		}
+
+		doc.MetaData["raw_score"] = raw
+		doc.MetaData["score"] = score
+		doc.WithScore(score)
+		result = append(result, doc)
+	}
+
+	return result, nil
+}
+
+// GetType 返回组件类型，用于 Callback 标识
+func (r *scoreNormalizingRetriever) GetType() string {
+	return "ScoreNormalizing"
+}
+
+// rawDocScore 提取后端写入的原始分数。
+// 优先读取 Eino 标准的 _score，其次读取 Redis 返回的 distance 字段。
+func rawDocScore(doc *schema.Document) (float64, bool) {
+	for _, key := range []string{"_score", "distance"} {
+		switch v := doc.MetaData[key].(type) {
+		case float64:
+			return v, true
+		case float32:
+			return float64(v), true
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// stripScoreThreshold 移除调用方设置的阈值选项，阈值由归一化层统一应用
+func stripScoreThreshold(opts []retriever.Option) []retriever.Option {
+	result := make([]retriever.Option, 0, len(opts)+1)
+	for _, opt := range opts {
+		if retriever.GetCommonOptions(nil, opt).ScoreThreshold != nil {
+			continue
+		}
+		result = append(result, opt)
+	}
+	return result
+}
+
+// staticEmbedder 返回预先计算好的查询向量，避免底层 Retriever 重复调用 Embedding 服务
+type staticEmbedder struct {
+	vector []float64
+}
+
+// EmbedStrings 对任意输入返回预先计算的向量
+func (e *staticEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i := range texts {
+		vectors[i] = e.vector
+	}
+	return vectors, nil
+}
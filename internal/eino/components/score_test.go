@@ -0,0 +1,148 @@
+package components
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// fakeRetriever 返回固定文档并记录调用选项的测试 Retriever
+type fakeRetriever struct {
+	docs []*schema.Document
+	opts *retriever.Options
+}
+
+func (r *fakeRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	r.opts = retriever.GetCommonOptions(nil, opts...)
+	return r.docs, nil
+}
+
+func TestNormalizeScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric scoreMetric
+		raw    float64
+		want   float64
+	}{
+		{name: "cosine", metric: metricCosine, raw: 0.9, want: 0.9},
+		{name: "negative cosine clamped", metric: metricCosine, raw: -0.3, want: 0},
+		{name: "cosine distance", metric: metricCosineDistance, raw: 0.1, want: 0.9},
+		{name: "l2", metric: metricL2, raw: math.Sqrt(0.2), want: 0.9},
+		{name: "l2 squared", metric: metricL2Squared, raw: 0.2, want: 0.9},
+		{name: "es cosine", metric: metricESCosine, raw: 0.95, want: 0.9},
+		{name: "es l2_norm", metric: metricESL2, raw: 1 / 1.2, want: 0.9},
+		{name: "es max_inner_product positive", metric: metricESMaxInnerProduct, raw: 1.9, want: 0.9},
+		{name: "es max_inner_product negative", metric: metricESMaxInnerProduct, raw: 0.5, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeScore(tt.metric, tt.raw)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveScoreMetric(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.RetrieverConfig
+		want    scoreMetric
+		wantErr bool
+	}{
+		{name: "qdrant default", cfg: config.RetrieverConfig{Provider: "qdrant"}, want: metricCosine},
+		{name: "qdrant euclid", cfg: config.RetrieverConfig{Provider: "qdrant", Qdrant: config.QdrantRetrieverConfig{Distance: "Euclid"}}, want: metricL2},
+		{name: "milvus default", cfg: config.RetrieverConfig{Provider: "milvus"}, want: metricExact},
+		{name: "milvus l2", cfg: config.RetrieverConfig{Provider: "milvus", Milvus: config.MilvusRetrieverConfig{MetricType: "L2"}}, want: metricL2Squared},
+		{name: "redis default", cfg: config.RetrieverConfig{Provider: "redis"}, want: metricCosineDistance},
+		{name: "es8 knn", cfg: config.RetrieverConfig{Provider: "es8"}, want: metricESCosine},
+		{name: "es8 hybrid", cfg: config.RetrieverConfig{Provider: "es8", ES8: config.ES8RetrieverConfig{SearchMode: "hybrid"}}, want: metricExact},
+		{name: "vikingdb default", cfg: config.RetrieverConfig{Provider: "vikingdb"}, want: metricCosine},
+		{name: "unsupported distance", cfg: config.RetrieverConfig{Provider: "qdrant", Qdrant: config.QdrantRetrieverConfig{Distance: "Manhattan"}}, wantErr: true},
+		{name: "unsupported provider", cfg: config.RetrieverConfig{Provider: "faiss"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveScoreMetric(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected err=%v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScoreNormalizingRetriever_Threshold(t *testing.T) {
+	redisDocs := func() []*schema.Document {
+		return []*schema.Document{
+			{ID: "near", MetaData: map[string]any{"distance": "0.05"}},
+			{ID: "far", MetaData: map[string]any{"distance": "0.4"}},
+		}
+	}
+	inner := &fakeRetriever{docs: redisDocs()}
+	r := newScoreNormalizingRetriever(inner, &fakeEmbedder{}, metricCosineDistance, 0.8)
+
+	docs, err := r.Retrieve(context.Background(), "query", retriever.WithTopK(5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if inner.opts.ScoreThreshold != nil {
+		t.Errorf("expected threshold not passed to backend, got %v", *inner.opts.ScoreThreshold)
+	}
+	if len(docs) != 1 || docs[0].ID != "near" {
+		t.Fatalf("expected only near doc, got %d docs", len(docs))
+	}
+	if score := docs[0].MetaData["score"].(float64); math.Abs(score-0.95) > 1e-9 {
+		t.Errorf("expected score 0.95, got %v", score)
+	}
+	if docs[0].MetaData["raw_score"] != 0.05 {
+		t.Errorf("expected raw_score 0.05, got %v", docs[0].MetaData["raw_score"])
+	}
+
+	// 调用方传入的阈值覆盖默认阈值
+	inner.docs = redisDocs()
+	docs, err = r.Retrieve(context.Background(), "query", retriever.WithScoreThreshold(0.5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Errorf("expected 2 docs with lower threshold, got %d", len(docs))
+	}
+}
+
+func TestScoreNormalizingRetriever_Exact(t *testing.T) {
+	near := &schema.Document{ID: "near", MetaData: map[string]any{"_score": 0.016}}
+	near.WithDenseVector([]float64{1, 0})
+	far := &schema.Document{ID: "far", MetaData: map[string]any{"_score": 0.033}}
+	far.WithDenseVector([]float64{0, 1})
+
+	inner := &fakeRetriever{docs: []*schema.Document{near, far}}
+	r := newScoreNormalizingRetriever(inner, &fakeEmbedder{vector: []float64{0.8, 0.6}}, metricExact, 0)
+
+	docs, err := r.Retrieve(context.Background(), "query")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 docs, got %d", len(docs))
+	}
+	if score := docs[0].Score(); math.Abs(score-0.8) > 1e-9 {
+		t.Errorf("expected score 0.8, got %v", score)
+	}
+	if score := docs[1].Score(); math.Abs(score-0.6) > 1e-9 {
+		t.Errorf("expected score 0.6, got %v", score)
+	}
+	if _, ok := inner.opts.Embedding.(*staticEmbedder); !ok {
+		t.Errorf("expected precomputed query vector passed to backend, got %T", inner.opts.Embedding)
+	}
+}
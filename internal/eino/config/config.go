@@ -6,13 +6,22 @@ import "time"
 // EinoConfig Eino 框架的总配置结构。
 // 包含了 Embedder、Retriever、Indexer 等组件的配置，以及查询、存储、质量检查和回调系统的配置。
 type EinoConfig struct {
-	Embedder  EmbedderConfig  `yaml:"embedder"`
-	Retriever RetrieverConfig `yaml:"retriever"`
-	Indexer   IndexerConfig   `yaml:"indexer"`
-	Query     QueryConfig     `yaml:"query"`
-	Store     StoreConfig     `yaml:"store"`
-	Quality   QualityConfig   `yaml:"quality"`
-	Callbacks CallbacksConfig `yaml:"callbacks"`
+	Embedder      EmbedderConfig      `yaml:"embedder"`
+	Retriever     RetrieverConfig     `yaml:"retriever"`
+	Reranker      RerankerConfig      `yaml:"reranker"`
+	ChatModel     ChatModelConfig     `yaml:"chat_model"`
+	Indexer       IndexerConfig       `yaml:"indexer"`
+	Query         QueryConfig         `yaml:"query"`
+	Store         StoreConfig         `yaml:"store"`
+	Normalization NormalizationConfig `yaml:"normalization"`
+	Templates     TemplatesConfig     `yaml:"prompt_templates"`
+	Slots         SlotsConfig         `yaml:"answer_slots"`
+	Quality       QualityConfig       `yaml:"quality"`
+	PII           PIIConfig           `yaml:"pii"`
+	Trust         TrustConfig         `yaml:"trust"`
+	Conversation  ConversationConfig  `yaml:"conversation"`
+	Generation    GenerationConfig    `yaml:"generation"`
+	Callbacks     CallbacksConfig     `yaml:"callbacks"`
 }
 
 // EmbedderConfig 定义文本嵌入（Embedding）服务的配置。
@@ -29,22 +38,92 @@ type EmbedderConfig struct {
 	ByAzure    bool   `yaml:"by_azure"`
 	APIVersion string `yaml:"api_version"`
 
-	// ARK 专用
-	Region     string `yaml:"region"`
-	AccessKey  string `yaml:"access_key"`
-	SecretKey  string `yaml:"secret_key"`
-	RetryTimes *int   `yaml:"retry_times"`
+	// 云厂商鉴权与地域（ARK、Qianfan 使用 AK/SK 签名，Dashscope、Tencentcloud 使用 Region 选择接入地址）
+	Region    string `yaml:"region"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+
+	// RetryTimes 可重试错误的最大重试次数（启用 Resilience 时生效，未设置时为 2）
+	RetryTimes *int `yaml:"retry_times"`
 
 	// Tencentcloud 专用
 	SecretID string `yaml:"secret_id"`
+
+	// Cache 向量结果缓存配置（对任意提供商生效）
+	Cache EmbeddingCacheConfig `yaml:"cache"`
+
+	// Batching 并发请求合批配置（对任意提供商生效）
+	Batching EmbeddingBatchingConfig `yaml:"batching"`
+
+	// Resilience 重试、熔断与故障转移配置（对任意提供商生效）
+	Resilience EmbeddingResilienceConfig `yaml:"resilience"`
+}
+
+// EmbeddingResilienceConfig 定义 Embedder 的重试、熔断与故障转移配置。
+// 每个提供商独立重试和熔断；主提供商失败或熔断时按顺序尝试备用提供商。
+type EmbeddingResilienceConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// 重试退避：每次重试间隔翻倍并叠加随机抖动，不超过 MaxBackoff
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+
+	// FailureThreshold 连续失败（重试耗尽）多少次后熔断
+	FailureThreshold int `yaml:"failure_threshold"`
+	// OpenDuration 熔断持续时间，到期后放行一次试探调用
+	OpenDuration time.Duration `yaml:"open_duration"`
+
+	// Fallbacks 按顺序尝试的备用提供商（向量维度必须与主提供商一致，启动时校验；
+	// 备用配置中的 cache、batching、resilience 字段不生效）。
+	// 维度一致不代表向量空间一致：备用提供商使用不同模型时，故障转移期间写入的条目与其余条目
+	// 不可比，相似度检索会失准；备用提供商应答的向量不写入 Embedding 缓存。
+	Fallbacks []EmbedderConfig `yaml:"fallbacks"`
+}
+
+// EmbeddingBatchingConfig 定义 Embedding 请求合批的配置。
+// 在时间窗口内收集并发请求的文本，合并为一次提供商调用，减少按请求计费和 RPM 限流的影响。
+type EmbeddingBatchingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Window 收集并发请求的时间窗口（从批次中第一个请求到达开始计时）
+	Window time.Duration `yaml:"window"`
+	// MaxBatchSize 单次调用的最大文本数，达到时立即发送
+	MaxBatchSize int `yaml:"max_batch_size"`
+}
+
+// EmbeddingCacheConfig 定义向量结果缓存的配置。
+// 以模型名和规范化文本的哈希为键，先查进程内 LRU，再查可选的本地磁盘或 Redis 后端，
+// 未命中的文本才调用 Embedding 提供商。
+type EmbeddingCacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxEntries 进程内 LRU 的最大条目数
+	MaxEntries int `yaml:"max_entries"`
+	// Backend 二级缓存后端：memory（仅进程内，默认）, disk, redis
+	Backend string `yaml:"backend"`
+	// TTL 二级缓存条目的有效期（0 表示不过期）
+	TTL time.Duration `yaml:"ttl"`
+
+	// Disk 专用配置
+	Dir string `yaml:"dir"`
+
+	// Redis 专用配置
+	Redis EmbeddingCacheRedisConfig `yaml:"redis"`
+}
+
+// EmbeddingCacheRedisConfig 定义向量结果缓存 Redis 后端的配置。
+type EmbeddingCacheRedisConfig struct {
+	Addr      string `yaml:"addr"`
+	Password  string `yaml:"password"`
+	DB        int    `yaml:"db"`
+	KeyPrefix string `yaml:"key_prefix"`
 }
 
 // RetrieverConfig 定义检索器（Retriever）的配置。
 // 负责从向量数据库中检索相似的文本片段。
 type RetrieverConfig struct {
-	Provider       string  `yaml:"provider"` // qdrant, milvus, redis, es8, vikingdb
-	Collection     string  `yaml:"collection"`
-	TopK           int     `yaml:"top_k"`
+	Provider   string `yaml:"provider"` // qdrant, milvus, redis, es8, vikingdb
+	Collection string `yaml:"collection"`
+	TopK       int    `yaml:"top_k"`
+	// ScoreThreshold 相似度阈值。各后端的原始分数会统一换算为 0-1 余弦相似度后再比较
 	ScoreThreshold float64 `yaml:"score_threshold"`
 
 	// Qdrant 专用配置
@@ -70,6 +149,7 @@ type QdrantRetrieverConfig struct {
 	APIKey     string `yaml:"api_key"`
 	UseTLS     bool   `yaml:"use_tls"`
 	VectorName string `yaml:"vector_name"`
+	Distance   string `yaml:"distance"` // 集合的距离类型：Cosine, Euclid, Dot（用于分数归一化）
 }
 
 // MilvusRetrieverConfig 定义 Milvus 检索器的专用配置。
@@ -80,7 +160,7 @@ type MilvusRetrieverConfig struct {
 	Password     string   `yaml:"password"`
 	VectorField  string   `yaml:"vector_field"`
 	OutputFields []string `yaml:"output_fields"`
-	MetricType   string   `yaml:"metric_type"`
+	MetricType   string   `yaml:"metric_type"` // COSINE, IP, L2, HAMMING（默认，对应 Eino Milvus Indexer 的二进制向量集合）
 }
 
 // RedisRetrieverConfig 定义 Redis 检索器的专用配置。
@@ -92,6 +172,8 @@ type RedisRetrieverConfig struct {
 	Prefix       string   `yaml:"prefix"` // 键前缀，用于删除操作
 	VectorField  string   `yaml:"vector_field"`
 	ReturnFields []string `yaml:"return_fields"`
+	// DistanceMetric 向量索引的距离类型：COSINE（默认）, IP, L2（用于分数归一化）
+	DistanceMetric string `yaml:"distance_metric"`
 }
 
 // ES8RetrieverConfig 定义 Elasticsearch 8 检索器的专用配置。
@@ -102,6 +184,30 @@ type ES8RetrieverConfig struct {
 	Index       string   `yaml:"index"`
 	VectorField string   `yaml:"vector_field"`
 	SearchMode  string   `yaml:"search_mode"` // knn, hybrid
+	// Similarity 向量字段映射的相似度类型：cosine（默认）, dot_product, l2_norm, max_inner_product
+	Similarity string `yaml:"similarity"`
+
+	// TextField 混合检索中 BM25 匹配的文本字段（默认 content，即存储的问题文本）
+	TextField string `yaml:"text_field"`
+	// UserTypeField 用于 user_type 过滤的字段（需为 keyword 类型，默认 user_type）
+	UserTypeField string `yaml:"user_type_field"`
+	// NumCandidates KNN 每个分片考虑的候选数量（默认与 TopK 相同）
+	NumCandidates int `yaml:"num_candidates"`
+
+	// Hybrid 混合检索配置（仅在 search_mode 为 hybrid 时生效）
+	Hybrid ES8HybridConfig `yaml:"hybrid"`
+}
+
+// ES8HybridConfig 定义 Elasticsearch 8 混合检索（BM25 + KNN）的融合配置。
+// 支持按权重线性融合或使用倒数排名融合（RRF）。
+type ES8HybridConfig struct {
+	Fusion     string  `yaml:"fusion"`      // weighted, rrf
+	KNNWeight  float64 `yaml:"knn_weight"`  // weighted 模式下 KNN 分数权重
+	TextWeight float64 `yaml:"text_weight"` // weighted 模式下 BM25 分数权重
+
+	// RRF 配置（需要 Elasticsearch 对应的许可证）
+	RRFRankConstant   int64 `yaml:"rrf_rank_constant"`
+	RRFRankWindowSize int64 `yaml:"rrf_rank_window_size"`
 }
 
 // VikingDBRetrieverConfig 定义 VikingDB 检索器的专用配置。
@@ -122,6 +228,8 @@ type VikingDBRetrieverConfig struct {
 	EmbeddingModelName  string `yaml:"embedding_model_name"`
 	UseSparse           bool   `yaml:"use_sparse"`
 	DenseWeight         float64 `yaml:"dense_weight"`
+	// Distance 索引的距离类型：cosine（默认）, ip, l2（用于分数归一化）
+	Distance string `yaml:"distance"`
 }
 
 // IndexerConfig 定义索引器（Indexer）的配置。
@@ -201,6 +309,45 @@ type VikingDBIndexerConfig struct {
 	AddBatchSize        int    `yaml:"add_batch_size"`
 }
 
+// RerankerConfig 定义重排序（Rerank）组件的配置。
+// 启用后在检索与结果选择之间插入 rerank 节点，相似度阈值改为作用于重排序分数。
+type RerankerConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Provider string `yaml:"provider"` // http（Cohere/Jina 风格 Rerank API）, lexical（内置词重叠）
+
+	// HTTP Rerank API 配置
+	APIKey  string `yaml:"api_key"`
+	BaseURL string `yaml:"base_url"` // 如 https://api.jina.ai/v1，请求发送到 {base_url}/rerank
+	Model   string `yaml:"model"`
+	Timeout int    `yaml:"timeout"` // 秒
+
+	// TopN 重排序后保留的文档数量（0 表示保留全部）
+	TopN int `yaml:"top_n"`
+	// ScoreThreshold 重排序分数阈值（请求未指定阈值时使用）
+	ScoreThreshold float64 `yaml:"score_threshold"`
+}
+
+// ChatModelConfig 定义对话模型（ChatModel）的配置。
+// 供答案改写等需要调用大模型的节点使用。
+type ChatModelConfig struct {
+	Provider string `yaml:"provider"` // openai（OpenAI 兼容接口）, stub（本地桩，用于测试）
+	APIKey   string `yaml:"api_key"`
+	BaseURL  string `yaml:"base_url"`
+	Model    string `yaml:"model"`
+	Timeout  int    `yaml:"timeout"` // 秒
+
+	// 生成参数（可选）
+	Temperature *float32 `yaml:"temperature"`
+	MaxTokens   *int     `yaml:"max_tokens"`
+
+	// Azure OpenAI 专用
+	ByAzure    bool   `yaml:"by_azure"`
+	APIVersion string `yaml:"api_version"`
+
+	// StubResponse stub 提供商的固定回复（为空时回显最后一条用户消息）
+	StubResponse string `yaml:"stub_response"`
+}
+
 // QueryConfig 定义查询流程（Query Graph）的配置。
 // 包含预处理、后处理开关，结果选择策略以及超时设置。
 type QueryConfig struct {
@@ -215,6 +362,161 @@ type QueryConfig struct {
 	// 超时配置（秒）
 	EmbeddingTimeout int `yaml:"embedding_timeout"`
 	RetrieveTimeout  int `yaml:"retrieve_timeout"`
+
+	// 实体与数字冲突守卫
+	EntityGuard EntityGuardConfig `yaml:"entity_guard"`
+
+	// 否定/极性冲突守卫
+	NegationGuard NegationGuardConfig `yaml:"negation_guard"`
+
+	// 近似命中的答案改写
+	Adaptation AdaptationConfig `yaml:"adaptation"`
+
+	// 相同并发查询合并
+	Coalescing CoalescingConfig `yaml:"coalescing"`
+}
+
+// CoalescingConfig 定义相同并发查询的合并配置。
+// 规范化问题、user_type、生效的 top_k 和阈值以及对话上下文均相同的并发查询共享一次 Graph 执行及其结果。
+type CoalescingConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// AdaptationConfig 定义近似命中时的答案改写配置。
+// 分数介于改写阈值与命中阈值之间的结果视为近似命中，使用 ChatModel 将缓存答案改写为适配新问题的答案。
+type AdaptationConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Threshold 改写阈值（近似命中区间的下界，应低于命中阈值）
+	Threshold float64 `yaml:"threshold"`
+
+	// SystemPrompt 改写使用的系统提示词（为空时使用内置提示词）
+	SystemPrompt string `yaml:"system_prompt"`
+
+	// Timeout 改写超时时间（秒），超时视为未命中
+	Timeout int `yaml:"timeout"`
+}
+
+// EntityGuardConfig 定义实体与数字冲突守卫的配置。
+// 从查询和候选问题中提取数字、日期、版本号、金额及自定义实体，冲突时拒绝候选。
+type EntityGuardConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// StrictPresence 为 true 时，一方包含某类实体而另一方不包含也视为冲突
+	StrictPresence bool `yaml:"strict_presence"`
+
+	// Patterns 自定义实体模式（如订单号、SKU、产品型号）
+	Patterns []EntityPattern `yaml:"patterns"`
+}
+
+// NegationGuardConfig 定义否定/极性冲突守卫的配置。
+// 查询与候选问题的否定极性不一致时（如"能否取消订单"与"为什么不能取消订单"），降权或拒绝候选。
+type NegationGuardConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Action 冲突处理方式：reject（拒绝，默认）, demote（降权）
+	Action string `yaml:"action"`
+
+	// DemoteFactor demote 模式下的分数乘数（0-1）
+	DemoteFactor float64 `yaml:"demote_factor"`
+
+	// MinScore demote 模式下的最低分数：降权后低于命中阈值或该分数的候选将被拒绝（0 表示仅按命中阈值）
+	MinScore float64 `yaml:"min_score"`
+
+	// ExtraNegations 额外的否定词
+	ExtraNegations []string `yaml:"extra_negations"`
+}
+
+// EntityPattern 定义一个自定义实体的正则表达式。
+type EntityPattern struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+}
+
+// NormalizationConfig 定义问题文本的规范化流水线配置。
+// 存储和查询使用同一条流水线，规范化后的文本用于向量化和检索，原始问题仍保存在元数据中。
+type NormalizationConfig struct {
+	// Steps 按顺序执行的规范化步骤：
+	// nfkc, fullwidth（全角转半角）, t2s（繁体转简体）, casefold, punctuation, stopwords, synonyms
+	Steps []string `yaml:"steps"`
+
+	// Stopwords 停用词列表（stopwords 步骤使用）
+	Stopwords []string `yaml:"stopwords"`
+
+	// StopwordsFile 停用词文件（每行一个词，# 开头为注释）
+	StopwordsFile string `yaml:"stopwords_file"`
+
+	// SynonymsFile 同义词词典文件（每行 "标准词: 同义词1, 同义词2"，# 开头为注释）
+	SynonymsFile string `yaml:"synonyms_file"`
+}
+
+// TemplatesConfig 定义提示词模板剥离的配置。
+// 应用将用户问题包装在固定的提示词模板中时，按模板提取可变部分后再进行预处理和向量化，
+// 模板 ID 写入缓存元数据，仅在模板一致时才允许命中。
+type TemplatesConfig struct {
+	// Default 默认的模板列表（按顺序匹配，取第一个匹配的模板）
+	Default []PromptTemplate `yaml:"default"`
+
+	// UserTypes 按 user_type 覆盖的模板列表
+	UserTypes map[string][]PromptTemplate `yaml:"user_types"`
+}
+
+// PromptTemplate 定义一个提示词模板。
+// Template 与 Pattern 二选一：Template 为带 {name} 占位符的模板原文，Pattern 为带捕获组的正则表达式。
+type PromptTemplate struct {
+	ID       string `yaml:"id"`
+	Template string `yaml:"template"`
+	Pattern  string `yaml:"pattern"`
+}
+
+// SlotsConfig 定义参数化答案（槽位填充）的配置。
+// 存储时答案可包含 {name} 槽位及其提取规则，命中时从新问题中提取取值填充答案。
+type SlotsConfig struct {
+	// Patterns 命名槽位的默认提取正则（槽位名 -> 正则），存储请求中的规则未指定正则时使用
+	Patterns map[string]string `yaml:"patterns"`
+}
+
+// PIIConfig 定义个人敏感信息（PII）检测的配置。
+// 支持的类型：email, phone, id_card, bank_card；处理方式：reject, mask, flag。
+// 存储时仅扫描问题和答案，客户端提交的 metadata 原样写入，不经过扫描。
+type PIIConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Actions 各类型的处理方式（类型 -> 处理方式），未配置的类型使用 DefaultAction
+	Actions       map[string]string `yaml:"actions"`
+	DefaultAction string            `yaml:"default_action"`
+
+	// QueryLogs 查询日志中问题文本的记录方式：omit（不记录，默认）, redact（脱敏后记录）, raw（原文）
+	QueryLogs string `yaml:"query_logs"`
+}
+
+// TrustConfig 定义存储来源认证与信任分级的配置。
+// 启用后存储请求需通过来源凭证（Bearer Token）或 HMAC 签名认证，
+// 低信任来源写入的条目处于待确认状态，经足够多的独立来源确认后才会被查询命中。
+type TrustConfig struct {
+	Enabled bool            `yaml:"enabled"`
+	Sources []TrustedSource `yaml:"sources"`
+
+	// AllowAnonymous 是否接受未认证的存储请求（统一视为低信任来源 anonymous）
+	AllowAnonymous bool `yaml:"allow_anonymous"`
+
+	// Confirmations 待确认条目转为正式条目所需的独立来源数（含首次写入的来源）
+	Confirmations int `yaml:"confirmations"`
+
+	// MaxClockSkew HMAC 签名时间戳与服务器时间允许的最大偏差
+	MaxClockSkew time.Duration `yaml:"max_clock_skew"`
+
+	// LookupTopK 查找同一问答对的已有条目时检索的候选数
+	LookupTopK int `yaml:"lookup_top_k"`
+}
+
+// TrustedSource 定义一个可信的存储来源。
+// Token 和 Secret 至少配置一项：Token 用于 Bearer 认证，Secret 用于验证 HMAC-SHA256 签名。
+type TrustedSource struct {
+	ID     string `yaml:"id"`
+	Token  string `yaml:"token"`
+	Secret string `yaml:"secret"`
+	Trust  string `yaml:"trust"` // high, low（默认）
 }
 
 // StoreConfig 定义存储流程（Store Graph）的配置。
@@ -225,6 +527,69 @@ type StoreConfig struct {
 	MinQuestionLength   int     `yaml:"min_question_length"`
 	MinAnswerLength     int     `yaml:"min_answer_length"`
 	ScoreThreshold      float64 `yaml:"score_threshold"`
+
+	// Async 异步写入队列配置
+	Async AsyncStoreConfig `yaml:"async"`
+}
+
+// AsyncStoreConfig 定义异步写入队列（write-behind）的配置。
+// 启用后存储请求先追加到本地日志并立即返回任务 ID，由后台 worker 写入，临时故障按退避间隔重试，
+// 服务重启后重放日志中未完成的任务。
+// 启用 PII 扫描时日志只保存脱敏后的问题和答案；flag 处理方式的 PII、对话历史和 metadata 按原样保存在日志中，
+// 直到任务结束后的下一次日志压缩（每小时）。
+type AsyncStoreConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	JournalDir string `yaml:"journal_dir"`
+	Workers    int    `yaml:"workers"`
+	QueueSize  int    `yaml:"queue_size"`
+
+	// MaxAttempts 单个任务的最大尝试次数（默认 10，0 表示不限次数）；非临时性错误不重试
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+
+	// Retention 已完成任务的状态保留时长（过期后从日志和任务查询中移除）
+	Retention time.Duration `yaml:"retention"`
+}
+
+// ConversationConfig 定义多轮对话缓存的配置。
+// 查询与存储时对历史消息计算上下文指纹，仅在上下文兼容时才命中缓存。
+type ConversationConfig struct {
+	// MaxTurns 参与上下文指纹计算的最近历史消息数（0 表示全部）
+	MaxTurns int `yaml:"max_turns"`
+
+	// Default 默认的上下文兼容策略
+	Default ConversationPolicy `yaml:"default"`
+
+	// UserTypes 按 user_type 覆盖的上下文兼容策略
+	UserTypes map[string]ConversationPolicy `yaml:"user_types"`
+}
+
+// ConversationPolicy 定义上下文兼容规则。
+type ConversationPolicy struct {
+	// Match 兼容规则：exact（上下文指纹完全一致，默认）, semantic（上下文向量相似）, ignore（忽略上下文）
+	Match string `yaml:"match"`
+
+	// MinSimilarity semantic 模式下上下文向量的最小余弦相似度
+	MinSimilarity float64 `yaml:"min_similarity"`
+}
+
+// GenerationConfig 定义生成参数（模型、系统提示词、温度、工具定义）感知的缓存配置。
+// 存储时记录生成上下文，查询时要求生成上下文匹配后才命中缓存。
+type GenerationConfig struct {
+	// Match 匹配规则：exact（所有字段完全一致，默认）, compatible（按兼容规则匹配）, ignore（忽略生成上下文）
+	Match string `yaml:"match"`
+
+	// TemperatureBuckets 温度分桶的上边界（升序），如 [0.3, 0.7, 1.0] 划分为 4 个桶
+	TemperatureBuckets []float64 `yaml:"temperature_buckets"`
+
+	// 以下为 compatible 规则的参数
+	// ModelGroups 兼容模型组（组名 -> 模型列表），同组模型的缓存可互相命中
+	ModelGroups map[string][]string `yaml:"model_groups"`
+	// MaxTemperatureBucketDistance 允许的温度桶距离（0 表示必须同桶）
+	MaxTemperatureBucketDistance int `yaml:"max_temperature_bucket_distance"`
+	// IgnoreFields 不参与匹配的字段：model, system_prompt, temperature, tools
+	IgnoreFields []string `yaml:"ignore_fields"`
 }
 
 // QualityConfig 定义质量检查组件的详细配置。
@@ -245,12 +610,64 @@ type QualityConfig struct {
 	// 综合分数阈值
 	ScoreThreshold float64 `yaml:"score_threshold"`
 
-	// 并行执行配置
+	// 并行执行配置（CheckTimeout 为单项检查的超时，TimeoutPolicy 为超时后的处理：pass/fail/skip）
 	ParallelWorkers int           `yaml:"parallel_workers"`
 	CheckTimeout    time.Duration `yaml:"check_timeout"`
+	TimeoutPolicy   string        `yaml:"timeout_policy"`
 
 	// 黑名单关键词
 	BlacklistKeywords []string `yaml:"blacklist_keywords"`
+
+	// 拒答/报错检测
+	Refusal RefusalConfig `yaml:"refusal"`
+
+	// 加权评估策略（为空时使用内置的 length、blacklist、rule 策略，启用拒答检测时追加 refusal）
+	Strategies []QualityStrategy `yaml:"strategies"`
+
+	// LLM 评审（使用 chat_model 按评分标准评估答案是否回答了问题）
+	Judge JudgeConfig `yaml:"judge"`
+}
+
+// QualityStrategy 定义一个加权质量评估策略。
+// Name 为注册的策略名称，Config 为策略特定的参数。
+type QualityStrategy struct {
+	Name    string                 `yaml:"name"`
+	Weight  float64                `yaml:"weight"`
+	Enabled bool                   `yaml:"enabled"`
+	Config  map[string]interface{} `yaml:"config"`
+
+	// 单项超时和超时处理（为空时使用 check_timeout 和 timeout_policy）
+	Timeout   time.Duration `yaml:"timeout"`
+	OnTimeout string        `yaml:"on_timeout"`
+}
+
+// RefusalConfig 定义拒答/报错检测的配置。
+// 内置多语言拒答特征和常见报错特征，关键词作为额外特征追加。
+// 内置报错特征仅在报错内容位于答案开头或占答案大部分时计入，解释报错的正常答案不受影响。
+type RefusalConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Threshold 判定为拒答或报错的最低命中分数（0-1，各特征按权重和出现位置累加）
+	Threshold float64 `yaml:"threshold"`
+
+	// 额外的道歉/拒答关键词和错误关键词（单个关键词即可判定：道歉关键词在答案开头或短答案中生效，错误关键词不论位置）
+	ApologyKeywords []string `yaml:"apology_keywords"`
+	ErrorKeywords   []string `yaml:"error_keywords"`
+}
+
+// JudgeConfig 定义 LLM 评审质量检查的配置。
+// 评审超时使用 QualityConfig.CheckTimeout。
+type JudgeConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Threshold 通过评审的最低分数（0-1，评审输出的 0-10 分按比例换算）
+	Threshold float64 `yaml:"threshold"`
+
+	// Rubric 评分标准提示词（为空时使用内置评分标准）
+	Rubric string `yaml:"rubric"`
+
+	// FailMode 评审调用失败或结果无法解析时的处理方式：closed（拒绝写入，默认）, open（放行）
+	FailMode string `yaml:"fail_mode"`
 }
 
 // CallbacksConfig 定义 Eino 框架的回调系统配置。
@@ -316,6 +733,28 @@ func DefaultEinoConfig() *EinoConfig {
 			Provider: "openai",
 			Model:    "text-embedding-3-small",
 			Timeout:  30,
+			Cache: EmbeddingCacheConfig{
+				Enabled:    false,
+				MaxEntries: 10000,
+				Backend:    "memory",
+				Dir:        "data/embeddings",
+				Redis: EmbeddingCacheRedisConfig{
+					Addr:      "localhost:6379",
+					KeyPrefix: "llm_cache:embedding:",
+				},
+			},
+			Batching: EmbeddingBatchingConfig{
+				Enabled:      false,
+				Window:       10 * time.Millisecond,
+				MaxBatchSize: 32,
+			},
+			Resilience: EmbeddingResilienceConfig{
+				Enabled:          false,
+				InitialBackoff:   200 * time.Millisecond,
+				MaxBackoff:       5 * time.Second,
+				FailureThreshold: 5,
+				OpenDuration:     30 * time.Second,
+			},
 		},
 		Retriever: RetrieverConfig{
 			Provider:       "qdrant",
@@ -327,6 +766,15 @@ func DefaultEinoConfig() *EinoConfig {
 				Port: 6334,
 			},
 		},
+		Reranker: RerankerConfig{
+			Enabled:  false,
+			Provider: "lexical",
+			Timeout:  10,
+		},
+		ChatModel: ChatModelConfig{
+			Provider: "openai",
+			Timeout:  30,
+		},
 		Indexer: IndexerConfig{
 			Provider:   "qdrant",
 			Collection: "llm_cache",
@@ -344,12 +792,38 @@ func DefaultEinoConfig() *EinoConfig {
 			Temperature:        0.7,
 			EmbeddingTimeout:   30,
 			RetrieveTimeout:    30,
+			EntityGuard: EntityGuardConfig{
+				Enabled: true,
+			},
+			NegationGuard: NegationGuardConfig{
+				Enabled:      false,
+				Action:       "reject",
+				DemoteFactor: 0.5,
+			},
+			Adaptation: AdaptationConfig{
+				Enabled:   false,
+				Threshold: 0.6,
+				Timeout:   15,
+			},
+			Coalescing: CoalescingConfig{
+				Enabled: false,
+			},
 		},
 		Store: StoreConfig{
 			QualityCheckEnabled: true,
 			MinQuestionLength:   5,
 			MinAnswerLength:     10,
 			ScoreThreshold:      0.5,
+			Async: AsyncStoreConfig{
+				Enabled:        false,
+				JournalDir:     "data/journal",
+				Workers:        2,
+				QueueSize:      1000,
+				MaxAttempts:    10,
+				InitialBackoff: time.Second,
+				MaxBackoff:     time.Minute,
+				Retention:      24 * time.Hour,
+			},
 		},
 		Quality: QualityConfig{
 			Enabled:                    true,
@@ -361,7 +835,39 @@ func DefaultEinoConfig() *EinoConfig {
 			ScoreThreshold:             0.5,
 			ParallelWorkers:            3,
 			CheckTimeout:               5 * time.Second,
+			TimeoutPolicy:              "skip",
 			BlacklistKeywords:          []string{},
+			Refusal: RefusalConfig{
+				Enabled:   true,
+				Threshold: 0.6,
+			},
+			Judge: JudgeConfig{
+				Enabled:   false,
+				Threshold: 0.6,
+				FailMode:  "closed",
+			},
+		},
+		PII: PIIConfig{
+			Enabled:       false,
+			DefaultAction: "mask",
+			QueryLogs:     "omit",
+		},
+		Trust: TrustConfig{
+			Enabled:       false,
+			Confirmations: 2,
+			MaxClockSkew:  5 * time.Minute,
+			LookupTopK:    10,
+		},
+		Conversation: ConversationConfig{
+			MaxTurns: 6,
+			Default: ConversationPolicy{
+				Match:         "exact",
+				MinSimilarity: 0.85,
+			},
+		},
+		Generation: GenerationConfig{
+			Match:              "exact",
+			TemperatureBuckets: []float64{0.3, 0.7, 1.0},
 		},
 		Callbacks: CallbacksConfig{
 			Logging: LoggingCallbackConfig{
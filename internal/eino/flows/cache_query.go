@@ -11,17 +11,29 @@ import (
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 
+	"llm-cache/internal/eino/components"
 	"llm-cache/internal/eino/config"
 	"llm-cache/internal/eino/nodes"
 )
 
 // CacheQueryInput 定义查询请求的输入参数。
 // 包含查询文本、用户类型、TopK 和相似度阈值。
+// Messages 为可选的多轮对话历史，用于计算上下文指纹。
 type CacheQueryInput struct {
-	Query          string  `json:"query"`
-	UserType       string  `json:"user_type"`
-	TopK           int     `json:"top_k,omitempty"`
-	ScoreThreshold float64 `json:"score_threshold,omitempty"`
+	Query          string            `json:"query"`
+	UserType       string            `json:"user_type"`
+	TopK           int               `json:"top_k,omitempty"`
+	ScoreThreshold float64           `json:"score_threshold,omitempty"`
+	Messages       []*schema.Message `json:"messages,omitempty"`
+
+	// Generation 为可选的生成上下文（模型、系统提示词、温度、工具定义）
+	Generation *nodes.GenerationContext `json:"generation,omitempty"`
+
+	// Explain 为 true 时在输出中返回候选及其被拒绝的原因
+	Explain bool `json:"explain,omitempty"`
+
+	// Slots 为可选的槽位取值，命中参数化答案时优先于从问题中提取的取值
+	Slots map[string]string `json:"slots,omitempty"`
 }
 
 // CacheQueryOutput 定义查询请求的输出结果。
@@ -33,6 +45,37 @@ type CacheQueryOutput struct {
 	Score    float64        `json:"score,omitempty"`
 	CacheID  string         `json:"cache_id,omitempty"`
 	Metadata map[string]any `json:"metadata,omitempty"`
+	Explain  *QueryExplain  `json:"explain,omitempty"`
+
+	// Slots 命中参数化答案时填充的槽位取值
+	Slots map[string]string `json:"slots,omitempty"`
+
+	// Adapted 为 true 表示近似命中，答案已由 ChatModel 改写（原答案保存在 metadata.cached_answer）
+	Adapted bool `json:"adapted"`
+}
+
+// queryState 定义查询 Graph 的本地状态（每次执行独立）。
+// 用于在节点之间传递原始请求参数，避免节点输入输出类型被请求级字段污染。
+type queryState struct {
+	Input *CacheQueryInput
+	// Query 预处理并规范化后的查询文本（供检索、重排序使用；守卫节点使用 Input.Query 原文）
+	Query string
+	// Conversation 多轮对话上下文
+	Conversation *nodes.ConversationContext
+	// Generation 生成上下文指纹
+	Generation nodes.GenerationFingerprint
+	// TemplateID 查询匹配的提示词模板 ID
+	TemplateID string
+	// Slots 参数化答案填充的槽位取值
+	Slots map[string]string
+	// HitThreshold 本次请求的命中阈值（低于该阈值的结果为近似命中）
+	HitThreshold float64
+	// Adapted 答案是否经过改写
+	Adapted bool
+	// Candidates 检索阶段返回的候选（用于 explain）
+	Candidates []ExplainCandidate
+	// Rejections 各守卫节点拒绝的候选及原因
+	Rejections []CandidateRejection
 }
 
 // CacheQueryGraph 定义缓存查询的 Eino Graph 流程。
@@ -40,6 +83,15 @@ type CacheQueryOutput struct {
 type CacheQueryGraph struct {
 	embedder         embedding.Embedder
 	retriever        retriever.Retriever
+	reranker         components.Reranker
+	rerankCfg        *config.RerankerConfig
+	contextMatcher   *nodes.ContextMatcher
+	genMatcher       *nodes.GenerationMatcher
+	normalizer       *nodes.Normalizer
+	templates        *nodes.TemplateExtractor
+	slots            *nodes.SlotFiller
+	adapter          *nodes.AnswerAdapter
+	hitThreshold     float64
 	cfg              *config.QueryConfig
 	callbackHandlers []callbacks.Handler
 }
@@ -59,34 +111,352 @@ func NewCacheQueryGraph(
 	return &CacheQueryGraph{
 		embedder:         embedder,
 		retriever:        ret,
+		contextMatcher:   nodes.NewContextMatcher(nil),
+		genMatcher:       nodes.NewGenerationMatcher(nil),
+		slots:            nodes.NewSlotFiller(nil),
 		cfg:              cfg,
 		callbackHandlers: callbackHandlers,
 	}
 }
 
+// WithConversation 设置多轮对话上下文兼容规则（默认要求上下文指纹完全一致）。
+// 参数 cfg: 多轮对话配置。
+// 返回: CacheQueryGraph 指针，便于链式调用。
+func (g *CacheQueryGraph) WithConversation(cfg *config.ConversationConfig) *CacheQueryGraph {
+	g.contextMatcher = nodes.NewContextMatcher(cfg)
+	return g
+}
+
+// WithReranker 为查询 Graph 启用重排序节点（位于检索与结果选择之间）。
+// 参数 reranker: 重排序组件。
+// 参数 cfg: 重排序配置（提供默认阈值）。
+// 返回: CacheQueryGraph 指针，便于链式调用。
+func (g *CacheQueryGraph) WithReranker(reranker components.Reranker, cfg *config.RerankerConfig) *CacheQueryGraph {
+	g.reranker = reranker
+	g.rerankCfg = cfg
+	return g
+}
+
+// WithGeneration 设置生成上下文匹配规则（默认要求生成上下文完全一致）。
+// 参数 cfg: 生成上下文配置。
+// 返回: CacheQueryGraph 指针，便于链式调用。
+func (g *CacheQueryGraph) WithGeneration(cfg *config.GenerationConfig) *CacheQueryGraph {
+	g.genMatcher = nodes.NewGenerationMatcher(cfg)
+	return g
+}
+
+// WithNormalizer 设置查询文本规范化流水线（应与 Store Graph 使用同一配置）。
+// 参数 normalizer: 文本规范化流水线。
+// 返回: CacheQueryGraph 指针，便于链式调用。
+func (g *CacheQueryGraph) WithNormalizer(normalizer *nodes.Normalizer) *CacheQueryGraph {
+	g.normalizer = normalizer
+	return g
+}
+
+// WithTemplates 设置提示词模板剥离（应与 Store Graph 使用同一配置）。
+// 启用后查询仅使用模板中的可变部分检索，且只命中模板一致的缓存。
+// 参数 templates: 提示词模板提取器。
+// 返回: CacheQueryGraph 指针，便于链式调用。
+func (g *CacheQueryGraph) WithTemplates(templates *nodes.TemplateExtractor) *CacheQueryGraph {
+	g.templates = templates
+	return g
+}
+
+// WithSlots 设置参数化答案的槽位配置（提供命名槽位的默认提取正则）。
+// 参数 cfg: 槽位配置。
+// 返回: CacheQueryGraph 指针，便于链式调用。
+func (g *CacheQueryGraph) WithSlots(cfg *config.SlotsConfig) *CacheQueryGraph {
+	g.slots = nodes.NewSlotFiller(cfg)
+	return g
+}
+
+// WithHitThreshold 设置默认命中阈值（请求未指定阈值且未启用重排序时使用，通常为检索器配置的阈值）。
+// 参数 threshold: 默认命中阈值。
+// 返回: CacheQueryGraph 指针，便于链式调用。
+func (g *CacheQueryGraph) WithHitThreshold(threshold float64) *CacheQueryGraph {
+	g.hitThreshold = threshold
+	return g
+}
+
+// WithAdaptation 为查询 Graph 启用近似命中的答案改写节点（位于槽位填充与后处理之间）。
+// 检索阈值降低至改写阈值，分数低于命中阈值的结果由 ChatModel 改写答案后返回。
+// 参数 adapter: 答案改写器。
+// 参数 hitThreshold: 默认命中阈值（请求未指定阈值时使用，通常为检索器配置的阈值）。
+// 返回: CacheQueryGraph 指针，便于链式调用。
+func (g *CacheQueryGraph) WithAdaptation(adapter *nodes.AnswerAdapter, hitThreshold float64) *CacheQueryGraph {
+	g.adapter = adapter
+	g.hitThreshold = hitThreshold
+	return g
+}
+
 // Compile 将 Graph 编译为可执行的 Runnable 实例。
-// 构建节点（预处理、检索、选择、后处理）和边，并应用配置。
+// 构建节点（预处理、检索、重排序、候选守卫、选择、槽位填充、答案改写、后处理）和边，并应用配置。
 // 参数 ctx: 上下文对象。
 // 返回: 可执行的 Runnable 对象或错误。
 func (g *CacheQueryGraph) Compile(ctx context.Context) (compose.Runnable[*CacheQueryInput, *CacheQueryOutput], error) {
-	graph := compose.NewGraph[*CacheQueryInput, *CacheQueryOutput]()
+	entityGuard, err := nodes.NewEntityGuard(&g.cfg.EntityGuard)
+	if err != nil {
+		return nil, fmt.Errorf("create entity guard: %w", err)
+	}
+	negationGuard := nodes.NewNegationGuard(&g.cfg.NegationGuard)
+
+	graph := compose.NewGraph[*CacheQueryInput, *CacheQueryOutput](
+		compose.WithGenLocalState(func(ctx context.Context) *queryState {
+			return &queryState{}
+		}),
+	)
 
-	// 1. 添加预处理节点
+	// 1. 添加预处理节点（同时将原始请求和对话上下文写入本地状态）
 	preprocessNode := compose.InvokableLambda(func(ctx context.Context, input *CacheQueryInput) (string, error) {
+		query := input.Query
 		if g.cfg.PreprocessEnabled {
-			return nodes.PreprocessQueryToString(ctx, input.Query)
+			var err error
+			if query, err = nodes.PreprocessQueryToString(ctx, query); err != nil {
+				return "", err
+			}
 		}
-		return input.Query, nil
+		if g.normalizer != nil {
+			query = g.normalizer.Normalize(query)
+		}
+		return query, nil
 	})
-	if err := graph.AddLambdaNode("preprocess", preprocessNode); err != nil {
+	if err := graph.AddLambdaNode("preprocess", preprocessNode,
+		compose.WithStatePreHandler(func(ctx context.Context, input *CacheQueryInput, state *queryState) (*CacheQueryInput, error) {
+			state.Conversation = nodes.BuildConversationContext(input.Query, input.Messages, g.contextMatcher.MaxTurns())
+			state.Generation = g.genMatcher.Fingerprint(input.Generation)
+
+			// 未显式提供问题时，以最后一轮用户消息作为查询
+			if input.Query == "" && state.Conversation.Question != "" {
+				derived := *input
+				derived.Query = state.Conversation.Question
+				input = &derived
+			}
+			state.Input = input
+
+			// 剥离提示词模板，仅保留可变部分进入预处理（守卫节点仍使用原文）
+			if g.templates != nil {
+				question, templateID := g.templates.Extract(input.UserType, input.Query)
+				state.TemplateID = templateID
+				if templateID != "" {
+					stripped := *input
+					stripped.Query = question
+					return &stripped, nil
+				}
+			}
+			return input, nil
+		}),
+	); err != nil {
 		return nil, fmt.Errorf("add preprocess node: %w", err)
 	}
 
-	// 2. 添加 Retriever 节点
-	if err := graph.AddRetrieverNode("retrieve", g.retriever); err != nil {
+	// 2. 添加检索节点（根据请求参数设置 TopK、阈值和 user_type 过滤）
+	retrieveNode := compose.InvokableLambda(func(ctx context.Context, query string) ([]*schema.Document, error) {
+		var opts []retriever.Option
+		hitThreshold := g.hitThreshold
+		err := compose.ProcessState(ctx, func(_ context.Context, state *queryState) error {
+			state.Query = query
+			opts = buildRetrieveOptions(state.Input)
+			if state.Input.ScoreThreshold > 0 {
+				hitThreshold = state.Input.ScoreThreshold
+			}
+			state.HitThreshold = hitThreshold
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if g.reranker != nil {
+			// 启用重排序时检索阶段不过滤，阈值在重排序后应用
+			opts = append(opts, retriever.WithScoreThreshold(0))
+		} else if g.adapter != nil {
+			// 启用答案改写时检索阶段使用改写阈值，保留近似命中的结果
+			opts = append(opts, retriever.WithScoreThreshold(min(hitThreshold, g.adapter.Threshold())))
+		}
+
+		docs, err := g.retriever.Retrieve(ctx, query, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		err = compose.ProcessState(ctx, func(_ context.Context, state *queryState) error {
+			if state.Input.Explain {
+				state.Candidates = buildExplainCandidates(docs)
+			}
+			return nil
+		})
+		return docs, err
+	})
+	if err := graph.AddLambdaNode("retrieve", retrieveNode); err != nil {
 		return nil, fmt.Errorf("add retriever node: %w", err)
 	}
 
+	// 2.1 添加重排序节点（可选）
+	if g.reranker != nil {
+		rerankNode := compose.InvokableLambda(func(ctx context.Context, docs []*schema.Document) ([]*schema.Document, error) {
+			var query string
+			threshold := g.rerankCfg.ScoreThreshold
+			err := compose.ProcessState(ctx, func(_ context.Context, state *queryState) error {
+				query = state.Query
+				if state.Input != nil && state.Input.ScoreThreshold > 0 {
+					threshold = state.Input.ScoreThreshold
+				}
+				state.HitThreshold = threshold
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			reranked, err := g.reranker.Rerank(ctx, query, docs)
+			if err != nil {
+				return nil, fmt.Errorf("rerank documents: %w", err)
+			}
+			if g.adapter != nil {
+				threshold = min(threshold, g.adapter.Threshold())
+			}
+			return nodes.ApplyRerankScores(reranked, threshold), nil
+		})
+		if err := graph.AddLambdaNode("rerank", rerankNode); err != nil {
+			return nil, fmt.Errorf("add rerank node: %w", err)
+		}
+	}
+
+	// 2.2 添加待确认过滤节点（跳过低信任来源写入且尚未确认的候选）
+	probationNode := newGuardNode("probation_filter", func(ctx context.Context, state queryState) (candidateFilter, error) {
+		return func(doc *schema.Document) (bool, string) {
+			if nodes.InProbation(doc) {
+				return false, "cached entry awaiting confirmation"
+			}
+			return true, ""
+		}, nil
+	})
+	if err := graph.AddLambdaNode("probation_filter", probationNode); err != nil {
+		return nil, fmt.Errorf("add probation_filter node: %w", err)
+	}
+
+	// 2.3 添加 PII 过滤节点（跳过标记为包含未脱敏个人敏感信息的候选）
+	piiNode := newGuardNode("pii_filter", func(ctx context.Context, state queryState) (candidateFilter, error) {
+		return func(doc *schema.Document) (bool, string) {
+			if nodes.ContainsPII(doc) {
+				return false, "cached entry contains pii"
+			}
+			return true, ""
+		}, nil
+	})
+	if err := graph.AddLambdaNode("pii_filter", piiNode); err != nil {
+		return nil, fmt.Errorf("add pii_filter node: %w", err)
+	}
+
+	// 2.4 添加模板过滤节点（仅保留提示词模板一致的候选）
+	templateNode := newGuardNode("template_filter", func(ctx context.Context, state queryState) (candidateFilter, error) {
+		if g.templates == nil || !g.templates.Enabled() {
+			return nil, nil
+		}
+		return func(doc *schema.Document) (bool, string) {
+			return g.templates.Compatible(doc, state.TemplateID)
+		}, nil
+	})
+	if err := graph.AddLambdaNode("template_filter", templateNode); err != nil {
+		return nil, fmt.Errorf("add template_filter node: %w", err)
+	}
+
+	// 2.5 添加上下文过滤节点（仅保留对话上下文兼容的候选）
+	contextNode := newGuardNode("context_filter", func(ctx context.Context, state queryState) (candidateFilter, error) {
+		userType := state.Input.UserType
+		cc := state.Conversation
+
+		// semantic 规则需要当前上下文的向量
+		var vector []float64
+		if cc.Hash != "" && g.contextMatcher.NeedsVector(userType) {
+			vectors, err := g.embedder.EmbedStrings(ctx, []string{cc.Text})
+			if err != nil {
+				return nil, fmt.Errorf("embed conversation context: %w", err)
+			}
+			if len(vectors) > 0 {
+				vector = vectors[0]
+			}
+		}
+
+		return func(doc *schema.Document) (bool, string) {
+			if !g.contextMatcher.Compatible(doc, userType, cc, vector) {
+				return false, "conversation context mismatch"
+			}
+			delete(doc.MetaData, nodes.MetaContextVector)
+			return true, ""
+		}, nil
+	})
+	if err := graph.AddLambdaNode("context_filter", contextNode); err != nil {
+		return nil, fmt.Errorf("add context_filter node: %w", err)
+	}
+
+	// 2.6 添加生成上下文过滤节点（仅保留生成参数匹配的候选）
+	generationNode := newGuardNode("generation_filter", func(ctx context.Context, state queryState) (candidateFilter, error) {
+		return func(doc *schema.Document) (bool, string) {
+			if ok, field := g.genMatcher.Compatible(doc, state.Generation); !ok {
+				return false, "generation context mismatch: " + field
+			}
+			return true, ""
+		}, nil
+	})
+	if err := graph.AddLambdaNode("generation_filter", generationNode); err != nil {
+		return nil, fmt.Errorf("add generation_filter node: %w", err)
+	}
+
+	// 2.7 添加实体冲突守卫节点（数字、日期、版本号、金额、自定义实体冲突时拒绝）
+	entityNode := newGuardNode("entity_guard", func(ctx context.Context, state queryState) (candidateFilter, error) {
+		if !g.cfg.EntityGuard.Enabled {
+			return nil, nil
+		}
+		queryEntities := entityGuard.Extract(state.Input.Query)
+		return func(doc *schema.Document) (bool, string) {
+			question := nodes.ExtractQuestion(doc)
+			if question == "" {
+				question = doc.Content
+			}
+			// 参数化答案的槽位取值允许不同，比较前从双方遮盖
+			if rules, _ := g.slots.Rules(doc); len(rules) > 0 {
+				masked := entityGuard.Extract(g.slots.Mask(rules, state.Input.Query))
+				return entityGuard.Check(masked, g.slots.Mask(rules, question))
+			}
+			return entityGuard.Check(queryEntities, question)
+		}, nil
+	})
+	if err := graph.AddLambdaNode("entity_guard", entityNode); err != nil {
+		return nil, fmt.Errorf("add entity_guard node: %w", err)
+	}
+
+	// 2.8 添加否定/极性冲突守卫节点（极性不一致的候选被拒绝或降权）
+	negationNode := newGuardNode("negation_guard", func(ctx context.Context, state queryState) (candidateFilter, error) {
+		if !g.cfg.NegationGuard.Enabled {
+			return nil, nil
+		}
+		queryNegated := negationGuard.Negated(state.Input.Query)
+		hitThreshold := state.HitThreshold
+		return func(doc *schema.Document) (bool, string) {
+			question := nodes.ExtractQuestion(doc)
+			if question == "" {
+				question = doc.Content
+			}
+			ok, reason := negationGuard.Check(queryNegated, question)
+			if ok || negationGuard.Action() == nodes.NegationActionReject {
+				return ok, reason
+			}
+
+			// 降权模式：降低分数后参与结果选择，低于命中阈值或最低分数时拒绝（结果选择节点不再校验阈值）
+			score, keep := negationGuard.Demote(nodes.ExtractScore(doc), hitThreshold)
+			if !keep {
+				return false, reason
+			}
+			doc.MetaData["score"] = score
+			doc.MetaData["polarity_mismatch"] = true
+			doc.WithScore(score)
+			return true, ""
+		}, nil
+	})
+	if err := graph.AddLambdaNode("negation_guard", negationNode); err != nil {
+		return nil, fmt.Errorf("add negation_guard node: %w", err)
+	}
+
 	// 3. 添加结果选择节点
 	selector := nodes.NewResultSelector(g.cfg.SelectionStrategy, g.cfg.Temperature)
 	selectNode := compose.InvokableLambda(selector.Select)
@@ -94,16 +464,140 @@ func (g *CacheQueryGraph) Compile(ctx context.Context) (compose.Runnable[*CacheQ
 		return nil, fmt.Errorf("add select node: %w", err)
 	}
 
+	// 3.1 添加槽位填充节点（参数化答案从新问题中提取槽位取值，无法解析时视为未命中）
+	slotNode := compose.InvokableLambda(func(ctx context.Context, doc *schema.Document) (*schema.Document, error) {
+		if doc == nil {
+			return nil, nil
+		}
+
+		var input *CacheQueryInput
+		err := compose.ProcessState(ctx, func(_ context.Context, state *queryState) error {
+			input = state.Input
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		rules, fillErr := g.slots.Rules(doc)
+		if fillErr == nil && len(rules) == 0 {
+			return doc, nil
+		}
+		var answer string
+		var values map[string]string
+		if fillErr == nil {
+			template, _ := doc.MetaData["answer"].(string)
+			answer, values, fillErr = g.slots.Fill(template, rules, input.Query, input.Slots)
+		}
+
+		err = compose.ProcessState(ctx, func(_ context.Context, state *queryState) error {
+			if fillErr != nil {
+				state.Rejections = append(state.Rejections, CandidateRejection{
+					CacheID:  doc.ID,
+					Question: nodes.ExtractQuestion(doc),
+					Score:    nodes.ExtractScore(doc),
+					Stage:    "slot_fill",
+					Reason:   fillErr.Error(),
+				})
+				return nil
+			}
+			state.Slots = values
+			return nil
+		})
+		if err != nil || fillErr != nil {
+			return nil, err
+		}
+
+		doc.MetaData["answer"] = answer
+		return doc, nil
+	})
+	if err := graph.AddLambdaNode("slot_fill", slotNode); err != nil {
+		return nil, fmt.Errorf("add slot_fill node: %w", err)
+	}
+
+	// 3.2 添加答案改写节点（可选，近似命中时使用 ChatModel 改写答案，改写失败视为未命中）
+	if g.adapter != nil {
+		adaptNode := compose.InvokableLambda(func(ctx context.Context, doc *schema.Document) (*schema.Document, error) {
+			if doc == nil {
+				return nil, nil
+			}
+
+			var question string
+			var hitThreshold float64
+			err := compose.ProcessState(ctx, func(_ context.Context, state *queryState) error {
+				question = state.Input.Query
+				hitThreshold = state.HitThreshold
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if nodes.ExtractScore(doc) >= hitThreshold {
+				return doc, nil
+			}
+
+			cachedAnswer, _ := doc.MetaData["answer"].(string)
+			adapted, adaptErr := g.adapter.Adapt(ctx, nodes.ExtractQuestion(doc), cachedAnswer, question)
+
+			err = compose.ProcessState(ctx, func(_ context.Context, state *queryState) error {
+				if adaptErr != nil {
+					state.Rejections = append(state.Rejections, CandidateRejection{
+						CacheID:  doc.ID,
+						Question: nodes.ExtractQuestion(doc),
+						Score:    nodes.ExtractScore(doc),
+						Stage:    "adaptation",
+						Reason:   adaptErr.Error(),
+					})
+					return nil
+				}
+				state.Adapted = true
+				return nil
+			})
+			if err != nil || adaptErr != nil {
+				return nil, err
+			}
+
+			doc.MetaData["cached_answer"] = cachedAnswer
+			doc.MetaData["answer"] = adapted
+			return doc, nil
+		})
+		if err := graph.AddLambdaNode("adaptation", adaptNode); err != nil {
+			return nil, fmt.Errorf("add adaptation node: %w", err)
+		}
+	}
+
 	// 4. 添加后处理节点
 	postprocessNode := compose.InvokableLambda(func(ctx context.Context, doc *schema.Document) (*CacheQueryOutput, error) {
+		var explain *QueryExplain
+		var slots map[string]string
+		var adapted bool
+		err := compose.ProcessState(ctx, func(_ context.Context, state *queryState) error {
+			slots = state.Slots
+			adapted = state.Adapted
+			if state.Input.Explain {
+				explain = &QueryExplain{
+					Query:      state.Query,
+					Candidates: state.Candidates,
+					Rejections: state.Rejections,
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
 		if doc == nil {
-			return &CacheQueryOutput{Hit: false}, nil
+			return &CacheQueryOutput{Hit: false, Explain: explain}, nil
 		}
 
 		output := &CacheQueryOutput{
 			Hit:      true,
 			CacheID:  doc.ID,
 			Metadata: doc.MetaData,
+			Explain:  explain,
+			Slots:    slots,
+			Adapted:  adapted,
 		}
 
 		// 从 MetaData 提取问答
@@ -123,21 +617,18 @@ func (g *CacheQueryGraph) Compile(ctx context.Context) (compose.Runnable[*CacheQ
 		return nil, fmt.Errorf("add postprocess node: %w", err)
 	}
 
-	// 5. 连接节点
-	if err := graph.AddEdge(compose.START, "preprocess"); err != nil {
-		return nil, fmt.Errorf("add edge START->preprocess: %w", err)
-	}
-	if err := graph.AddEdge("preprocess", "retrieve"); err != nil {
-		return nil, fmt.Errorf("add edge preprocess->retrieve: %w", err)
+	// 5. 按顺序连接节点
+	chain := []string{compose.START, "preprocess", "retrieve"}
+	if g.reranker != nil {
+		chain = append(chain, "rerank")
 	}
-	if err := graph.AddEdge("retrieve", "select"); err != nil {
-		return nil, fmt.Errorf("add edge retrieve->select: %w", err)
+	chain = append(chain, "probation_filter", "pii_filter", "template_filter", "context_filter", "generation_filter", "entity_guard", "negation_guard", "select", "slot_fill")
+	if g.adapter != nil {
+		chain = append(chain, "adaptation")
 	}
-	if err := graph.AddEdge("select", "postprocess"); err != nil {
-		return nil, fmt.Errorf("add edge select->postprocess: %w", err)
-	}
-	if err := graph.AddEdge("postprocess", compose.END); err != nil {
-		return nil, fmt.Errorf("add edge postprocess->END: %w", err)
+	chain = append(chain, "postprocess", compose.END)
+	if err := addChainEdges(graph, chain); err != nil {
+		return nil, err
 	}
 
 	// 编译 Graph（带 Callback 处理器）
@@ -156,6 +647,33 @@ func (g *CacheQueryGraph) Compile(ctx context.Context) (compose.Runnable[*CacheQ
 	return runnable, nil
 }
 
+// addChainEdges 按顺序连接节点列表中相邻的节点
+func addChainEdges(graph *compose.Graph[*CacheQueryInput, *CacheQueryOutput], chain []string) error {
+	for i := 0; i+1 < len(chain); i++ {
+		if err := graph.AddEdge(chain[i], chain[i+1]); err != nil {
+			return fmt.Errorf("add edge %s->%s: %w", chain[i], chain[i+1], err)
+		}
+	}
+	return nil
+}
+
+// buildRetrieveOptions 根据查询输入构建检索选项。
+// 仅在请求显式指定时覆盖 Retriever 的默认 TopK 和阈值。
+func buildRetrieveOptions(input *CacheQueryInput) []retriever.Option {
+	if input == nil {
+		return nil
+	}
+
+	opts := []retriever.Option{components.WithUserType(input.UserType)}
+	if input.TopK > 0 {
+		opts = append(opts, retriever.WithTopK(input.TopK))
+	}
+	if input.ScoreThreshold > 0 {
+		opts = append(opts, retriever.WithScoreThreshold(input.ScoreThreshold))
+	}
+	return opts
+}
+
 // Run 执行一次完整的缓存查询流程。
 // 编译并运行 Graph。
 // 参数 ctx: 上下文对象。
@@ -0,0 +1,74 @@
+package flows
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// staticRetriever 返回固定的候选（每次检索返回副本）
+type staticRetriever struct {
+	docs []*schema.Document
+}
+
+func (r *staticRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	docs := make([]*schema.Document, len(r.docs))
+	for i, doc := range r.docs {
+		docs[i] = &schema.Document{ID: doc.ID, Content: doc.Content, MetaData: maps.Clone(doc.MetaData)}
+	}
+	return docs, nil
+}
+
+func TestCacheQueryNegationDemote(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		scoreThreshold float64
+		wantHit        bool
+	}{
+		{"conflict below hit threshold is a miss", "为什么不能取消订单", 0, false},
+		{"same polarity is a hit", "如何取消订单", 0, true},
+		{"conflict above request threshold is kept", "为什么不能取消订单", 0.4, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ret := &staticRetriever{docs: []*schema.Document{{
+				ID:      "c1",
+				Content: "如何取消订单",
+				MetaData: map[string]any{
+					"question": "如何取消订单",
+					"answer":   "在订单详情页点击取消",
+					"score":    0.9,
+				},
+			}}}
+			cfg := &config.QueryConfig{
+				SelectionStrategy: "highest_score",
+				NegationGuard:     config.NegationGuardConfig{Enabled: true, Action: "demote", DemoteFactor: 0.5},
+			}
+			runner, err := NewCacheQueryGraph(constantEmbedder{}, ret, cfg).
+				WithHitThreshold(0.8).
+				Compile(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output, err := runner.Invoke(context.Background(), &CacheQueryInput{
+				Query:          tt.query,
+				UserType:       "default",
+				ScoreThreshold: tt.scoreThreshold,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.Hit != tt.wantHit {
+				t.Errorf("expected hit=%v, got %+v", tt.wantHit, output)
+			}
+		})
+	}
+}
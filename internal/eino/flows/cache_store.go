@@ -3,28 +3,45 @@ package flows
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/callbacks"
 	"github.com/cloudwego/eino/components/embedding"
 	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/components/retriever"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 	"github.com/google/uuid"
 
+	"llm-cache/internal/eino/components"
 	"llm-cache/internal/eino/config"
 	"llm-cache/internal/eino/nodes"
 )
 
 // CacheStoreInput 定义缓存存储请求的输入参数。
 // 包含问答对、用户类型、元数据和强制写入标志。
+// Messages 为可选的多轮对话历史，用于计算上下文指纹。
 type CacheStoreInput struct {
-	Question   string         `json:"question"`
-	Answer     string         `json:"answer"`
-	UserType   string         `json:"user_type"`
-	Metadata   map[string]any `json:"metadata,omitempty"`
-	ForceWrite bool           `json:"force_write,omitempty"`
+	Question   string            `json:"question"`
+	Answer     string            `json:"answer"`
+	UserType   string            `json:"user_type"`
+	Metadata   map[string]any    `json:"metadata,omitempty"`
+	ForceWrite bool              `json:"force_write,omitempty"`
+	Messages   []*schema.Message `json:"messages,omitempty"`
+
+	// Generation 为可选的生成上下文（模型、系统提示词、温度、工具定义）
+	Generation *nodes.GenerationContext `json:"generation,omitempty"`
+
+	// Slots 为可选的槽位规则，非空时 Answer 作为带 {name} 槽位的答案模板
+	Slots []nodes.SlotRule `json:"slots,omitempty"`
+
+	// Source、Trust 为认证后的存储来源及其信任等级（由服务端设置，不接受客户端传入）
+	Source string `json:"-"`
+	Trust  string `json:"-"`
 }
 
 // CacheStoreOutput 定义缓存存储请求的输出结果。
@@ -34,12 +51,20 @@ type CacheStoreOutput struct {
 	CacheID  string `json:"cache_id,omitempty"`
 	Rejected bool   `json:"rejected,omitempty"`
 	Reason   string `json:"reason,omitempty"`
+	// QualityScore 质量检查的综合分数，QualityDetails 为各评估策略的明细（跳过质量检查时为空）
+	QualityScore   float64              `json:"quality_score,omitempty"`
+	QualityDetails []*nodes.CheckDetail `json:"quality_details,omitempty"`
+	// Status 条目状态（启用来源信任时返回 active 或 probation），Confirmations 为已确认的独立来源数
+	Status        string `json:"status,omitempty"`
+	Confirmations int    `json:"confirmations,omitempty"`
 }
 
 // EmbeddingResult 定义嵌入处理的中间结果（内部使用）。
 // 包含原始数据、生成的向量以及可能的拒绝原因。
 type EmbeddingResult struct {
 	Question string
+	// Content 规范化后的问题（作为索引内容）
+	Content  string
 	Answer   string
 	UserType string
 	Metadata map[string]any
@@ -48,6 +73,15 @@ type EmbeddingResult struct {
 	Reason   string
 }
 
+// storeState 定义存储 Graph 的本地状态（每次执行独立）。
+// 用于在节点之间传递原始请求中不经过质量检查节点的字段。
+type storeState struct {
+	Input   *CacheStoreInput
+	Quality *nodes.QualityCheckResult
+	// PIIReason PII 扫描拒绝写入的原因（为空表示通过）
+	PIIReason string
+}
+
 // CacheStoreGraph 定义缓存存储的 Eino Graph 流程。
 // 包含质量检查、文本向量化和索引存储等步骤，支持基于质量检查结果的条件分支。
 type CacheStoreGraph struct {
@@ -55,6 +89,16 @@ type CacheStoreGraph struct {
 	indexer          indexer.Indexer
 	cfg              *config.StoreConfig
 	quality          *config.QualityConfig
+	contextMatcher   *nodes.ContextMatcher
+	genMatcher       *nodes.GenerationMatcher
+	normalizer       *nodes.Normalizer
+	templates        *nodes.TemplateExtractor
+	slots            *nodes.SlotFiller
+	judge            *nodes.QualityJudge
+	pii              *nodes.PIIScanner
+	trust            *nodes.TrustPolicy
+	trustCfg         *config.TrustConfig
+	retriever        retriever.Retriever
 	callbackHandlers []callbacks.Handler
 }
 
@@ -77,19 +121,111 @@ func NewCacheStoreGraph(
 		indexer:          idx,
 		cfg:              cfg,
 		quality:          quality,
+		contextMatcher:   nodes.NewContextMatcher(nil),
+		genMatcher:       nodes.NewGenerationMatcher(nil),
+		slots:            nodes.NewSlotFiller(nil),
 		callbackHandlers: callbackHandlers,
 	}
 }
 
+// WithConversation 设置多轮对话配置（决定指纹窗口以及是否需要保存上下文向量）。
+// 参数 cfg: 多轮对话配置。
+// 返回: CacheStoreGraph 指针，便于链式调用。
+func (g *CacheStoreGraph) WithConversation(cfg *config.ConversationConfig) *CacheStoreGraph {
+	g.contextMatcher = nodes.NewContextMatcher(cfg)
+	return g
+}
+
+// WithGeneration 设置生成上下文配置（决定温度分桶方式）。
+// 参数 cfg: 生成上下文配置。
+// 返回: CacheStoreGraph 指针，便于链式调用。
+func (g *CacheStoreGraph) WithGeneration(cfg *config.GenerationConfig) *CacheStoreGraph {
+	g.genMatcher = nodes.NewGenerationMatcher(cfg)
+	return g
+}
+
+// WithNormalizer 设置问题文本规范化流水线（应与 Query Graph 使用同一配置）。
+// 规范化后的问题用于向量化和索引内容，原始问题保存在 question 元数据中。
+// 参数 normalizer: 文本规范化流水线。
+// 返回: CacheStoreGraph 指针，便于链式调用。
+func (g *CacheStoreGraph) WithNormalizer(normalizer *nodes.Normalizer) *CacheStoreGraph {
+	g.normalizer = normalizer
+	return g
+}
+
+// WithTemplates 设置提示词模板剥离（应与 Query Graph 使用同一配置）。
+// 启用后仅使用问题中的可变部分向量化，匹配的模板 ID 写入 template_id 元数据。
+// 参数 templates: 提示词模板提取器。
+// 返回: CacheStoreGraph 指针，便于链式调用。
+func (g *CacheStoreGraph) WithTemplates(templates *nodes.TemplateExtractor) *CacheStoreGraph {
+	g.templates = templates
+	return g
+}
+
+// WithSlots 设置参数化答案的槽位配置（提供命名槽位的默认提取正则）。
+// 参数 cfg: 槽位配置。
+// 返回: CacheStoreGraph 指针，便于链式调用。
+func (g *CacheStoreGraph) WithSlots(cfg *config.SlotsConfig) *CacheStoreGraph {
+	g.slots = nodes.NewSlotFiller(cfg)
+	return g
+}
+
+// WithJudge 设置 LLM 评审器（在规则质量检查通过后评估答案是否回答了问题）。
+// 参数 judge: LLM 评审器。
+// 返回: CacheStoreGraph 指针，便于链式调用。
+func (g *CacheStoreGraph) WithJudge(judge *nodes.QualityJudge) *CacheStoreGraph {
+	g.judge = judge
+	return g
+}
+
+// WithPII 设置个人敏感信息扫描（在质量检查之前执行，强制写入也不会跳过）。
+// 参数 scanner: PII 扫描器。
+// 返回: CacheStoreGraph 指针，便于链式调用。
+func (g *CacheStoreGraph) WithPII(scanner *nodes.PIIScanner) *CacheStoreGraph {
+	g.pii = scanner
+	return g
+}
+
+// WithTrust 设置存储来源的信任分级。
+// 启用后同一问答对使用确定性 ID，低信任来源写入的条目处于待确认状态，
+// 其他来源写入同一问答对时通过 Retriever 找到已有条目并记录确认。
+// 参数 cfg: 来源信任配置。
+// 参数 r: 用于查找已有条目的 Retriever（应与 Query Graph 使用同一集合）。
+// 返回: CacheStoreGraph 指针，便于链式调用。
+func (g *CacheStoreGraph) WithTrust(cfg *config.TrustConfig, r retriever.Retriever) *CacheStoreGraph {
+	g.trust = nodes.NewTrustPolicy(cfg)
+	g.trustCfg = cfg
+	g.retriever = r
+	return g
+}
+
+// QualityChecker 创建与存储流程一致的质量检查器（配置的评估策略和 LLM 评审）。
+// 用于质量检查试运行，不经过 PII 扫描、向量化和写入。
+// 返回: QualityChecker 指针，评估策略配置无效时返回错误。
+func (g *CacheStoreGraph) QualityChecker() (*nodes.QualityChecker, error) {
+	strategies, err := nodes.NewQualityStrategies(g.quality)
+	if err != nil {
+		return nil, fmt.Errorf("create quality strategies: %w", err)
+	}
+	return nodes.NewQualityChecker(g.quality).WithStrategies(strategies).WithJudge(g.judge), nil
+}
+
 // Compile 将 Graph 编译为可执行的 Runnable 实例。
 // 构建节点（质量检查、Embedding、索引）和分支逻辑。
 // 参数 ctx: 上下文对象。
 // 返回: 可执行的 Runnable 对象或错误。
 func (g *CacheStoreGraph) Compile(ctx context.Context) (compose.Runnable[*CacheStoreInput, *CacheStoreOutput], error) {
-	graph := compose.NewGraph[*CacheStoreInput, *CacheStoreOutput]()
+	graph := compose.NewGraph[*CacheStoreInput, *CacheStoreOutput](
+		compose.WithGenLocalState(func(ctx context.Context) *storeState {
+			return &storeState{}
+		}),
+	)
 
 	// 1. 添加质量检查节点
-	qualityChecker := nodes.NewQualityChecker(g.quality)
+	qualityChecker, err := g.QualityChecker()
+	if err != nil {
+		return nil, err
+	}
 	qualityNode := compose.InvokableLambda(func(ctx context.Context, input *CacheStoreInput) (*nodes.QualityCheckResult, error) {
 		return qualityChecker.Check(ctx, &nodes.QualityCheckInput{
 			Question:   input.Question,
@@ -99,7 +235,16 @@ func (g *CacheStoreGraph) Compile(ctx context.Context) (compose.Runnable[*CacheS
 			ForceWrite: input.ForceWrite,
 		})
 	})
-	if err := graph.AddLambdaNode("quality_check", qualityNode); err != nil {
+	if err := graph.AddLambdaNode("quality_check", qualityNode,
+		compose.WithStatePreHandler(func(ctx context.Context, input *CacheStoreInput, state *storeState) (*CacheStoreInput, error) {
+			state.Input = input
+			return input, nil
+		}),
+		compose.WithStatePostHandler(func(ctx context.Context, result *nodes.QualityCheckResult, state *storeState) (*nodes.QualityCheckResult, error) {
+			state.Quality = result
+			return result, nil
+		}),
+	); err != nil {
 		return nil, fmt.Errorf("add quality_check node: %w", err)
 	}
 
@@ -112,19 +257,113 @@ func (g *CacheStoreGraph) Compile(ctx context.Context) (compose.Runnable[*CacheS
 			}, nil
 		}
 
-		vectors, err := g.embedder.EmbedStrings(ctx, []string{result.Question})
+		var messages []*schema.Message
+		var generation *nodes.GenerationContext
+		var slots []nodes.SlotRule
+		var forceWrite bool
+		err := compose.ProcessState(ctx, func(_ context.Context, state *storeState) error {
+			messages = state.Input.Messages
+			generation = state.Input.Generation
+			slots = state.Input.Slots
+			forceWrite = state.Input.ForceWrite
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// 校验参数化答案的槽位规则（在向量化之前拒绝无效规则）
+		var slotRules string
+		if len(slots) > 0 {
+			if slotRules, err = g.slots.Prepare(result.Answer, slots); err != nil {
+				return &EmbeddingResult{
+					Rejected: true,
+					Reason:   fmt.Sprintf("invalid answer slots: %v", err),
+				}, nil
+			}
+		}
+
+		// 剥离提示词模板并规范化问题文本（与查询侧使用同一流水线）
+		content, templateID := result.Question, ""
+		if g.templates != nil {
+			content, templateID = g.templates.Extract(result.UserType, content)
+		}
+		if g.normalizer != nil {
+			content = g.normalizer.Normalize(content)
+		}
+
+		// 计算对话上下文指纹；semantic 规则下与问题一起批量向量化上下文
+		cc := nodes.BuildConversationContext(result.Question, messages, g.contextMatcher.MaxTurns())
+		texts := []string{content}
+		contextIndex := -1
+		if cc.Hash != "" && g.contextMatcher.NeedsVector(result.UserType) {
+			contextIndex = len(texts)
+			texts = append(texts, cc.Text)
+		}
+
+		// 启用相关性检查时，答案前缀也在同一批次中向量化
+		answerIndex := -1
+		if qualityChecker.RelevanceEnabled(forceWrite) {
+			answerIndex = len(texts)
+			texts = append(texts, nodes.AnswerChunk(result.Answer))
+		}
+
+		vectors, err := g.embedder.EmbedStrings(ctx, texts)
 		if err != nil {
 			return nil, fmt.Errorf("embed question: %w", err)
 		}
-		if len(vectors) == 0 {
+		if len(vectors) < len(texts) {
 			return nil, fmt.Errorf("no embedding generated")
 		}
 
+		// 拒绝问题与答案语义不相关的问答对（通常是调用方错配了问答）
+		var relevance float64
+		if answerIndex >= 0 {
+			score, ok, reason := qualityChecker.CheckRelevance(vectors[0], vectors[answerIndex])
+			if !ok {
+				return &EmbeddingResult{
+					Rejected: true,
+					Reason:   reason,
+				}, nil
+			}
+			relevance = score
+		}
+
+		metadata := make(map[string]any, len(result.Metadata)+12)
+		for k, v := range result.Metadata {
+			metadata[k] = v
+		}
+		for k, v := range cc.Metadata() {
+			metadata[k] = v
+		}
+		for k, v := range g.genMatcher.Metadata(g.genMatcher.Fingerprint(generation)) {
+			metadata[k] = v
+		}
+		metadata[nodes.MetaTemplateID] = templateID
+		if len(result.Details) > 0 {
+			details, err := json.Marshal(result.Details)
+			if err != nil {
+				return nil, fmt.Errorf("marshal quality details: %w", err)
+			}
+			metadata[nodes.MetaQualityScore] = result.Score
+			metadata[nodes.MetaQualityDetails] = string(details)
+		}
+		if slotRules != "" {
+			metadata[nodes.MetaAnswerSlots] = slotRules
+		}
+		if contextIndex >= 0 {
+			metadata[nodes.MetaContextVector] = vectors[contextIndex]
+		}
+		if answerIndex >= 0 {
+			metadata[nodes.MetaRelevanceScore] = relevance
+		}
+
 		return &EmbeddingResult{
 			Question: result.Question,
+			Content:  content,
 			Answer:   result.Answer,
 			UserType: result.UserType,
-			Metadata: result.Metadata,
+			Metadata: metadata,
 			Vector:   vectors[0],
 		}, nil
 	})
@@ -135,32 +374,73 @@ func (g *CacheStoreGraph) Compile(ctx context.Context) (compose.Runnable[*CacheS
 	// 3. 添加 Index 节点
 	indexNode := compose.InvokableLambda(func(ctx context.Context, result *EmbeddingResult) (*CacheStoreOutput, error) {
 		cacheID := generateCacheID()
+		createdAt := time.Now().Unix()
+
+		// 启用来源信任时，记录来源并确认同一问答对的已有条目
+		var trustMeta map[string]any
+		if g.trust != nil {
+			entryID := nodes.EntryID(result.UserType, result.Content, result.Answer, result.Metadata)
+			var existing *schema.Document
+			var err error
+			if cacheID, trustMeta, existing, err = g.resolveTrust(ctx, entryID, result); err != nil {
+				return nil, err
+			}
+			if trustMeta == nil {
+				// 条目已生效或来源重复确认，无需写回
+				output := &CacheStoreOutput{
+					Success:       true,
+					CacheID:       cacheID,
+					Status:        nodes.EntryStatus(existing.MetaData),
+					Confirmations: len(nodes.ConfirmedBy(existing.MetaData)),
+				}
+				if err := fillQualityOutput(ctx, output); err != nil {
+					return nil, err
+				}
+				return output, nil
+			}
+			if existing != nil {
+				if ts, ok := toUnix(existing.MetaData["created_at"]); ok {
+					createdAt = ts
+				}
+			}
+		}
 
 		doc := &schema.Document{
 			ID:      cacheID,
-			Content: result.Question,
+			Content: result.Content,
 			MetaData: map[string]any{
 				"question":   result.Question,
 				"answer":     result.Answer,
 				"user_type":  result.UserType,
-				"created_at": time.Now().Unix(),
+				"created_at": createdAt,
 			},
 		}
 
-		// 合并自定义 Metadata
+		// 合并自定义 Metadata（来源元数据最后写入，不允许客户端覆盖）
 		for k, v := range result.Metadata {
 			doc.MetaData[k] = v
 		}
+		for k, v := range trustMeta {
+			doc.MetaData[k] = v
+		}
 
 		ids, err := g.indexer.Store(ctx, []*schema.Document{doc})
 		if err != nil {
 			return nil, fmt.Errorf("store document: %w", err)
 		}
 
-		return &CacheStoreOutput{
+		output := &CacheStoreOutput{
 			Success: true,
 			CacheID: ids[0],
-		}, nil
+		}
+		if trustMeta != nil {
+			output.Status, _ = trustMeta[nodes.MetaStatus].(string)
+			output.Confirmations, _ = trustMeta[nodes.MetaConfirmations].(int)
+		}
+		if err := fillQualityOutput(ctx, output); err != nil {
+			return nil, err
+		}
+		return output, nil
 	})
 	if err := graph.AddLambdaNode("index_node", indexNode); err != nil {
 		return nil, fmt.Errorf("add index node: %w", err)
@@ -168,11 +448,15 @@ func (g *CacheStoreGraph) Compile(ctx context.Context) (compose.Runnable[*CacheS
 
 	// 4. 添加拒绝节点
 	rejectNode := compose.InvokableLambda(func(ctx context.Context, result *EmbeddingResult) (*CacheStoreOutput, error) {
-		return &CacheStoreOutput{
+		output := &CacheStoreOutput{
 			Success:  false,
 			Rejected: true,
 			Reason:   result.Reason,
-		}, nil
+		}
+		if err := fillQualityOutput(ctx, output); err != nil {
+			return nil, err
+		}
+		return output, nil
 	})
 	if err := graph.AddLambdaNode("reject_node", rejectNode); err != nil {
 		return nil, fmt.Errorf("add reject node: %w", err)
@@ -193,8 +477,12 @@ func (g *CacheStoreGraph) Compile(ctx context.Context) (compose.Runnable[*CacheS
 		return nil, fmt.Errorf("add branch: %w", err)
 	}
 
-	// 6. 连接节点
-	if err := graph.AddEdge(compose.START, "quality_check"); err != nil {
+	// 6. 连接节点（启用 PII 扫描时在质量检查之前执行）
+	if g.pii != nil {
+		if err := g.addPIINodes(graph); err != nil {
+			return nil, err
+		}
+	} else if err := graph.AddEdge(compose.START, "quality_check"); err != nil {
 		return nil, fmt.Errorf("add edge START->quality_check: %w", err)
 	}
 	if err := graph.AddEdge("quality_check", "embedding"); err != nil {
@@ -238,6 +526,198 @@ func (g *CacheStoreGraph) Run(ctx context.Context, input *CacheStoreInput) (*Cac
 	return runnable.Invoke(ctx, input)
 }
 
+// addPIINodes 添加 PII 扫描节点及其拒绝分支。
+// 扫描节点按处理方式脱敏或标记问答对后交给质量检查，需要拒绝时转到 pii_reject 节点。
+func (g *CacheStoreGraph) addPIINodes(graph *compose.Graph[*CacheStoreInput, *CacheStoreOutput]) error {
+	scanNode := compose.InvokableLambda(func(ctx context.Context, input *CacheStoreInput) (*CacheStoreInput, error) {
+		scanned, reason := ScanStoreInput(g.pii, input)
+		if reason != "" {
+			err := compose.ProcessState(ctx, func(_ context.Context, state *storeState) error {
+				state.PIIReason = reason
+				return nil
+			})
+			return input, err
+		}
+		return scanned, nil
+	})
+	if err := graph.AddLambdaNode("pii_scan", scanNode); err != nil {
+		return fmt.Errorf("add pii_scan node: %w", err)
+	}
+
+	rejectNode := compose.InvokableLambda(func(ctx context.Context, input *CacheStoreInput) (*CacheStoreOutput, error) {
+		var reason string
+		err := compose.ProcessState(ctx, func(_ context.Context, state *storeState) error {
+			reason = state.PIIReason
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &CacheStoreOutput{
+			Success:  false,
+			Rejected: true,
+			Reason:   reason,
+		}, nil
+	})
+	if err := graph.AddLambdaNode("pii_reject", rejectNode); err != nil {
+		return fmt.Errorf("add pii_reject node: %w", err)
+	}
+
+	branch := compose.NewGraphBranch(func(ctx context.Context, input *CacheStoreInput) (string, error) {
+		var rejected bool
+		err := compose.ProcessState(ctx, func(_ context.Context, state *storeState) error {
+			rejected = state.PIIReason != ""
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		if rejected {
+			return "pii_reject", nil
+		}
+		return "quality_check", nil
+	}, map[string]bool{
+		"quality_check": true,
+		"pii_reject":    true,
+	})
+	if err := graph.AddBranch("pii_scan", branch); err != nil {
+		return fmt.Errorf("add pii branch: %w", err)
+	}
+
+	if err := graph.AddEdge(compose.START, "pii_scan"); err != nil {
+		return fmt.Errorf("add edge START->pii_scan: %w", err)
+	}
+	if err := graph.AddEdge("pii_reject", compose.END); err != nil {
+		return fmt.Errorf("add edge pii_reject->END: %w", err)
+	}
+	return nil
+}
+
+// ScanStoreInput 按 PII 处理方式扫描存储输入的问题和答案。
+// 已脱敏的文本再次扫描不会产生新的匹配，因此可在写入 Graph 之前预先扫描（如异步写入日志）。
+// 参数 scanner: PII 扫描器。
+// 参数 input: 存储输入。
+// 返回: 脱敏或标记后的输入副本，以及拒绝原因（为空表示通过）。
+func ScanStoreInput(scanner *nodes.PIIScanner, input *CacheStoreInput) (*CacheStoreInput, string) {
+	result := scanner.Process(input.Question, input.Answer)
+	if result.Rejected {
+		return input, result.Reason
+	}
+
+	scanned := *input
+	scanned.Question = result.Question
+	scanned.Answer = result.Answer
+	if len(result.Types) > 0 {
+		scanned.Metadata = make(map[string]any, len(input.Metadata)+2)
+		for k, v := range input.Metadata {
+			scanned.Metadata[k] = v
+		}
+		scanned.Metadata[nodes.MetaPIITypes] = strings.Join(result.Types, ",")
+		if result.Flagged {
+			scanned.Metadata[nodes.MetaContainsPII] = true
+		}
+	}
+	return &scanned, ""
+}
+
+// resolveTrust 计算本次存储的条目 ID 和来源元数据。
+// 已生效的条目写入 entryID，待确认的条目写入 ProbationEntryID(entryID)，
+// 因此未找到已有条目时的低信任写入不会覆盖已生效的条目。
+// 待确认条目生效后原待确认条目保留在索引中（查询时被 probation_filter 跳过）。
+// 同一问答对已有条目时将本次存储作为确认合并；返回 nil 元数据表示无需写回。
+func (g *CacheStoreGraph) resolveTrust(ctx context.Context, entryID string, result *EmbeddingResult) (string, map[string]any, *schema.Document, error) {
+	var source, trust string
+	err := compose.ProcessState(ctx, func(_ context.Context, state *storeState) error {
+		source = state.Input.Source
+		trust = state.Input.Trust
+		return nil
+	})
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if source == "" {
+		source, trust = nodes.AnonymousSource, nodes.TrustLow
+	}
+
+	existing, err := g.findEntry(ctx, entryID, result)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	var metadata map[string]any
+	if existing == nil {
+		metadata = g.trust.Entry(source, trust)
+	} else {
+		var changed bool
+		if metadata, changed = g.trust.Confirm(existing.MetaData, source, trust); !changed {
+			return existing.ID, nil, existing, nil
+		}
+	}
+	if nodes.EntryStatus(metadata) == nodes.StatusActive {
+		return entryID, metadata, existing, nil
+	}
+	return nodes.ProbationEntryID(entryID), metadata, existing, nil
+}
+
+// findEntry 检索同一问答对的已有条目（按确定性 ID 匹配，复用已计算的问题向量）。
+// 已生效的条目优先；否则返回待确认条目。
+func (g *CacheStoreGraph) findEntry(ctx context.Context, entryID string, result *EmbeddingResult) (*schema.Document, error) {
+	opts := []retriever.Option{
+		components.WithUserType(result.UserType),
+		components.WithQueryVector(result.Vector),
+	}
+	if g.trustCfg.LookupTopK > 0 {
+		opts = append(opts, retriever.WithTopK(g.trustCfg.LookupTopK))
+	}
+	docs, err := g.retriever.Retrieve(ctx, result.Content, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("lookup existing entry: %w", err)
+	}
+
+	probationID := nodes.ProbationEntryID(entryID)
+	var entry, probation *schema.Document
+	for _, doc := range docs {
+		switch doc.ID {
+		case entryID:
+			entry = doc
+		case probationID:
+			probation = doc
+		}
+	}
+	if entry != nil && (!nodes.InProbation(entry) || probation == nil) {
+		return entry, nil
+	}
+	return probation, nil
+}
+
+// toUnix 将不同后端返回的时间戳元数据转换为 Unix 秒
+func toUnix(v any) (int64, bool) {
+	switch ts := v.(type) {
+	case int64:
+		return ts, true
+	case int:
+		return int64(ts), true
+	case float64:
+		return int64(ts), true
+	case string:
+		parsed, err := strconv.ParseInt(ts, 10, 64)
+		return parsed, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// fillQualityOutput 将质量检查的综合分数和策略明细写入存储结果
+func fillQualityOutput(ctx context.Context, output *CacheStoreOutput) error {
+	return compose.ProcessState(ctx, func(_ context.Context, state *storeState) error {
+		if state.Quality != nil && len(state.Quality.Details) > 0 {
+			output.QualityScore = state.Quality.Score
+			output.QualityDetails = state.Quality.Details
+		}
+		return nil
+	})
+}
+
 // generateCacheID 生成唯一的缓存项 ID (UUID)。
 func generateCacheID() string {
 	return uuid.New().String()
@@ -0,0 +1,153 @@
+package flows
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+	"llm-cache/internal/eino/nodes"
+)
+
+// constantEmbedder 为所有文本返回相同的向量
+type constantEmbedder struct{}
+
+func (constantEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i := range texts {
+		vectors[i] = []float64{1, 0}
+	}
+	return vectors, nil
+}
+
+// memoryIndex 内存中的 Indexer 和 Retriever；miss 为 true 时检索不返回任何条目（模拟相似度检索未命中）
+type memoryIndex struct {
+	mu   sync.Mutex
+	docs map[string]*schema.Document
+	miss bool
+}
+
+func newMemoryIndex() *memoryIndex {
+	return &memoryIndex{docs: make(map[string]*schema.Document)}
+}
+
+func (m *memoryIndex) Store(ctx context.Context, docs []*schema.Document, opts ...indexer.Option) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		m.docs[doc.ID] = &schema.Document{ID: doc.ID, Content: doc.Content, MetaData: maps.Clone(doc.MetaData)}
+		ids[i] = doc.ID
+	}
+	return ids, nil
+}
+
+func (m *memoryIndex) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.miss {
+		return nil, nil
+	}
+	docs := make([]*schema.Document, 0, len(m.docs))
+	for _, doc := range m.docs {
+		docs = append(docs, &schema.Document{ID: doc.ID, Content: doc.Content, MetaData: maps.Clone(doc.MetaData)})
+	}
+	return docs, nil
+}
+
+func (m *memoryIndex) status(id string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	doc, ok := m.docs[id]
+	if !ok {
+		return ""
+	}
+	return nodes.EntryStatus(doc.MetaData)
+}
+
+func newTrustStoreGraph(t *testing.T, index *memoryIndex, confirmations int) func(source, trust string) *CacheStoreOutput {
+	t.Helper()
+	graph := NewCacheStoreGraph(constantEmbedder{}, index, &config.StoreConfig{}, &config.QualityConfig{}).
+		WithTrust(&config.TrustConfig{Enabled: true, Confirmations: confirmations, LookupTopK: 10}, index)
+	runnable, err := graph.Compile(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return func(source, trust string) *CacheStoreOutput {
+		t.Helper()
+		output, err := runnable.Invoke(context.Background(), &CacheStoreInput{
+			Question:   "如何重置密码",
+			Answer:     "在设置页面点击重置密码",
+			UserType:   "default",
+			ForceWrite: true,
+			Source:     source,
+			Trust:      trust,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return output
+	}
+}
+
+func TestCacheStoreTrustRestore(t *testing.T) {
+	tests := []struct {
+		name string
+		miss bool
+	}{
+		{"lookup finds active entry", false},
+		{"lookup misses active entry", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := newMemoryIndex()
+			store := newTrustStoreGraph(t, index, 2)
+
+			active := store("admin", nodes.TrustHigh)
+			if active.Status != nodes.StatusActive {
+				t.Fatalf("expected %v, got %v", nodes.StatusActive, active.Status)
+			}
+
+			// 低信任来源重复写入同一问答对不会降级已生效的条目
+			index.miss = tt.miss
+			store("crawler", nodes.TrustLow)
+			if got := index.status(active.CacheID); got != nodes.StatusActive {
+				t.Errorf("expected %v, got %v", nodes.StatusActive, got)
+			}
+		})
+	}
+}
+
+func TestCacheStoreTrustConfirmation(t *testing.T) {
+	index := newMemoryIndex()
+	store := newTrustStoreGraph(t, index, 2)
+
+	first := store("a", nodes.TrustLow)
+	if first.Status != nodes.StatusProbation || first.Confirmations != 1 {
+		t.Fatalf("unexpected output: %+v", first)
+	}
+
+	// 同一来源重复写入不计入独立确认
+	if again := store("a", nodes.TrustLow); again.Status != nodes.StatusProbation || again.CacheID != first.CacheID {
+		t.Errorf("unexpected output: %+v", again)
+	}
+
+	// 第二个独立来源确认后条目以确定性 ID 生效
+	second := store("b", nodes.TrustLow)
+	if second.Status != nodes.StatusActive || second.Confirmations != 2 {
+		t.Fatalf("unexpected output: %+v", second)
+	}
+	if first.CacheID != nodes.ProbationEntryID(second.CacheID) {
+		t.Errorf("expected probation entry %v, got %v", nodes.ProbationEntryID(second.CacheID), first.CacheID)
+	}
+	if got := index.status(second.CacheID); got != nodes.StatusActive {
+		t.Errorf("expected %v, got %v", nodes.StatusActive, got)
+	}
+}
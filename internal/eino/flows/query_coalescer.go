@@ -0,0 +1,218 @@
+// Package flows 提供 Eino Graph 流程定义
+package flows
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"golang.org/x/text/unicode/norm"
+
+	"llm-cache/internal/eino/config"
+	"llm-cache/internal/eino/nodes"
+)
+
+// QueryCoalescer 合并相同的并发查询。
+// 键相同的查询在执行期间到达时不再重复执行 Graph，而是等待并共享同一次执行的结果。
+// 执行与发起请求的上下文取消解耦：调用方超时或断开只影响自身，不影响其他等待者。
+type QueryCoalescer struct {
+	runner    compose.Runnable[*CacheQueryInput, *CacheQueryOutput]
+	topK      int
+	threshold float64
+
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+
+	total      atomic.Int64
+	executions atomic.Int64
+	coalesced  atomic.Int64
+}
+
+// coalescedCall 表示一次正在执行的查询
+type coalescedCall struct {
+	done   chan struct{}
+	output *CacheQueryOutput
+	err    error
+}
+
+// coalesceKey 定义参与合并键计算的查询字段
+type coalesceKey struct {
+	Query          string                   `json:"query"`
+	UserType       string                   `json:"user_type"`
+	TopK           int                      `json:"top_k"`
+	ScoreThreshold float64                  `json:"score_threshold"`
+	Messages       []*schema.Message        `json:"messages,omitempty"`
+	Generation     *nodes.GenerationContext `json:"generation,omitempty"`
+	Explain        bool                     `json:"explain,omitempty"`
+	Slots          map[string]string        `json:"slots,omitempty"`
+}
+
+// NewQueryCoalescer 创建查询合并器。
+// 参数 runner: 编译后的 Query Graph。
+// 参数 retrieverCfg: 检索器配置（请求未指定 top_k 和阈值时使用其默认值计算合并键）。
+// 返回: QueryCoalescer 指针。
+func NewQueryCoalescer(runner compose.Runnable[*CacheQueryInput, *CacheQueryOutput], retrieverCfg *config.RetrieverConfig) *QueryCoalescer {
+	return &QueryCoalescer{
+		runner:    runner,
+		topK:      retrieverCfg.TopK,
+		threshold: retrieverCfg.ScoreThreshold,
+		calls:     make(map[string]*coalescedCall),
+	}
+}
+
+// Invoke 执行查询，相同的查询正在执行时等待并共享其结果。
+// 参数 ctx: 上下文对象（仅控制当前调用方的等待）。
+// 参数 input: 查询输入。
+// 返回: 查询结果（每个调用方获得独立的副本）、是否与其他查询合并，以及错误。
+func (c *QueryCoalescer) Invoke(ctx context.Context, input *CacheQueryInput) (*CacheQueryOutput, bool, error) {
+	key, err := c.key(input)
+	if err != nil {
+		return nil, false, err
+	}
+	c.total.Add(1)
+
+	c.mu.Lock()
+	call, shared := c.calls[key]
+	if !shared {
+		call = &coalescedCall{done: make(chan struct{})}
+		c.calls[key] = call
+	}
+	c.mu.Unlock()
+
+	if shared {
+		c.coalesced.Add(1)
+	} else {
+		c.executions.Add(1)
+		go c.execute(context.WithoutCancel(ctx), key, input, call)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, shared, ctx.Err()
+	case <-call.done:
+	}
+	if call.err != nil {
+		return nil, shared, call.err
+	}
+	return cloneQueryOutput(call.output), shared, nil
+}
+
+// GetMetrics 获取查询合并统计。
+// 返回一个包含总调用数、实际执行数、被合并的调用数、合并率和正在执行的查询数的 Map。
+func (c *QueryCoalescer) GetMetrics() map[string]interface{} {
+	total, coalesced := c.total.Load(), c.coalesced.Load()
+	rate := 0.0
+	if total > 0 {
+		rate = float64(coalesced) / float64(total)
+	}
+
+	c.mu.Lock()
+	inFlight := len(c.calls)
+	c.mu.Unlock()
+
+	return map[string]interface{}{
+		"total_calls":   total,
+		"executions":    c.executions.Load(),
+		"coalesced":     coalesced,
+		"coalesce_rate": rate,
+		"in_flight":     inFlight,
+	}
+}
+
+// execute 执行 Graph 并通知所有等待者
+func (c *QueryCoalescer) execute(ctx context.Context, key string, input *CacheQueryInput, call *coalescedCall) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = fmt.Errorf("query graph panic: %v", r)
+		}
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	call.output, call.err = c.runner.Invoke(ctx, input)
+}
+
+// key 计算合并键。
+// 问题仅做轻量规范化（NFKC、合并空白）：守卫节点基于原文判断否定和实体，
+// 停用词、同义词等规范化步骤可能使语义不同的问题得到相同的键。
+func (c *QueryCoalescer) key(input *CacheQueryInput) (string, error) {
+	k := coalesceKey{
+		Query:          strings.Join(strings.Fields(norm.NFKC.String(input.Query)), " "),
+		UserType:       input.UserType,
+		TopK:           c.topK,
+		ScoreThreshold: c.threshold,
+		Messages:       input.Messages,
+		Generation:     input.Generation,
+		Explain:        input.Explain,
+		Slots:          input.Slots,
+	}
+	if input.TopK > 0 {
+		k.TopK = input.TopK
+	}
+	if input.ScoreThreshold > 0 {
+		k.ScoreThreshold = input.ScoreThreshold
+	}
+
+	data, err := json.Marshal(k)
+	if err != nil {
+		return "", fmt.Errorf("marshal coalesce key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cloneQueryOutput 深拷贝查询结果（Metadata、Slots 和 Explain），使各调用方可以独立修改
+func cloneQueryOutput(output *CacheQueryOutput) *CacheQueryOutput {
+	if output == nil {
+		return nil
+	}
+	cloned := *output
+	if output.Metadata != nil {
+		cloned.Metadata = cloneValue(output.Metadata).(map[string]any)
+	}
+	if output.Slots != nil {
+		cloned.Slots = make(map[string]string, len(output.Slots))
+		for k, v := range output.Slots {
+			cloned.Slots[k] = v
+		}
+	}
+	if output.Explain != nil {
+		explain := *output.Explain
+		explain.Candidates = append([]ExplainCandidate(nil), output.Explain.Candidates...)
+		explain.Rejections = append([]CandidateRejection(nil), output.Explain.Rejections...)
+		cloned.Explain = &explain
+	}
+	return &cloned
+}
+
+// cloneValue 递归拷贝元数据中的 map 和切片
+func cloneValue(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		cloned := make(map[string]any, len(value))
+		for k, item := range value {
+			cloned[k] = cloneValue(item)
+		}
+		return cloned
+	case []any:
+		cloned := make([]any, len(value))
+		for i, item := range value {
+			cloned[i] = cloneValue(item)
+		}
+		return cloned
+	case []string:
+		return append([]string(nil), value...)
+	case []float64:
+		return append([]float64(nil), value...)
+	default:
+		return v
+	}
+}
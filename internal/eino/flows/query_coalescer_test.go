@@ -0,0 +1,192 @@
+package flows
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+
+	"llm-cache/internal/eino/config"
+)
+
+// blockingRunner 编译一个在 release 关闭前阻塞的查询 Runnable，并统计执行次数
+func blockingRunner(t *testing.T, release <-chan struct{}, output *CacheQueryOutput, err error) (compose.Runnable[*CacheQueryInput, *CacheQueryOutput], *atomic.Int64) {
+	t.Helper()
+	var executions atomic.Int64
+	runner, compileErr := compose.NewChain[*CacheQueryInput, *CacheQueryOutput]().
+		AppendLambda(compose.InvokableLambda(func(ctx context.Context, input *CacheQueryInput) (*CacheQueryOutput, error) {
+			executions.Add(1)
+			<-release
+			if err != nil {
+				return nil, err
+			}
+			return cloneQueryOutput(output), nil
+		})).
+		Compile(context.Background())
+	if compileErr != nil {
+		t.Fatalf("unexpected error: %v", compileErr)
+	}
+	return runner, &executions
+}
+
+// waitForCalls 等待合并器收到 n 次调用
+func waitForCalls(t *testing.T, c *QueryCoalescer, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if c.total.Load() >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d calls, got %d", n, c.total.Load())
+}
+
+type coalescedResult struct {
+	output *CacheQueryOutput
+	shared bool
+	err    error
+}
+
+// invokeConcurrently 并发发起 n 个相同的查询，返回等待结果的函数
+func invokeConcurrently(ctxs []context.Context, c *QueryCoalescer, input *CacheQueryInput) func() []coalescedResult {
+	results := make([]coalescedResult, len(ctxs))
+	var wg sync.WaitGroup
+	for i, ctx := range ctxs {
+		wg.Add(1)
+		go func(i int, ctx context.Context) {
+			defer wg.Done()
+			output, shared, err := c.Invoke(ctx, input)
+			results[i] = coalescedResult{output, shared, err}
+		}(i, ctx)
+	}
+	return func() []coalescedResult {
+		wg.Wait()
+		return results
+	}
+}
+
+func backgroundContexts(n int) []context.Context {
+	ctxs := make([]context.Context, n)
+	for i := range ctxs {
+		ctxs[i] = context.Background()
+	}
+	return ctxs
+}
+
+func TestQueryCoalescer(t *testing.T) {
+	const n = 8
+	release := make(chan struct{})
+	output := &CacheQueryOutput{
+		Hit:      true,
+		CacheID:  "c1",
+		Answer:   "在设置页面重置",
+		Metadata: map[string]any{"tags": []any{"account"}, "extra": map[string]any{"k": "v"}},
+		Explain:  &QueryExplain{Query: "如何重置密码", Candidates: []ExplainCandidate{{CacheID: "c1", Score: 0.9}}},
+	}
+	runner, executions := blockingRunner(t, release, output, nil)
+	coalescer := NewQueryCoalescer(runner, &config.RetrieverConfig{TopK: 5})
+
+	wait := invokeConcurrently(backgroundContexts(n), coalescer, &CacheQueryInput{Query: "如何重置密码", UserType: "u"})
+	waitForCalls(t, coalescer, n)
+	close(release)
+	results := wait()
+
+	if got := executions.Load(); got != 1 {
+		t.Errorf("expected 1 execution, got %d", got)
+	}
+	var shared int
+	for _, r := range results {
+		if r.err != nil {
+			t.Fatalf("unexpected error: %v", r.err)
+		}
+		if r.shared {
+			shared++
+		}
+		if r.output.CacheID != "c1" {
+			t.Errorf("expected %v, got %v", "c1", r.output.CacheID)
+		}
+	}
+	if shared != n-1 {
+		t.Errorf("expected %d shared results, got %d", n-1, shared)
+	}
+	if metrics := coalescer.GetMetrics(); metrics["executions"] != int64(1) || metrics["coalesced"] != int64(n-1) || metrics["in_flight"] != 0 {
+		t.Errorf("unexpected metrics: %v", metrics)
+	}
+
+	// 每个调用方获得独立的结果副本，并发修改互不影响
+	var wg sync.WaitGroup
+	for i, r := range results {
+		wg.Add(1)
+		go func(i int, out *CacheQueryOutput) {
+			defer wg.Done()
+			out.Metadata["caller"] = i
+			out.Metadata["extra"].(map[string]any)["k"] = i
+			out.Metadata["tags"].([]any)[0] = i
+			out.Explain.Candidates[0].Score = float64(i)
+			out.Explain.Query = "changed"
+		}(i, r.output)
+	}
+	wg.Wait()
+	for i, r := range results {
+		if r.output.Metadata["caller"] != i || r.output.Metadata["extra"].(map[string]any)["k"] != i ||
+			r.output.Metadata["tags"].([]any)[0] != i || r.output.Explain.Candidates[0].Score != float64(i) {
+			t.Errorf("result %d: expected independent copy, got %+v", i, r.output.Metadata)
+		}
+	}
+}
+
+func TestQueryCoalescerError(t *testing.T) {
+	const n = 4
+	release := make(chan struct{})
+	errBackend := errors.New("retriever unavailable")
+	runner, executions := blockingRunner(t, release, nil, errBackend)
+	coalescer := NewQueryCoalescer(runner, &config.RetrieverConfig{})
+
+	wait := invokeConcurrently(backgroundContexts(n), coalescer, &CacheQueryInput{Query: "q", UserType: "u"})
+	waitForCalls(t, coalescer, n)
+	close(release)
+
+	for i, r := range wait() {
+		if !errors.Is(r.err, errBackend) {
+			t.Errorf("caller %d: expected %v, got %v", i, errBackend, r.err)
+		}
+	}
+	if got := executions.Load(); got != 1 {
+		t.Errorf("expected 1 execution, got %d", got)
+	}
+}
+
+func TestQueryCoalescerCancel(t *testing.T) {
+	release := make(chan struct{})
+	runner, executions := blockingRunner(t, release, &CacheQueryOutput{Hit: true, CacheID: "c1"}, nil)
+	coalescer := NewQueryCoalescer(runner, &config.RetrieverConfig{})
+
+	// 发起执行的调用方取消后，执行继续并将结果交给其他等待者
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	ctxs := []context.Context{cancelCtx, context.Background(), context.Background()}
+	input := &CacheQueryInput{Query: "q", UserType: "u"}
+	cancelled := invokeConcurrently(ctxs[:1], coalescer, input)
+	waitForCalls(t, coalescer, 1)
+	waiting := invokeConcurrently(ctxs[1:], coalescer, input)
+	waitForCalls(t, coalescer, 3)
+
+	cancel()
+	if r := cancelled()[0]; !errors.Is(r.err, context.Canceled) || r.shared {
+		t.Errorf("expected cancelled leader, got %+v", r)
+	}
+
+	close(release)
+	for i, r := range waiting() {
+		if r.err != nil || !r.shared || r.output.CacheID != "c1" {
+			t.Errorf("waiter %d: unexpected result %+v", i, r)
+		}
+	}
+	if got := executions.Load(); got != 1 {
+		t.Errorf("expected 1 execution, got %d", got)
+	}
+}
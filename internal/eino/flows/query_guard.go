@@ -0,0 +1,105 @@
+// Package flows 提供 Eino Graph 流程定义
+package flows
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/nodes"
+)
+
+// QueryExplain 定义查询的解释信息（请求 explain 时返回）。
+// 包含检索到的候选以及各守卫节点拒绝候选的原因。
+type QueryExplain struct {
+	Query      string               `json:"query"`
+	Candidates []ExplainCandidate   `json:"candidates"`
+	Rejections []CandidateRejection `json:"rejections,omitempty"`
+}
+
+// ExplainCandidate 定义检索阶段返回的候选缓存。
+type ExplainCandidate struct {
+	CacheID  string  `json:"cache_id"`
+	Question string  `json:"question,omitempty"`
+	Score    float64 `json:"score"`
+}
+
+// CandidateRejection 定义候选缓存被守卫节点拒绝的记录。
+type CandidateRejection struct {
+	CacheID  string  `json:"cache_id"`
+	Question string  `json:"question,omitempty"`
+	Score    float64 `json:"score"`
+	Stage    string  `json:"stage"`
+	Reason   string  `json:"reason"`
+}
+
+// candidateFilter 判断候选文档是否通过，不通过时返回原因
+type candidateFilter func(doc *schema.Document) (bool, string)
+
+// newGuardNode 创建候选过滤（守卫）节点。
+// prepare 基于本地状态快照构建过滤函数（在状态锁之外执行，可调用外部服务），
+// 被拒绝的候选及原因记录到本地状态，供 explain 输出。
+// 参数 stage: 守卫节点名称（记录在拒绝原因中）。
+// 参数 prepare: 构建过滤函数，返回 nil 表示本次请求跳过该守卫。
+// 返回: Lambda 节点。
+func newGuardNode(stage string, prepare func(ctx context.Context, state queryState) (candidateFilter, error)) *compose.Lambda {
+	return compose.InvokableLambda(func(ctx context.Context, docs []*schema.Document) ([]*schema.Document, error) {
+		var snapshot queryState
+		err := compose.ProcessState(ctx, func(_ context.Context, state *queryState) error {
+			snapshot = *state
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		filter, err := prepare(ctx, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		if filter == nil {
+			return docs, nil
+		}
+
+		result := make([]*schema.Document, 0, len(docs))
+		var rejections []CandidateRejection
+		for _, doc := range docs {
+			if ok, reason := filter(doc); !ok {
+				rejections = append(rejections, CandidateRejection{
+					CacheID:  doc.ID,
+					Question: nodes.ExtractQuestion(doc),
+					Score:    nodes.ExtractScore(doc),
+					Stage:    stage,
+					Reason:   reason,
+				})
+				continue
+			}
+			result = append(result, doc)
+		}
+
+		if len(rejections) > 0 {
+			err = compose.ProcessState(ctx, func(_ context.Context, state *queryState) error {
+				state.Rejections = append(state.Rejections, rejections...)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	})
+}
+
+// buildExplainCandidates 将检索结果转换为 explain 候选列表
+func buildExplainCandidates(docs []*schema.Document) []ExplainCandidate {
+	candidates := make([]ExplainCandidate, len(docs))
+	for i, doc := range docs {
+		candidates[i] = ExplainCandidate{
+			CacheID:  doc.ID,
+			Question: nodes.ExtractQuestion(doc),
+			Score:    nodes.ExtractScore(doc),
+		}
+	}
+	return candidates
+}
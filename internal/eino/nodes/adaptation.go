@@ -0,0 +1,72 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// defaultAdaptationPrompt 默认的答案改写系统提示词
+const defaultAdaptationPrompt = "你是一个答案改写助手。给定一个已缓存的问题及其答案，以及一个相近的新问题，" +
+	"请基于缓存答案中的事实改写出回答新问题的答案。不要引入缓存答案中没有的事实，" +
+	"保持原答案的语言和风格，只输出改写后的答案。"
+
+// AnswerAdapter 实现近似命中时的答案改写。
+// 使用 ChatModel 将缓存的答案改写为适配新问题的答案。
+type AnswerAdapter struct {
+	model model.BaseChatModel
+	cfg   *config.AdaptationConfig
+}
+
+// NewAnswerAdapter 创建一个新的答案改写器。
+// 参数 chatModel: 用于改写的 ChatModel。
+// 参数 cfg: 答案改写配置。
+// 返回: AnswerAdapter 指针。
+func NewAnswerAdapter(chatModel model.BaseChatModel, cfg *config.AdaptationConfig) *AnswerAdapter {
+	return &AnswerAdapter{model: chatModel, cfg: cfg}
+}
+
+// Threshold 返回改写阈值（近似命中区间的下界）
+func (a *AnswerAdapter) Threshold() float64 {
+	return a.cfg.Threshold
+}
+
+// Adapt 将缓存答案改写为回答新问题的答案。
+// 参数 ctx: 上下文对象。
+// 参数 cachedQuestion: 缓存的问题。
+// 参数 cachedAnswer: 缓存的答案。
+// 参数 question: 新问题。
+// 返回: 改写后的答案，调用失败或返回为空时返回错误。
+func (a *AnswerAdapter) Adapt(ctx context.Context, cachedQuestion, cachedAnswer, question string) (string, error) {
+	if a.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(a.cfg.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	systemPrompt := a.cfg.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = defaultAdaptationPrompt
+	}
+	messages := []*schema.Message{
+		schema.SystemMessage(systemPrompt),
+		schema.UserMessage(fmt.Sprintf("缓存的问题：%s\n缓存的答案：%s\n新问题：%s", cachedQuestion, cachedAnswer, question)),
+	}
+
+	resp, err := a.model.Generate(ctx, messages)
+	if err != nil {
+		return "", fmt.Errorf("generate adapted answer: %w", err)
+	}
+	answer := strings.TrimSpace(resp.Content)
+	if answer == "" {
+		return "", fmt.Errorf("empty adapted answer")
+	}
+	return answer, nil
+}
@@ -0,0 +1,68 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// fakeChatModel 记录输入并返回固定回复的 ChatModel
+type fakeChatModel struct {
+	reply string
+	err   error
+	input []*schema.Message
+}
+
+func (m *fakeChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.input = input
+	if m.err != nil {
+		return nil, m.err
+	}
+	return schema.AssistantMessage(m.reply, nil), nil
+}
+
+func (m *fakeChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestAnswerAdapterAdapt(t *testing.T) {
+	tests := []struct {
+		name     string
+		reply    string
+		err      error
+		expected string
+		wantErr  bool
+	}{
+		{"adapted", "  The store opens at 9am on Sunday. ", nil, "The store opens at 9am on Sunday.", false},
+		{"model error", "", errors.New("boom"), "", true},
+		{"empty reply", "   ", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatModel := &fakeChatModel{reply: tt.reply, err: tt.err}
+			adapter := NewAnswerAdapter(chatModel, &config.AdaptationConfig{Threshold: 0.6, Timeout: 5})
+
+			answer, err := adapter.Adapt(context.Background(), "when does the store open on saturday", "9am on Saturday.", "when does the store open on sunday")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if answer != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, answer)
+			}
+
+			if len(chatModel.input) != 2 || chatModel.input[0].Role != schema.System {
+				t.Fatalf("expected system and user messages, got %v", chatModel.input)
+			}
+			if !strings.Contains(chatModel.input[1].Content, "when does the store open on sunday") {
+				t.Errorf("expected prompt to contain new question, got %q", chatModel.input[1].Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/components"
+	"llm-cache/internal/eino/config"
+)
+
+// 上下文相关的元数据键
+const (
+	// MetaContextHash 历史消息的上下文指纹（无历史时为空字符串）
+	MetaContextHash = "context_hash"
+	// MetaContextTurns 参与指纹计算的历史消息数
+	MetaContextTurns = "context_turns"
+	// MetaContextVector 历史消息的上下文向量（仅 semantic 兼容规则需要）
+	MetaContextVector = "context_vector"
+)
+
+// 上下文兼容规则
+const (
+	ContextMatchExact    = "exact"
+	ContextMatchSemantic = "semantic"
+	ContextMatchIgnore   = "ignore"
+)
+
+// ConversationContext 定义多轮对话的上下文信息。
+// Question 为最后一轮用户消息，History 为其之前参与指纹计算的历史消息。
+type ConversationContext struct {
+	Question string
+	History  []*schema.Message
+	Hash     string
+	Text     string
+}
+
+// BuildConversationContext 从问题和历史消息构建对话上下文。
+// 如果问题为空，则取最后一条用户消息作为问题；如果最后一条消息与问题相同，则不计入历史。
+// 参数 question: 当前问题（可为空）。
+// 参数 messages: 对话消息列表（可为空）。
+// 参数 maxTurns: 参与指纹计算的最近历史消息数（<= 0 表示全部）。
+// 返回: 对话上下文。
+func BuildConversationContext(question string, messages []*schema.Message, maxTurns int) *ConversationContext {
+	history := make([]*schema.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg != nil && strings.TrimSpace(msg.Content) != "" {
+			history = append(history, msg)
+		}
+	}
+
+	// 最后一轮用户消息作为问题
+	if n := len(history); n > 0 && history[n-1].Role == schema.User {
+		last := strings.TrimSpace(history[n-1].Content)
+		if question == "" || strings.TrimSpace(question) == last {
+			question = history[n-1].Content
+			history = history[:n-1]
+		}
+	}
+
+	if maxTurns > 0 && len(history) > maxTurns {
+		history = history[len(history)-maxTurns:]
+	}
+
+	cc := &ConversationContext{
+		Question: question,
+		History:  history,
+	}
+	if len(history) == 0 {
+		return cc
+	}
+
+	// 规范化历史消息文本并计算指纹
+	lines := make([]string, len(history))
+	for i, msg := range history {
+		lines[i] = string(msg.Role) + ": " + normalizeWhitespace(strings.ToLower(msg.Content))
+	}
+	cc.Text = strings.Join(lines, "\n")
+	cc.Hash = hashText(cc.Text)
+
+	return cc
+}
+
+// Metadata 返回需要写入缓存文档的上下文元数据
+func (cc *ConversationContext) Metadata() map[string]any {
+	return map[string]any{
+		MetaContextHash:  cc.Hash,
+		MetaContextTurns: len(cc.History),
+	}
+}
+
+// ContextMatcher 实现对话上下文兼容性检查。
+// 根据 user_type 选择兼容规则，过滤上下文不兼容的候选文档。
+type ContextMatcher struct {
+	cfg *config.ConversationConfig
+}
+
+// NewContextMatcher 创建一个新的上下文兼容性检查器。
+// 参数 cfg: 多轮对话配置（为 nil 时使用 exact 规则）。
+// 返回: ContextMatcher 指针。
+func NewContextMatcher(cfg *config.ConversationConfig) *ContextMatcher {
+	if cfg == nil {
+		cfg = &config.ConversationConfig{}
+	}
+	return &ContextMatcher{cfg: cfg}
+}
+
+// MaxTurns 返回参与指纹计算的最近历史消息数
+func (m *ContextMatcher) MaxTurns() int {
+	return m.cfg.MaxTurns
+}
+
+// Policy 返回指定 user_type 的上下文兼容规则
+func (m *ContextMatcher) Policy(userType string) config.ConversationPolicy {
+	policy, ok := m.cfg.UserTypes[userType]
+	if !ok {
+		policy = m.cfg.Default
+	}
+	if policy.Match == "" {
+		policy.Match = ContextMatchExact
+	}
+	if policy.MinSimilarity <= 0 {
+		policy.MinSimilarity = 0.85
+	}
+	return policy
+}
+
+// NeedsVector 判断指定 user_type 是否需要上下文向量（semantic 规则）
+func (m *ContextMatcher) NeedsVector(userType string) bool {
+	return m.Policy(userType).Match == ContextMatchSemantic
+}
+
+// Compatible 判断候选文档的上下文是否与当前对话上下文兼容。
+// 参数 doc: 候选文档。
+// 参数 userType: 用户类型。
+// 参数 cc: 当前对话上下文。
+// 参数 vector: 当前上下文向量（仅 semantic 规则使用）。
+// 返回: 是否兼容。
+func (m *ContextMatcher) Compatible(doc *schema.Document, userType string, cc *ConversationContext, vector []float64) bool {
+	policy := m.Policy(userType)
+	docHash, _ := doc.MetaData[MetaContextHash].(string)
+
+	switch policy.Match {
+	case ContextMatchIgnore:
+		return true
+
+	case ContextMatchSemantic:
+		// 任一方无历史时退化为精确匹配
+		if cc.Hash == "" || docHash == "" {
+			return cc.Hash == docHash
+		}
+		if cc.Hash == docHash {
+			return true
+		}
+		docVector := toFloatSlice(doc.MetaData[MetaContextVector])
+		return components.CosineSimilarity(vector, docVector) >= policy.MinSimilarity
+
+	default:
+		return cc.Hash == docHash
+	}
+}
+
+// toFloatSlice 将元数据中的向量转换为 []float64（兼容 JSON 反序列化后的 []any）
+func toFloatSlice(v any) []float64 {
+	switch vec := v.(type) {
+	case []float64:
+		return vec
+	case []float32:
+		result := make([]float64, len(vec))
+		for i, f := range vec {
+			result[i] = float64(f)
+		}
+		return result
+	case []any:
+		result := make([]float64, 0, len(vec))
+		for _, item := range vec {
+			if f, ok := item.(float64); ok {
+				result = append(result, f)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestBuildConversationContext(t *testing.T) {
+	history := []*schema.Message{
+		schema.UserMessage("推荐两款手机"),
+		schema.AssistantMessage("iPhone 15 和 Pixel 8", nil),
+	}
+
+	t.Run("no history", func(t *testing.T) {
+		cc := BuildConversationContext("如何重置密码", nil, 6)
+		if cc.Hash != "" || cc.Question != "如何重置密码" {
+			t.Errorf("expected empty hash and original question, got %q %q", cc.Hash, cc.Question)
+		}
+	})
+
+	t.Run("question from last user turn", func(t *testing.T) {
+		messages := append(append([]*schema.Message{}, history...), schema.UserMessage("第二款怎么样"))
+		cc := BuildConversationContext("", messages, 6)
+		if cc.Question != "第二款怎么样" {
+			t.Errorf("expected last user turn as question, got %q", cc.Question)
+		}
+		if len(cc.History) != 2 || cc.Hash == "" {
+			t.Errorf("expected 2 history turns with hash, got %d %q", len(cc.History), cc.Hash)
+		}
+		if explicit := BuildConversationContext("第二款怎么样", history, 6); explicit.Hash != cc.Hash {
+			t.Errorf("expected same hash with explicit question")
+		}
+	})
+
+	t.Run("normalized fingerprint", func(t *testing.T) {
+		a := BuildConversationContext("q", []*schema.Message{schema.UserMessage("Hello   World")}, 6)
+		b := BuildConversationContext("q", []*schema.Message{schema.UserMessage("hello world")}, 6)
+		if a.Hash != b.Hash {
+			t.Errorf("expected case and whitespace insensitive hash")
+		}
+	})
+
+	t.Run("max turns window", func(t *testing.T) {
+		messages := append([]*schema.Message{schema.UserMessage("很早之前的话题")}, history...)
+		a := BuildConversationContext("第二款怎么样", messages, 2)
+		b := BuildConversationContext("第二款怎么样", history, 2)
+		if a.Hash != b.Hash {
+			t.Errorf("expected turns outside window to be ignored")
+		}
+	})
+}
+
+func TestContextMatcher_Compatible(t *testing.T) {
+	matcher := NewContextMatcher(&config.ConversationConfig{
+		Default: config.ConversationPolicy{Match: "exact"},
+		UserTypes: map[string]config.ConversationPolicy{
+			"faq":  {Match: "ignore"},
+			"chat": {Match: "semantic", MinSimilarity: 0.9},
+		},
+	})
+
+	standalone := BuildConversationContext("第二款怎么样", nil, 0)
+	followUp := BuildConversationContext("第二款怎么样", []*schema.Message{schema.UserMessage("推荐两款手机")}, 0)
+
+	plainDoc := &schema.Document{MetaData: map[string]any{}}
+	contextDoc := &schema.Document{MetaData: map[string]any{
+		MetaContextHash:   "other-hash",
+		MetaContextVector: []any{1.0, 0.0},
+	}}
+
+	tests := []struct {
+		name     string
+		doc      *schema.Document
+		userType string
+		cc       *ConversationContext
+		vector   []float64
+		want     bool
+	}{
+		{name: "exact both standalone", doc: plainDoc, userType: "default", cc: standalone, want: true},
+		{name: "exact follow-up vs standalone doc", doc: plainDoc, userType: "default", cc: followUp, want: false},
+		{name: "exact different context", doc: contextDoc, userType: "default", cc: followUp, want: false},
+		{name: "ignore", doc: contextDoc, userType: "faq", cc: standalone, want: true},
+		{name: "semantic similar", doc: contextDoc, userType: "chat", cc: followUp, vector: []float64{0.99, 0.1}, want: true},
+		{name: "semantic dissimilar", doc: contextDoc, userType: "chat", cc: followUp, vector: []float64{0.1, 0.99}, want: false},
+		{name: "semantic standalone vs context doc", doc: contextDoc, userType: "chat", cc: standalone, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matcher.Compatible(tt.doc, tt.userType, tt.cc, tt.vector); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
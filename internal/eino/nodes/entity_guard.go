@@ -0,0 +1,215 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"llm-cache/internal/eino/config"
+)
+
+// 内置实体类别（按提取顺序排列，先提取的片段会被遮盖，避免被后续类别重复提取）
+const (
+	EntityCurrency = "currency"
+	EntityDate     = "date"
+	EntityVersion  = "version"
+	EntityNumber   = "number"
+)
+
+var (
+	// 金额：符号前缀（$100、¥1,200.50、$3k）
+	currencyPrefixPattern = regexp.MustCompile(`([$€£¥￥])\s?(\d[\d,]*(?:\.\d+)?)\s*([kKmM万亿])?`)
+	// 金额：单位后缀（100元、3万元、20 USD、5 dollars）
+	currencySuffixPattern = regexp.MustCompile(`(?i)(\d[\d,]*(?:\.\d+)?)\s*(万|亿)?\s*(美元|欧元|英镑|人民币|元|块|(?:usd|eur|gbp|cny|rmb|dollars?|euros?)\b)`)
+
+	// 日期：2024-03-05、2024/3、2024年3月5日
+	fullDatePattern = regexp.MustCompile(`(\d{4})[-/.年](\d{1,2})(?:[-/.月](\d{1,2})[日号]?)?`)
+	// 日期：3月5日
+	monthDayPattern = regexp.MustCompile(`(\d{1,2})月(\d{1,2})[日号]`)
+	// 年份：2024、2024年
+	yearPattern = regexp.MustCompile(`\b((?:19|20)\d{2})\b|(\d{4})年`)
+
+	// 版本号：v2、v1.2、1.2.3
+	versionPrefixPattern = regexp.MustCompile(`(?i)\bv(\d+(?:\.\d+)*)\b`)
+	versionDottedPattern = regexp.MustCompile(`\b(\d+\.\d+\.\d+(?:\.\d+)?)\b`)
+
+	// 数字：1,000、3.14、42
+	numberPattern = regexp.MustCompile(`\d[\d,]*(?:\.\d+)?`)
+)
+
+// currencyCodes 货币符号/单位到货币代码的映射
+var currencyCodes = map[string]string{
+	"$": "USD", "usd": "USD", "dollar": "USD", "dollars": "USD", "美元": "USD",
+	"€": "EUR", "eur": "EUR", "euro": "EUR", "euros": "EUR", "欧元": "EUR",
+	"£": "GBP", "gbp": "GBP", "英镑": "GBP",
+	"¥": "CNY", "￥": "CNY", "cny": "CNY", "rmb": "CNY", "人民币": "CNY", "元": "CNY", "块": "CNY",
+}
+
+// amountMultipliers 金额数量级单位
+var amountMultipliers = map[string]float64{
+	"k": 1e3, "K": 1e3, "m": 1e6, "M": 1e6, "万": 1e4, "亿": 1e8,
+}
+
+// Entities 定义从文本中提取的实体（类别 -> 规范化后的取值集合，已排序去重）。
+type Entities map[string][]string
+
+// entityPattern 定义编译后的自定义实体模式
+type entityPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// EntityGuard 实现实体与数字冲突守卫。
+// 从查询和候选问题中提取数字、日期、版本号、金额及自定义实体，取值冲突时拒绝候选。
+type EntityGuard struct {
+	strict   bool
+	patterns []entityPattern
+}
+
+// NewEntityGuard 创建一个新的实体冲突守卫。
+// 参数 cfg: 实体守卫配置。
+// 返回: EntityGuard 指针，自定义模式无法编译时返回错误。
+func NewEntityGuard(cfg *config.EntityGuardConfig) (*EntityGuard, error) {
+	guard := &EntityGuard{strict: cfg.StrictPresence}
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compile entity pattern %s: %w", p.Name, err)
+		}
+		guard.patterns = append(guard.patterns, entityPattern{name: p.Name, re: re})
+	}
+	return guard, nil
+}
+
+// Extract 从文本中提取实体。
+// 提取顺序：自定义实体、金额、日期、版本号、数字；已提取的片段会被遮盖。
+// 参数 text: 待提取的文本。
+// 返回: 提取到的实体。
+func (g *EntityGuard) Extract(text string) Entities {
+	entities := make(Entities)
+	add := func(category, value string) {
+		if value != "" && !slices.Contains(entities[category], value) {
+			entities[category] = append(entities[category], value)
+		}
+	}
+
+	// 1. 自定义实体
+	for _, p := range g.patterns {
+		text = maskMatches(p.re, text, func(m []string) {
+			add(p.name, strings.ToLower(m[0]))
+		})
+	}
+
+	// 2. 金额
+	text = maskMatches(currencyPrefixPattern, text, func(m []string) {
+		add(EntityCurrency, formatAmount(currencyCodes[m[1]], m[2], m[3]))
+	})
+	text = maskMatches(currencySuffixPattern, text, func(m []string) {
+		add(EntityCurrency, formatAmount(currencyCodes[strings.ToLower(m[3])], m[1], m[2]))
+	})
+
+	// 3. 日期
+	text = maskMatches(fullDatePattern, text, func(m []string) {
+		date := m[1] + "-" + padTwo(m[2])
+		if m[3] != "" {
+			date += "-" + padTwo(m[3])
+		}
+		add(EntityDate, date)
+	})
+	text = maskMatches(monthDayPattern, text, func(m []string) {
+		add(EntityDate, "--"+padTwo(m[1])+"-"+padTwo(m[2]))
+	})
+	text = maskMatches(yearPattern, text, func(m []string) {
+		add(EntityDate, m[1]+m[2])
+	})
+
+	// 4. 版本号
+	text = maskMatches(versionPrefixPattern, text, func(m []string) {
+		add(EntityVersion, m[1])
+	})
+	text = maskMatches(versionDottedPattern, text, func(m []string) {
+		add(EntityVersion, m[1])
+	})
+
+	// 5. 其余数字
+	maskMatches(numberPattern, text, func(m []string) {
+		add(EntityNumber, normalizeNumber(m[0]))
+	})
+
+	for category := range entities {
+		slices.Sort(entities[category])
+	}
+	return entities
+}
+
+// Check 检查候选问题的实体是否与查询实体冲突。
+// 参数 query: 查询中提取的实体。
+// 参数 candidate: 候选问题文本。
+// 返回: 是否通过，以及冲突时的原因。
+func (g *EntityGuard) Check(query Entities, candidate string) (bool, string) {
+	cached := g.Extract(candidate)
+
+	categories := make([]string, 0, len(query)+len(cached))
+	for category := range query {
+		categories = append(categories, category)
+	}
+	for category := range cached {
+		if _, ok := query[category]; !ok {
+			categories = append(categories, category)
+		}
+	}
+	slices.Sort(categories)
+
+	for _, category := range categories {
+		q, c := query[category], cached[category]
+		if slices.Equal(q, c) {
+			continue
+		}
+		if (len(q) == 0 || len(c) == 0) && !g.strict {
+			continue
+		}
+		return false, fmt.Sprintf("%s mismatch: query %v vs cached %v", category, q, c)
+	}
+	return true, ""
+}
+
+// maskMatches 对正则的每个匹配调用 fn，并用空格遮盖匹配片段
+func maskMatches(re *regexp.Regexp, text string, fn func(m []string)) string {
+	return re.ReplaceAllStringFunc(text, func(match string) string {
+		fn(re.FindStringSubmatch(match))
+		return strings.Repeat(" ", len(match))
+	})
+}
+
+// formatAmount 将金额规范化为 "货币代码 数值" 形式
+func formatAmount(code, amount, unit string) string {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(amount, ",", ""), 64)
+	if err != nil {
+		return ""
+	}
+	if multiplier, ok := amountMultipliers[unit]; ok {
+		value *= multiplier
+	}
+	return strings.TrimSpace(code + " " + strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// normalizeNumber 规范化数字（去除千分位分隔符和多余的零）
+func normalizeNumber(s string) string {
+	s = strings.Trim(strings.ReplaceAll(s, ",", ""), ".")
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// padTwo 将一位数补齐为两位
+func padTwo(s string) string {
+	if len(s) == 1 {
+		return "0" + s
+	}
+	return s
+}
@@ -0,0 +1,82 @@
+package nodes
+
+import (
+	"reflect"
+	"testing"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestEntityGuard_Extract(t *testing.T) {
+	guard, err := NewEntityGuard(&config.EntityGuardConfig{
+		Patterns: []config.EntityPattern{{Name: "sku", Pattern: `SKU-\d+`}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want Entities
+	}{
+		{text: "What is the 2023 tax rate?", want: Entities{EntityDate: {"2023"}}},
+		{text: "2024年3月5日的汇率", want: Entities{EntityDate: {"2024-03-05"}}},
+		{text: "upgrade from v1.2 to 2.0.1", want: Entities{EntityVersion: {"1.2", "2.0.1"}}},
+		{text: "退款 1,200 元需要多久", want: Entities{EntityCurrency: {"CNY 1200"}}},
+		{text: "Is $3k enough for 5 nights?", want: Entities{EntityCurrency: {"USD 3000"}, EntityNumber: {"5"}}},
+		{text: "Where is SKU-123?", want: Entities{"sku": {"sku-123"}}},
+		{text: "how do I reset my password", want: Entities{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := guard.Extract(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEntityGuard_Check(t *testing.T) {
+	lenient, _ := NewEntityGuard(&config.EntityGuardConfig{})
+	strict, _ := NewEntityGuard(&config.EntityGuardConfig{StrictPresence: true})
+
+	tests := []struct {
+		name      string
+		guard     *EntityGuard
+		query     string
+		candidate string
+		want      bool
+	}{
+		{name: "different year", guard: lenient, query: "what is the 2024 tax rate", candidate: "what is the 2023 tax rate", want: false},
+		{name: "same year", guard: lenient, query: "2024 tax rate?", candidate: "What's the tax rate in 2024", want: true},
+		{name: "different version", guard: lenient, query: "install go 1.21.0", candidate: "install go 1.20.3", want: false},
+		{name: "different amount", guard: lenient, query: "can I refund $50", candidate: "can I refund $500", want: false},
+		{name: "same amount different format", guard: lenient, query: "是否支持 1万元 以上转账", candidate: "是否支持 10000元 以上转账", want: true},
+		{name: "missing entity lenient", guard: lenient, query: "2024 tax rate", candidate: "tax rate", want: true},
+		{name: "missing entity strict", guard: strict, query: "2024 tax rate", candidate: "tax rate", want: false},
+		{name: "no entities", guard: strict, query: "reset password", candidate: "how to reset password", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := tt.guard.Check(tt.guard.Extract(tt.query), tt.candidate)
+			if got != tt.want {
+				t.Errorf("expected %v, got %v (reason: %s)", tt.want, got, reason)
+			}
+			if !got && reason == "" {
+				t.Errorf("expected rejection reason")
+			}
+		})
+	}
+}
+
+func TestNewEntityGuard_InvalidPattern(t *testing.T) {
+	_, err := NewEntityGuard(&config.EntityGuardConfig{
+		Patterns: []config.EntityPattern{{Name: "bad", Pattern: `(`}},
+	})
+	if err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,226 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// 生成上下文相关的元数据键
+const (
+	MetaGenModel             = "gen_model"
+	MetaGenSystemPromptHash  = "gen_system_prompt_hash"
+	MetaGenTemperatureBucket = "gen_temperature_bucket"
+	MetaGenToolSchemaHash    = "gen_tool_schema_hash"
+)
+
+// 生成上下文匹配规则
+const (
+	GenerationMatchExact      = "exact"
+	GenerationMatchCompatible = "compatible"
+	GenerationMatchIgnore     = "ignore"
+)
+
+// GenerationContext 定义产生答案时使用的生成参数。
+// 系统提示词和工具定义既可直接传入原文（由服务端计算哈希），也可传入客户端计算好的哈希。
+type GenerationContext struct {
+	Model            string          `json:"model,omitempty"`
+	SystemPrompt     string          `json:"system_prompt,omitempty"`
+	SystemPromptHash string          `json:"system_prompt_hash,omitempty"`
+	Temperature      *float64        `json:"temperature,omitempty"`
+	Tools            json.RawMessage `json:"tools,omitempty"`
+	ToolSchemaHash   string          `json:"tool_schema_hash,omitempty"`
+}
+
+// GenerationFingerprint 定义用于匹配的生成上下文指纹。
+// TemperatureBucket 为 -1 表示未指定温度。
+type GenerationFingerprint struct {
+	Model             string
+	SystemPromptHash  string
+	TemperatureBucket int
+	ToolSchemaHash    string
+}
+
+// GenerationMatcher 实现生成上下文指纹计算和匹配。
+type GenerationMatcher struct {
+	cfg *config.GenerationConfig
+}
+
+// NewGenerationMatcher 创建一个新的生成上下文匹配器。
+// 参数 cfg: 生成上下文配置（为 nil 时使用 exact 规则）。
+// 返回: GenerationMatcher 指针。
+func NewGenerationMatcher(cfg *config.GenerationConfig) *GenerationMatcher {
+	if cfg == nil {
+		cfg = &config.GenerationConfig{}
+	}
+	return &GenerationMatcher{cfg: cfg}
+}
+
+// Fingerprint 计算生成上下文指纹。
+// 参数 gc: 生成上下文（可为 nil）。
+// 返回: 生成上下文指纹。
+func (m *GenerationMatcher) Fingerprint(gc *GenerationContext) GenerationFingerprint {
+	fp := GenerationFingerprint{TemperatureBucket: -1}
+	if gc == nil {
+		return fp
+	}
+
+	fp.Model = strings.ToLower(strings.TrimSpace(gc.Model))
+
+	fp.SystemPromptHash = gc.SystemPromptHash
+	if gc.SystemPrompt != "" {
+		fp.SystemPromptHash = hashText(normalizeWhitespace(gc.SystemPrompt))
+	}
+
+	if gc.Temperature != nil {
+		fp.TemperatureBucket = m.temperatureBucket(*gc.Temperature)
+	}
+
+	fp.ToolSchemaHash = gc.ToolSchemaHash
+	if len(gc.Tools) > 0 {
+		fp.ToolSchemaHash = hashText(canonicalJSON(gc.Tools))
+	}
+
+	return fp
+}
+
+// Metadata 返回需要写入缓存文档的生成上下文元数据
+func (m *GenerationMatcher) Metadata(fp GenerationFingerprint) map[string]any {
+	return map[string]any{
+		MetaGenModel:             fp.Model,
+		MetaGenSystemPromptHash:  fp.SystemPromptHash,
+		MetaGenTemperatureBucket: fp.TemperatureBucket,
+		MetaGenToolSchemaHash:    fp.ToolSchemaHash,
+	}
+}
+
+// Compatible 判断候选文档的生成上下文是否与当前请求匹配。
+// 参数 doc: 候选文档。
+// 参数 fp: 当前请求的生成上下文指纹。
+// 返回: 是否匹配，以及不匹配时的字段名。
+func (m *GenerationMatcher) Compatible(doc *schema.Document, fp GenerationFingerprint) (bool, string) {
+	if m.cfg.Match == GenerationMatchIgnore {
+		return true, ""
+	}
+
+	docModel, _ := doc.MetaData[MetaGenModel].(string)
+	docPrompt, _ := doc.MetaData[MetaGenSystemPromptHash].(string)
+	docTools, _ := doc.MetaData[MetaGenToolSchemaHash].(string)
+	docBucket := metadataInt(doc.MetaData[MetaGenTemperatureBucket], -1)
+
+	compatible := m.cfg.Match == GenerationMatchCompatible
+
+	if !(compatible && m.ignored("model")) && !m.modelCompatible(docModel, fp.Model, compatible) {
+		return false, "model"
+	}
+	if !(compatible && m.ignored("system_prompt")) && docPrompt != fp.SystemPromptHash {
+		return false, "system_prompt"
+	}
+	if !(compatible && m.ignored("temperature")) && !m.temperatureCompatible(docBucket, fp.TemperatureBucket, compatible) {
+		return false, "temperature"
+	}
+	if !(compatible && m.ignored("tools")) && docTools != fp.ToolSchemaHash {
+		return false, "tools"
+	}
+	return true, ""
+}
+
+// temperatureBucket 计算温度所在的桶序号
+func (m *GenerationMatcher) temperatureBucket(temperature float64) int {
+	for i, upper := range m.cfg.TemperatureBuckets {
+		if temperature < upper {
+			return i
+		}
+	}
+	return len(m.cfg.TemperatureBuckets)
+}
+
+// ignored 判断字段是否在 compatible 规则下被忽略
+func (m *GenerationMatcher) ignored(field string) bool {
+	return slices.Contains(m.cfg.IgnoreFields, field)
+}
+
+// modelCompatible 判断模型是否匹配（compatible 规则下同一模型组视为匹配）
+func (m *GenerationMatcher) modelCompatible(a, b string, compatible bool) bool {
+	if a == b {
+		return true
+	}
+	if !compatible {
+		return false
+	}
+	for _, group := range m.cfg.ModelGroups {
+		inA, inB := false, false
+		for _, model := range group {
+			model = strings.ToLower(model)
+			inA = inA || model == a
+			inB = inB || model == b
+		}
+		if inA && inB {
+			return true
+		}
+	}
+	return false
+}
+
+// temperatureCompatible 判断温度桶是否匹配（compatible 规则下允许一定的桶距离）
+func (m *GenerationMatcher) temperatureCompatible(a, b int, compatible bool) bool {
+	if a == b {
+		return true
+	}
+	if !compatible || a < 0 || b < 0 {
+		return false
+	}
+	distance := a - b
+	if distance < 0 {
+		distance = -distance
+	}
+	return distance <= m.cfg.MaxTemperatureBucketDistance
+}
+
+// hashText 计算文本的 SHA-256 十六进制摘要
+func hashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalJSON 将 JSON 重新序列化为键有序的规范形式，解析失败时返回原文
+func canonicalJSON(raw json.RawMessage) string {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return string(raw)
+	}
+	return string(b)
+}
+
+// metadataInt 将元数据中的数值转换为 int（兼容 JSON 反序列化后的 float64 和字符串）
+func metadataInt(v any, fallback int) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return int(i)
+		}
+	case string:
+		if i, err := strconv.Atoi(n); err == nil {
+			return i
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,88 @@
+package nodes
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestGenerationMatcher_Fingerprint(t *testing.T) {
+	matcher := NewGenerationMatcher(&config.GenerationConfig{TemperatureBuckets: []float64{0.3, 0.7, 1.0}})
+
+	a := matcher.Fingerprint(&GenerationContext{
+		Model:        "GPT-4o",
+		SystemPrompt: "You are   a helpful assistant.",
+		Temperature:  floatPtr(0.5),
+		Tools:        json.RawMessage(`{"name":"search","parameters":{"type":"object"}}`),
+	})
+	b := matcher.Fingerprint(&GenerationContext{
+		Model:            "gpt-4o",
+		SystemPromptHash: hashText("You are a helpful assistant."),
+		Temperature:      floatPtr(0.6),
+		Tools:            json.RawMessage(`{"parameters": {"type": "object"}, "name": "search"}`),
+	})
+	if a != b {
+		t.Errorf("expected equal fingerprints, got %+v and %+v", a, b)
+	}
+	if a.TemperatureBucket != 1 {
+		t.Errorf("expected temperature bucket 1, got %d", a.TemperatureBucket)
+	}
+
+	if empty := matcher.Fingerprint(nil); empty.TemperatureBucket != -1 || empty.Model != "" {
+		t.Errorf("expected empty fingerprint, got %+v", empty)
+	}
+}
+
+func TestGenerationMatcher_Compatible(t *testing.T) {
+	buckets := []float64{0.3, 0.7, 1.0}
+	exact := NewGenerationMatcher(&config.GenerationConfig{Match: "exact", TemperatureBuckets: buckets})
+	compatible := NewGenerationMatcher(&config.GenerationConfig{
+		Match:                        "compatible",
+		TemperatureBuckets:           buckets,
+		ModelGroups:                  map[string][]string{"gpt-4o": {"gpt-4o", "gpt-4o-2024-08-06"}},
+		MaxTemperatureBucketDistance: 1,
+		IgnoreFields:                 []string{"tools"},
+	})
+	ignore := NewGenerationMatcher(&config.GenerationConfig{Match: "ignore"})
+
+	stored := exact.Fingerprint(&GenerationContext{Model: "gpt-4o", SystemPromptHash: "p1", Temperature: floatPtr(0.2), ToolSchemaHash: "t1"})
+	doc := &schema.Document{MetaData: map[string]any{}}
+	for k, v := range exact.Metadata(stored) {
+		doc.MetaData[k] = v
+	}
+	// 模拟向量库 JSON 往返后数值变为 float64
+	doc.MetaData[MetaGenTemperatureBucket] = float64(stored.TemperatureBucket)
+
+	tests := []struct {
+		name      string
+		matcher   *GenerationMatcher
+		gc        *GenerationContext
+		want      bool
+		wantField string
+	}{
+		{name: "exact same", matcher: exact, gc: &GenerationContext{Model: "gpt-4o", SystemPromptHash: "p1", Temperature: floatPtr(0.1), ToolSchemaHash: "t1"}, want: true},
+		{name: "exact other model", matcher: exact, gc: &GenerationContext{Model: "gpt-4o-mini", SystemPromptHash: "p1", Temperature: floatPtr(0.1), ToolSchemaHash: "t1"}, wantField: "model"},
+		{name: "exact other prompt", matcher: exact, gc: &GenerationContext{Model: "gpt-4o", SystemPromptHash: "p2", Temperature: floatPtr(0.1), ToolSchemaHash: "t1"}, wantField: "system_prompt"},
+		{name: "exact other bucket", matcher: exact, gc: &GenerationContext{Model: "gpt-4o", SystemPromptHash: "p1", Temperature: floatPtr(0.5), ToolSchemaHash: "t1"}, wantField: "temperature"},
+		{name: "exact missing context", matcher: exact, gc: nil, wantField: "model"},
+		{name: "compatible model group and adjacent bucket", matcher: compatible, gc: &GenerationContext{Model: "gpt-4o-2024-08-06", SystemPromptHash: "p1", Temperature: floatPtr(0.5), ToolSchemaHash: "t2"}, want: true},
+		{name: "compatible bucket too far", matcher: compatible, gc: &GenerationContext{Model: "gpt-4o", SystemPromptHash: "p1", Temperature: floatPtr(0.9), ToolSchemaHash: "t1"}, wantField: "temperature"},
+		{name: "ignore", matcher: ignore, gc: nil, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, field := tt.matcher.Compatible(doc, tt.matcher.Fingerprint(tt.gc))
+			if got != tt.want || field != tt.wantField {
+				t.Errorf("expected (%v, %q), got (%v, %q)", tt.want, tt.wantField, got, field)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"strings"
+	"unicode"
+
+	"llm-cache/internal/eino/config"
+)
+
+// 否定冲突处理方式
+const (
+	NegationActionReject = "reject"
+	NegationActionDemote = "demote"
+)
+
+// englishNegations 英文否定词（缩写形式 n't 另行识别）
+var englishNegations = []string{
+	"not", "no", "never", "none", "nobody", "nothing", "nowhere", "neither", "nor",
+	"cannot", "cant", "dont", "doesnt", "didnt", "isnt", "arent", "wasnt", "werent",
+	"wont", "wouldnt", "shouldnt", "couldnt", "havent", "hasnt", "hadnt", "without", "unable",
+}
+
+// chineseNegations 中文否定词（按长度降序匹配，避免"没有"被重复计为"没"）
+var chineseNegations = []string{
+	"没有", "并非", "无法", "不能", "不可", "未能", "不", "没", "无", "未", "非", "别", "勿", "莫",
+}
+
+// chineseNonNegations 包含否定字但不表达否定的常见词，匹配前先移除
+var chineseNonNegations = []string{
+	"不得不", "差不多", "不错", "不过", "不断", "不少", "不仅", "不久", "不管", "不论", "不然",
+	"没错", "无论", "非常", "莫名", "别人", "别的", "区别", "类别", "特别", "分别", "告别", "级别",
+}
+
+// NegationGuard 实现否定/极性冲突守卫。
+// 基于中英文否定词典统计否定词个数，奇数视为否定极性，查询与候选极性不一致时视为冲突。
+type NegationGuard struct {
+	cfg      *config.NegationGuardConfig
+	english  map[string]struct{}
+	chinese  []string
+	excluded []string
+}
+
+// NewNegationGuard 创建一个新的否定冲突守卫。
+// 参数 cfg: 否定守卫配置。
+// 返回: NegationGuard 指针。
+func NewNegationGuard(cfg *config.NegationGuardConfig) *NegationGuard {
+	guard := &NegationGuard{
+		cfg:      cfg,
+		english:  make(map[string]struct{}, len(englishNegations)),
+		chinese:  append([]string{}, chineseNegations...),
+		excluded: chineseNonNegations,
+	}
+	for _, word := range englishNegations {
+		guard.english[word] = struct{}{}
+	}
+
+	// 额外否定词：含汉字的加入中文词典，其余加入英文词典
+	for _, word := range cfg.ExtraNegations {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" {
+			continue
+		}
+		if strings.IndexFunc(word, func(r rune) bool { return unicode.Is(unicode.Han, r) }) >= 0 {
+			guard.chinese = append([]string{word}, guard.chinese...)
+		} else {
+			guard.english[strings.ReplaceAll(word, "'", "")] = struct{}{}
+		}
+	}
+	return guard
+}
+
+// Negated 判断文本是否为否定极性（否定词个数为奇数）
+func (g *NegationGuard) Negated(text string) bool {
+	return g.countNegations(strings.ToLower(text))%2 == 1
+}
+
+// Check 检查候选问题的极性是否与查询一致。
+// 参数 queryNegated: 查询是否为否定极性。
+// 参数 candidate: 候选问题文本。
+// 返回: 是否一致，以及不一致时的原因。
+func (g *NegationGuard) Check(queryNegated bool, candidate string) (bool, string) {
+	candidateNegated := g.Negated(candidate)
+	if queryNegated == candidateNegated {
+		return true, ""
+	}
+	return false, "polarity mismatch: query " + polarityName(queryNegated) + " vs cached " + polarityName(candidateNegated)
+}
+
+// Action 返回冲突处理方式
+func (g *NegationGuard) Action() string {
+	if g.cfg.Action == NegationActionDemote {
+		return NegationActionDemote
+	}
+	return NegationActionReject
+}
+
+// Demote 计算降权后的分数。
+// 参数 score: 候选的原始分数。
+// 参数 hitThreshold: 本次查询生效的命中阈值。
+// 返回: 降权后的分数，以及是否仍保留候选（降权后分数不低于命中阈值和 MinScore 时保留）。
+func (g *NegationGuard) Demote(score, hitThreshold float64) (float64, bool) {
+	factor := g.cfg.DemoteFactor
+	if factor <= 0 || factor > 1 {
+		factor = 0.5
+	}
+	demoted := score * factor
+	return demoted, demoted >= max(hitThreshold, g.cfg.MinScore)
+}
+
+// countNegations 统计文本中的否定词个数
+func (g *NegationGuard) countNegations(text string) int {
+	count := 0
+
+	// 1. 英文：按词切分，去除撇号后查词典，并识别 n't 缩写
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !(unicode.IsLetter(r) || r == '\'' || r == '’') || unicode.Is(unicode.Han, r)
+	})
+	for _, word := range words {
+		word = strings.ReplaceAll(word, "’", "'")
+		if strings.HasSuffix(word, "n't") {
+			count++
+			continue
+		}
+		if _, ok := g.english[strings.ReplaceAll(word, "'", "")]; ok {
+			count++
+		}
+	}
+
+	// 2. 中文：先移除正反问句式（能不能、有没有）和非否定词，再按词典逐个匹配
+	text = removeAffirmNegQuestions(text)
+	for _, word := range g.excluded {
+		text = strings.ReplaceAll(text, word, " ")
+	}
+	for _, word := range g.chinese {
+		count += strings.Count(text, word)
+		text = strings.ReplaceAll(text, word, " ")
+	}
+
+	return count
+}
+
+// removeAffirmNegQuestions 移除"A不A"/"A没A"形式的正反问句式（如"能不能"、"可不可以"、"有没有"）
+func removeAffirmNegQuestions(text string) string {
+	runes := []rune(text)
+	for i := 0; i+2 < len(runes); i++ {
+		if (runes[i+1] == '不' || runes[i+1] == '没') && runes[i] == runes[i+2] && unicode.Is(unicode.Han, runes[i]) {
+			runes[i], runes[i+1], runes[i+2] = ' ', ' ', ' '
+		}
+	}
+	return string(runes)
+}
+
+// polarityName 返回极性名称
+func polarityName(negated bool) string {
+	if negated {
+		return "negative"
+	}
+	return "positive"
+}
@@ -0,0 +1,72 @@
+package nodes
+
+import (
+	"testing"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestNegationGuard_Negated(t *testing.T) {
+	guard := NewNegationGuard(&config.NegationGuardConfig{ExtraNegations: []string{"拒绝"}})
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "Can I cancel my order?", want: false},
+		{text: "Why can't I cancel my order?", want: true},
+		{text: "Why can’t I cancel my order?", want: true},
+		{text: "I cannot log in", want: true},
+		{text: "Is it not true that I can't leave?", want: false},
+		{text: "How do I login without a password", want: true},
+		{text: "可以取消订单吗", want: false},
+		{text: "为什么不能取消订单", want: true},
+		{text: "订单没有发货怎么办", want: true},
+		{text: "能不能取消订单", want: false},
+		{text: "有没有优惠券", want: false},
+		{text: "这个产品不错吗", want: false},
+		{text: "非常好用", want: false},
+		{text: "为什么被拒绝退款", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := guard.Negated(tt.text); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNegationGuard_CheckAndDemote(t *testing.T) {
+	guard := NewNegationGuard(&config.NegationGuardConfig{Action: "demote", DemoteFactor: 0.5, MinScore: 0.4})
+
+	if ok, reason := guard.Check(guard.Negated("can I cancel my order"), "why can't I cancel my order"); ok || reason == "" {
+		t.Errorf("expected polarity mismatch with reason, got ok=%v reason=%q", ok, reason)
+	}
+	if ok, _ := guard.Check(guard.Negated("可以退款吗"), "怎么申请退款"); !ok {
+		t.Errorf("expected same polarity")
+	}
+
+	if guard.Action() != NegationActionDemote {
+		t.Errorf("expected demote action, got %s", guard.Action())
+	}
+	if score, keep := guard.Demote(0.9, 0); score != 0.45 || !keep {
+		t.Errorf("expected (0.45, true), got (%v, %v)", score, keep)
+	}
+	if _, keep := guard.Demote(0.7, 0); keep {
+		t.Errorf("expected candidate below min score to be dropped")
+	}
+	if _, keep := guard.Demote(0.9, 0.5); keep {
+		t.Errorf("expected candidate below hit threshold to be dropped")
+	}
+
+	// 未配置最低分数时按命中阈值判断
+	unbounded := NewNegationGuard(&config.NegationGuardConfig{Action: "demote", DemoteFactor: 0.5})
+	if _, keep := unbounded.Demote(0.9, 0.8); keep {
+		t.Errorf("expected candidate below hit threshold to be dropped")
+	}
+	if _, keep := unbounded.Demote(0.9, 0.4); !keep {
+		t.Errorf("expected candidate above hit threshold to be kept")
+	}
+}
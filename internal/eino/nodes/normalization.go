@@ -0,0 +1,286 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+
+	"llm-cache/internal/eino/config"
+)
+
+// 规范化步骤名称
+const (
+	NormalizeNFKC        = "nfkc"
+	NormalizeFullWidth   = "fullwidth"
+	NormalizeT2S         = "t2s"
+	NormalizeCaseFold    = "casefold"
+	NormalizePunctuation = "punctuation"
+	NormalizeStopwords   = "stopwords"
+	NormalizeSynonyms    = "synonyms"
+)
+
+// 常用繁体字及其对应的简体字（按位置一一对应）
+var (
+	traditionalChars = "這個們來時為說國會對學發後經動過還進問題開關應現與實長東員機場間車電話號碼帳戶幫" +
+		"務費價錢買賣貨單訂購寶銀報導線網絡頁設計認證錯誤權歷記錄數據庫體區級維護驗險檢詢" +
+		"嗎麼麽沒無請讓謝給從點選擇變換種類樣處辦備註冊陸舊壞響頭條產質優紅綠藍黃聽講讀寫" +
+		"書筆紙畫圖視覺親愛歡樂氣熱溫滿邊遠離島灣臺廣華歐亞幣漢語詞飛鐘錶鐵藥醫療衛總結統" +
+		"係繫鬆齊麗龍門閉闆隊陽陰際隨雙雜難雲靈韓順須預領頻顏顯風飯飲館馬髮鬧魚鳥鹽麥黨齒" +
+		"億償儲兒內兩減則剛創劃勝勞協厲參吳園圍團壓壽夠夢奪婦孫寧將專尋層屬歲師幾廳彈徑復" +
+		"態慣憶戲戰擁擔擊擴擬攝斷於晉暫曆極構標樹橋歸殺漲測濟滅災烏燈爭爾牆獨獲環畢異當盡" +
+		"監盤確禮禱稱穩窮競節範築簡籤糧紀約紛細終組綜綫緊練縣績續聯職膽脫腦藝虛蘇蘭術補裝" +
+		"複見規觀訊討訓託許評詳誠調談論諸識議讚豐負財貢責貫貴貸資賠賬贈趕趨跡躍軍軟輕較載" +
+		"輸轉農運遊達違遞適遲遷遺郵鄉釋針鈕銷鋼鍵鎖鏈鏡閒閱闊闡陳隱雖雞靜項頓頒頸額願顧飾" +
+		"養驅驚鬥黴齡"
+	simplifiedChars = "这个们来时为说国会对学发后经动过还进问题开关应现与实长东员机场间车电话号码账户帮" +
+		"务费价钱买卖货单订购宝银报导线网络页设计认证错误权历记录数据库体区级维护验险检询" +
+		"吗么么没无请让谢给从点选择变换种类样处办备注册陆旧坏响头条产质优红绿蓝黄听讲读写" +
+		"书笔纸画图视觉亲爱欢乐气热温满边远离岛湾台广华欧亚币汉语词飞钟表铁药医疗卫总结统" +
+		"系系松齐丽龙门闭板队阳阴际随双杂难云灵韩顺须预领频颜显风饭饮馆马发闹鱼鸟盐麦党齿" +
+		"亿偿储儿内两减则刚创划胜劳协厉参吴园围团压寿够梦夺妇孙宁将专寻层属岁师几厅弹径复" +
+		"态惯忆戏战拥担击扩拟摄断于晋暂历极构标树桥归杀涨测济灭灾乌灯争尔墙独获环毕异当尽" +
+		"监盘确礼祷称稳穷竞节范筑简签粮纪约纷细终组综线紧练县绩续联职胆脱脑艺虚苏兰术补装" +
+		"复见规观讯讨训托许评详诚调谈论诸识议赞丰负财贡责贯贵贷资赔账赠赶趋迹跃军软轻较载" +
+		"输转农运游达违递适迟迁遗邮乡释针钮销钢键锁链镜闲阅阔阐陈隐虽鸡静项顿颁颈额愿顾饰" +
+		"养驱惊斗霉龄"
+)
+
+// t2sTable 繁体字到简体字的映射表
+var t2sTable = buildT2STable()
+
+// normalizeStep 定义一个规范化步骤
+type normalizeStep func(text string) string
+
+// Normalizer 实现可配置的文本规范化流水线。
+// 存储和查询使用同一个 Normalizer，保证缓存问题与查询在同一规范形式下向量化和比较。
+type Normalizer struct {
+	steps []normalizeStep
+}
+
+// NewNormalizer 创建一个新的文本规范化流水线。
+// 停用词和同义词词典会先经过其之前的步骤规范化，保证与待处理文本形式一致。
+// 参数 cfg: 规范化配置（为 nil 或未配置步骤时仅做空白规范化）。
+// 返回: Normalizer 指针，步骤名称无效或词典文件读取失败时返回错误。
+func NewNormalizer(cfg *config.NormalizationConfig) (*Normalizer, error) {
+	n := &Normalizer{}
+	if cfg == nil {
+		return n, nil
+	}
+
+	for _, name := range cfg.Steps {
+		var step normalizeStep
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case NormalizeNFKC:
+			step = norm.NFKC.String
+		case NormalizeFullWidth:
+			step = width.Fold.String
+		case NormalizeT2S:
+			step = traditionalToSimplified
+		case NormalizeCaseFold:
+			step = cases.Fold().String
+		case NormalizePunctuation:
+			step = removeSpecialChars
+		case NormalizeStopwords:
+			words := slices.Clone(cfg.Stopwords)
+			if cfg.StopwordsFile != "" {
+				lines, err := readDictionaryLines(cfg.StopwordsFile)
+				if err != nil {
+					return nil, fmt.Errorf("load stopwords: %w", err)
+				}
+				words = append(words, lines...)
+			}
+			step = newStopwordStep(n.normalizeTerms(words))
+		case NormalizeSynonyms:
+			if cfg.SynonymsFile == "" {
+				return nil, fmt.Errorf("synonyms step requires synonyms_file")
+			}
+			synonyms, err := loadSynonyms(cfg.SynonymsFile, n.Normalize)
+			if err != nil {
+				return nil, fmt.Errorf("load synonyms: %w", err)
+			}
+			step = newSynonymStep(synonyms)
+		default:
+			return nil, fmt.Errorf("unsupported normalization step: %s", name)
+		}
+		n.steps = append(n.steps, step)
+	}
+	return n, nil
+}
+
+// Normalize 按配置顺序执行规范化步骤，最后合并多余的空白字符。
+// 参数 text: 待规范化的文本。
+// 返回: 规范化后的文本。
+func (n *Normalizer) Normalize(text string) string {
+	if n == nil {
+		return normalizeWhitespace(text)
+	}
+	for _, step := range n.steps {
+		text = step(text)
+	}
+	return normalizeWhitespace(text)
+}
+
+// normalizeTerms 使用当前已有的步骤规范化词典条目，并去除空条目
+func (n *Normalizer) normalizeTerms(terms []string) []string {
+	result := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term = n.Normalize(term); term != "" {
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+// traditionalToSimplified 将常用繁体字转换为简体字
+func traditionalToSimplified(text string) string {
+	return strings.Map(func(r rune) rune {
+		if s, ok := t2sTable[r]; ok {
+			return s
+		}
+		return r
+	}, text)
+}
+
+// newStopwordStep 创建停用词移除步骤。
+// 含汉字的停用词按子串移除（中文没有词间空格），其余停用词按整词移除。
+func newStopwordStep(words []string) normalizeStep {
+	tokens := make(map[string]struct{})
+	var han []string
+	for _, word := range words {
+		if containsHan(word) {
+			han = append(han, word)
+		} else {
+			tokens[word] = struct{}{}
+		}
+	}
+	// 长词优先移除，避免短词先拆散长词
+	slices.SortFunc(han, func(a, b string) int { return len(b) - len(a) })
+
+	return func(text string) string {
+		for _, word := range han {
+			text = strings.ReplaceAll(text, word, "")
+		}
+		fields := strings.Fields(text)
+		kept := fields[:0]
+		for _, field := range fields {
+			if _, ok := tokens[field]; !ok {
+				kept = append(kept, field)
+			}
+		}
+		return strings.Join(kept, " ")
+	}
+}
+
+// newSynonymStep 创建同义词替换步骤（同义词 -> 标准词）。
+// 长词优先匹配；不含汉字的同义词要求整词匹配，避免替换单词的一部分。
+func newSynonymStep(synonyms map[string]string) normalizeStep {
+	if len(synonyms) == 0 {
+		return func(text string) string { return text }
+	}
+
+	terms := make([]string, 0, len(synonyms))
+	for term := range synonyms {
+		terms = append(terms, term)
+	}
+	slices.SortFunc(terms, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+
+	alternatives := make([]string, len(terms))
+	for i, term := range terms {
+		alt := regexp.QuoteMeta(term)
+		if isWordByte(term[0]) {
+			alt = `\b` + alt
+		}
+		if isWordByte(term[len(term)-1]) {
+			alt += `\b`
+		}
+		alternatives[i] = alt
+	}
+	re := regexp.MustCompile(strings.Join(alternatives, "|"))
+
+	return func(text string) string {
+		return re.ReplaceAllStringFunc(text, func(match string) string {
+			return synonyms[match]
+		})
+	}
+}
+
+// loadSynonyms 读取同义词词典文件，返回同义词到标准词的映射。
+// 每行格式为 "标准词: 同义词1, 同义词2"，词条使用 normalize 规范化。
+func loadSynonyms(path string, normalize func(string) string) (map[string]string, error) {
+	lines, err := readDictionaryLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	synonyms := make(map[string]string)
+	for _, line := range lines {
+		canonical, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid synonym line %q: expected \"canonical: synonym, ...\"", line)
+		}
+		canonical = normalize(canonical)
+		if canonical == "" {
+			return nil, fmt.Errorf("invalid synonym line %q: empty canonical term", line)
+		}
+		for _, synonym := range strings.Split(rest, ",") {
+			if synonym = normalize(synonym); synonym != "" && synonym != canonical {
+				synonyms[synonym] = canonical
+			}
+		}
+	}
+	return synonyms, nil
+}
+
+// readDictionaryLines 读取词典文件的非空行（忽略 # 开头的注释行）
+func readDictionaryLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// buildT2STable 根据繁简对照字符串构建映射表
+func buildT2STable() map[rune]rune {
+	traditional, simplified := []rune(traditionalChars), []rune(simplifiedChars)
+	table := make(map[rune]rune, len(traditional))
+	for i, r := range traditional {
+		table[r] = simplified[i]
+	}
+	return table
+}
+
+// containsHan 判断文本是否包含汉字
+func containsHan(text string) bool {
+	return strings.IndexFunc(text, func(r rune) bool { return unicode.Is(unicode.Han, r) }) >= 0
+}
+
+// isWordByte 判断字节是否为正则 \b 意义上的单词字符
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
@@ -0,0 +1,164 @@
+package nodes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestNormalizerSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    []string
+		input    string
+		expected string
+	}{
+		{
+			name:     "no steps only normalizes whitespace",
+			steps:    nil,
+			input:    "  Hello   World  ",
+			expected: "Hello World",
+		},
+		{
+			name:     "nfkc folds compatibility characters",
+			steps:    []string{"nfkc"},
+			input:    "ﬁle Ⅻ",
+			expected: "file XII",
+		},
+		{
+			name:     "fullwidth to halfwidth",
+			steps:    []string{"fullwidth"},
+			input:    "ＡＰＩ　Ｋｅｙ１２３？",
+			expected: "API Key123?",
+		},
+		{
+			name:     "traditional to simplified",
+			steps:    []string{"t2s"},
+			input:    "如何註冊帳號",
+			expected: "如何注册账号",
+		},
+		{
+			name:     "case folding",
+			steps:    []string{"casefold"},
+			input:    "How To RESET Password",
+			expected: "how to reset password",
+		},
+		{
+			name:     "punctuation stripping keeps question marks",
+			steps:    []string{"punctuation"},
+			input:    "how, to: reset (password)?",
+			expected: "how to reset password?",
+		},
+		{
+			name:     "chained steps",
+			steps:    []string{"nfkc", "t2s", "casefold", "punctuation"},
+			input:    "ＶＩＰ會員，怎麼退款？",
+			expected: "vip会员怎么退款?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewNormalizer(&config.NormalizationConfig{Steps: tt.steps})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result := n.Normalize(tt.input); result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestNormalizerStopwords(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stopwords.txt")
+	if err := os.WriteFile(path, []byte("# comment\nplease\n请问\n"), 0o644); err != nil {
+		t.Fatalf("write stopwords: %v", err)
+	}
+
+	n, err := NewNormalizer(&config.NormalizationConfig{
+		Steps:         []string{"casefold", "stopwords"},
+		Stopwords:     []string{"The", "a"},
+		StopwordsFile: path,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Please reset the password", "reset password"},
+		{"a theme", "theme"},
+		{"请问如何重置密码", "如何重置密码"},
+	}
+	for _, tt := range tests {
+		if result := n.Normalize(tt.input); result != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, result)
+		}
+	}
+}
+
+func TestNormalizerSynonyms(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "synonyms.txt")
+	content := "# canonical: synonyms\nlogin: log in, sign in, signin\n密码: 口令, 密碼\nusa: us\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write synonyms: %v", err)
+	}
+
+	n, err := NewNormalizer(&config.NormalizationConfig{
+		Steps:        []string{"t2s", "casefold", "synonyms"},
+		SynonymsFile: path,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"How to Sign In", "how to login"},
+		{"cannot signin", "cannot login"},
+		{"忘记口令怎么办", "忘记密码怎么办"},
+		{"shipping to US", "shipping to usa"},
+		{"use bus", "use bus"},
+	}
+	for _, tt := range tests {
+		if result := n.Normalize(tt.input); result != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, result)
+		}
+	}
+}
+
+func TestNewNormalizerErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.txt")
+	if err := os.WriteFile(invalid, []byte("no separator here\n"), 0o644); err != nil {
+		t.Fatalf("write synonyms: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  *config.NormalizationConfig
+	}{
+		{"unknown step", &config.NormalizationConfig{Steps: []string{"stem"}}},
+		{"synonyms without file", &config.NormalizationConfig{Steps: []string{"synonyms"}}},
+		{"missing synonyms file", &config.NormalizationConfig{Steps: []string{"synonyms"}, SynonymsFile: filepath.Join(dir, "missing.txt")}},
+		{"invalid synonyms line", &config.NormalizationConfig{Steps: []string{"synonyms"}, SynonymsFile: invalid}},
+		{"missing stopwords file", &config.NormalizationConfig{Steps: []string{"stopwords"}, StopwordsFile: filepath.Join(dir, "missing.txt")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewNormalizer(tt.cfg); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
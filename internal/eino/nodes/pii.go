@@ -0,0 +1,342 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// 个人敏感信息（PII）类型
+const (
+	PIIEmail    = "email"
+	PIIPhone    = "phone"
+	PIIIDCard   = "id_card"
+	PIIBankCard = "bank_card"
+)
+
+// PII 处理方式
+const (
+	PIIActionReject = "reject" // 拒绝写入
+	PIIActionMask   = "mask"   // 原位脱敏后写入
+	PIIActionFlag   = "flag"   // 原样写入并标记 contains_pii，查询时跳过
+)
+
+// PII 相关的元数据键
+const (
+	// MetaContainsPII 缓存项包含未脱敏的个人敏感信息（查询时跳过）
+	MetaContainsPII = "contains_pii"
+	// MetaPIITypes 缓存项中检测到的 PII 类型（逗号分隔）
+	MetaPIITypes = "pii_types"
+)
+
+// piiTypes 按检测优先级排列的 PII 类型（重叠时保留优先级高的类型）
+var piiTypes = []string{PIIIDCard, PIIBankCard, PIIPhone, PIIEmail}
+
+// piiMasks 各类型脱敏后的占位符
+var piiMasks = map[string]string{
+	PIIEmail:    "[EMAIL]",
+	PIIPhone:    "[PHONE]",
+	PIIIDCard:   "[ID_CARD]",
+	PIIBankCard: "[BANK_CARD]",
+}
+
+var (
+	emailPattern    = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	phonePattern    = regexp.MustCompile(`(?:\+?86[ -]?)?1[3-9]\d(?:[ -]?\d{4}){2}`)
+	idCardPattern   = regexp.MustCompile(`\d{17}[\dXx]`)
+	digitRunPattern = regexp.MustCompile(`\d+(?:[ -]\d+)*`)
+	digitsPattern   = regexp.MustCompile(`\d+`)
+)
+
+// 银行卡号的位数范围
+const (
+	bankCardMinDigits = 13
+	bankCardMaxDigits = 19
+)
+
+// idCardWeights 居民身份证号码校验码的加权因子
+var idCardWeights = [17]int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+// idCardCheckCodes 居民身份证号码的校验码（按加权和模 11 取值）
+const idCardCheckCodes = "10X98765432"
+
+// PIIMatch 定义文本中检测到的一处 PII。
+type PIIMatch struct {
+	Type  string
+	Start int
+	End   int
+}
+
+// PIIResult 定义 PII 扫描的处理结果。
+// Question、Answer 为按 mask 处理后的文本，Types 为检测到的全部类型。
+type PIIResult struct {
+	Question string
+	Answer   string
+	Types    []string
+	Rejected bool
+	Reason   string
+	Flagged  bool
+}
+
+// PIIScanner 实现个人敏感信息的检测和处理。
+// 支持邮箱、手机号、居民身份证号（校验码验证）和银行卡号（Luhn 校验）。
+type PIIScanner struct {
+	actions map[string]string
+}
+
+// NewPIIScanner 创建一个新的 PII 扫描器。
+// 参数 cfg: PII 配置（未配置处理方式的类型使用 DefaultAction）。
+// 返回: PIIScanner 指针，类型或处理方式无效时返回错误。
+func NewPIIScanner(cfg *config.PIIConfig) (*PIIScanner, error) {
+	defaultAction := cfg.DefaultAction
+	if defaultAction == "" {
+		defaultAction = PIIActionMask
+	}
+
+	s := &PIIScanner{actions: make(map[string]string, len(piiTypes))}
+	for _, piiType := range piiTypes {
+		s.actions[piiType] = defaultAction
+	}
+	for piiType, action := range cfg.Actions {
+		if _, ok := piiMasks[piiType]; !ok {
+			return nil, fmt.Errorf("unknown pii type %q", piiType)
+		}
+		s.actions[piiType] = action
+	}
+	for piiType, action := range s.actions {
+		switch action {
+		case PIIActionReject, PIIActionMask, PIIActionFlag:
+		default:
+			return nil, fmt.Errorf("invalid pii action %q for %s", action, piiType)
+		}
+	}
+	return s, nil
+}
+
+// Scan 检测文本中的 PII，返回按位置排序且互不重叠的匹配
+func (s *PIIScanner) Scan(text string) []PIIMatch {
+	var matches []PIIMatch
+	for _, piiType := range piiTypes {
+		for _, m := range findPII(piiType, text) {
+			if !overlaps(matches, m) {
+				matches = append(matches, m)
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return matches
+}
+
+// Process 按各类型的处理方式处理问答对中的 PII。
+// 任一类型为 reject 时拒绝；mask 类型替换为占位符；flag 类型保留原文并标记。
+// 参数 question: 问题。
+// 参数 answer: 答案。
+// 返回: 处理结果。
+func (s *PIIScanner) Process(question, answer string) *PIIResult {
+	result := &PIIResult{Question: question, Answer: answer}
+
+	questionMatches := s.Scan(question)
+	answerMatches := s.Scan(answer)
+	found := make(map[string]bool)
+	for _, m := range append(questionMatches, answerMatches...) {
+		found[m.Type] = true
+	}
+	for _, piiType := range piiTypes {
+		if !found[piiType] {
+			continue
+		}
+		result.Types = append(result.Types, piiType)
+		switch s.actions[piiType] {
+		case PIIActionReject:
+			result.Rejected = true
+		case PIIActionFlag:
+			result.Flagged = true
+		}
+	}
+
+	if result.Rejected {
+		var rejected []string
+		for _, piiType := range result.Types {
+			if s.actions[piiType] == PIIActionReject {
+				rejected = append(rejected, piiType)
+			}
+		}
+		result.Reason = "contains pii: " + strings.Join(rejected, ", ")
+		return result
+	}
+
+	result.Question = s.mask(question, questionMatches, false)
+	result.Answer = s.mask(answer, answerMatches, false)
+	return result
+}
+
+// Redact 将文本中所有类型的 PII 替换为占位符（用于日志，不考虑处理方式）
+func (s *PIIScanner) Redact(text string) string {
+	return s.mask(text, s.Scan(text), true)
+}
+
+// mask 替换匹配到的 PII（all 为 false 时仅替换处理方式为 mask 的类型）
+func (s *PIIScanner) mask(text string, matches []PIIMatch, all bool) string {
+	if len(matches) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		if !all && s.actions[m.Type] != PIIActionMask {
+			continue
+		}
+		sb.WriteString(text[last:m.Start])
+		sb.WriteString(piiMasks[m.Type])
+		last = m.End
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// ContainsPII 判断缓存项是否标记为包含未脱敏的 PII
+func ContainsPII(doc *schema.Document) bool {
+	switch v := doc.MetaData[MetaContainsPII].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
+
+// findPII 查找指定类型的 PII 候选并进行边界和校验
+func findPII(piiType, text string) []PIIMatch {
+	var pattern *regexp.Regexp
+	var validate func(string) bool
+	switch piiType {
+	case PIIEmail:
+		pattern, validate = emailPattern, func(string) bool { return true }
+	case PIIPhone:
+		pattern, validate = phonePattern, func(string) bool { return true }
+	case PIIIDCard:
+		pattern, validate = idCardPattern, validIDCard
+	case PIIBankCard:
+		return findBankCards(text)
+	}
+
+	var matches []PIIMatch
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		if piiType != PIIEmail && !digitBoundary(text, loc[0], loc[1]) {
+			continue
+		}
+		if validate(text[loc[0]:loc[1]]) {
+			matches = append(matches, PIIMatch{Type: piiType, Start: loc[0], End: loc[1]})
+		}
+	}
+	return matches
+}
+
+// findBankCards 查找通过 Luhn 校验的银行卡号。
+// 将以空格或连字符分隔的数字串拆分为数字组，从左到右尝试由完整数字组构成的 13-19 位窗口（优先最长），
+// 避免卡号后紧跟的其他数字（如"4111 1111 1111 1111 12 件"）使整串校验失败而漏检卡号。
+func findBankCards(text string) []PIIMatch {
+	var matches []PIIMatch
+	for _, run := range digitRunPattern.FindAllStringIndex(text, -1) {
+		groups := digitsPattern.FindAllStringIndex(text[run[0]:run[1]], -1)
+		for i := 0; i < len(groups); {
+			end := -1
+			digits := 0
+			for j := i; j < len(groups); j++ {
+				digits += groups[j][1] - groups[j][0]
+				if digits > bankCardMaxDigits {
+					break
+				}
+				if digits >= bankCardMinDigits && validBankCard(text[run[0]+groups[i][0]:run[0]+groups[j][1]]) {
+					end = j
+				}
+			}
+			if end < 0 {
+				i++
+				continue
+			}
+			matches = append(matches, PIIMatch{Type: PIIBankCard, Start: run[0] + groups[i][0], End: run[0] + groups[end][1]})
+			i = end + 1
+		}
+	}
+	return matches
+}
+
+// digitBoundary 判断匹配前后是否没有紧邻的数字（避免截取更长数字串的一部分）
+func digitBoundary(text string, start, end int) bool {
+	if start > 0 && isDigit(text[start-1]) {
+		return false
+	}
+	if end < len(text) && (isDigit(text[end]) || text[end] == 'X' || text[end] == 'x') {
+		return false
+	}
+	return true
+}
+
+// overlaps 判断匹配是否与已有匹配重叠
+func overlaps(matches []PIIMatch, m PIIMatch) bool {
+	for _, existing := range matches {
+		if m.Start < existing.End && existing.Start < m.End {
+			return true
+		}
+	}
+	return false
+}
+
+// validIDCard 校验 18 位居民身份证号码的出生日期和校验码
+func validIDCard(id string) bool {
+	month := (id[10]-'0')*10 + (id[11] - '0')
+	day := (id[12]-'0')*10 + (id[13] - '0')
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return false
+	}
+
+	sum := 0
+	for i, w := range idCardWeights {
+		sum += int(id[i]-'0') * w
+	}
+	check := id[17]
+	if check == 'x' {
+		check = 'X'
+	}
+	return idCardCheckCodes[sum%11] == check
+}
+
+// validBankCard 使用 Luhn 算法校验 13-19 位银行卡号（允许空格和连字符分组）
+func validBankCard(card string) bool {
+	digits := make([]int, 0, len(card))
+	for i := 0; i < len(card); i++ {
+		if isDigit(card[i]) {
+			digits = append(digits, int(card[i]-'0'))
+		}
+	}
+	if len(digits) < bankCardMinDigits || len(digits) > bankCardMaxDigits {
+		return false
+	}
+
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// isDigit 判断字节是否为 ASCII 数字
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
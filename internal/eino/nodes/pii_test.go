@@ -0,0 +1,167 @@
+package nodes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestPIIScannerScan(t *testing.T) {
+	scanner, err := NewPIIScanner(&config.PIIConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"email", "contact me at alice.w@example.com.cn please", []string{PIIEmail}},
+		{"mobile phone", "我的手机是 138 0013 8000", []string{PIIPhone}},
+		{"phone with country code", "call +86 13800138000", []string{PIIPhone}},
+		{"valid id card", "身份证号11010519491231002X", []string{PIIIDCard}},
+		{"invalid id checksum", "身份证号110105194912310021", nil},
+		{"valid bank card", "卡号 4111 1111 1111 1111 扣款失败", []string{PIIBankCard}},
+		{"luhn failure", "卡号 4111 1111 1111 1112", nil},
+		{"bank card followed by digits", "card 4111 1111 1111 1111 12 items", []string{PIIBankCard}},
+		{"longer digit run", "订单号 202405011234567890123 已发货", nil},
+		{"multiple", "alice@example.com 13800138000", []string{PIIEmail, PIIPhone}},
+		{"none", "How do I reset my password?", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range scanner.Scan(tt.text) {
+				got = append(got, m.Type)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPIIScannerProcess(t *testing.T) {
+	scanner, err := NewPIIScanner(&config.PIIConfig{
+		Actions: map[string]string{PIIIDCard: PIIActionReject, PIIPhone: PIIActionFlag},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		question     string
+		answer       string
+		wantQuestion string
+		wantAnswer   string
+		wantRejected bool
+		wantFlagged  bool
+	}{
+		{
+			name:         "mask email in question and answer",
+			question:     "alice@example.com 收不到验证码怎么办？",
+			answer:       "请检查 alice@example.com 的垃圾邮件箱。",
+			wantQuestion: "[EMAIL] 收不到验证码怎么办？",
+			wantAnswer:   "请检查 [EMAIL] 的垃圾邮件箱。",
+		},
+		{
+			name:         "mask bank card followed by digits",
+			question:     "卡 4111 1111 1111 1111 12 件商品退款",
+			answer:       "退款将原路退回。",
+			wantQuestion: "卡 [BANK_CARD] 12 件商品退款",
+			wantAnswer:   "退款将原路退回。",
+		},
+		{
+			name:         "reject id card",
+			question:     "11010519491231002X 如何补办？",
+			answer:       "请携带户口本到派出所办理。",
+			wantQuestion: "11010519491231002X 如何补办？",
+			wantAnswer:   "请携带户口本到派出所办理。",
+			wantRejected: true,
+		},
+		{
+			name:         "flag phone and mask email",
+			question:     "13800138000 和 alice@example.com 换绑",
+			answer:       "请在账号设置中操作。",
+			wantQuestion: "13800138000 和 [EMAIL] 换绑",
+			wantAnswer:   "请在账号设置中操作。",
+			wantFlagged:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := scanner.Process(tt.question, tt.answer)
+			if result.Question != tt.wantQuestion || result.Answer != tt.wantAnswer {
+				t.Errorf("expected %q / %q, got %q / %q", tt.wantQuestion, tt.wantAnswer, result.Question, result.Answer)
+			}
+			if result.Rejected != tt.wantRejected {
+				t.Errorf("expected rejected %v, got %v (%s)", tt.wantRejected, result.Rejected, result.Reason)
+			}
+			if result.Flagged != tt.wantFlagged {
+				t.Errorf("expected flagged %v, got %v", tt.wantFlagged, result.Flagged)
+			}
+		})
+	}
+}
+
+func TestPIIScannerRedact(t *testing.T) {
+	scanner, err := NewPIIScanner(&config.PIIConfig{DefaultAction: PIIActionFlag})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "[PHONE] 的卡 [BANK_CARD] 被冻结"
+	if got := scanner.Redact("13800138000 的卡 4111-1111-1111-1111 被冻结"); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	// 卡号后紧跟其他数字时仍只遮盖卡号
+	expected = "card [BANK_CARD] 12 items"
+	if got := scanner.Redact("card 4111 1111 1111 1111 12 items"); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestNewPIIScannerInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.PIIConfig
+	}{
+		{"unknown type", config.PIIConfig{Actions: map[string]string{"passport": PIIActionMask}}},
+		{"unknown action", config.PIIConfig{Actions: map[string]string{PIIEmail: "drop"}}},
+		{"unknown default action", config.PIIConfig{DefaultAction: "drop"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPIIScanner(&tt.cfg); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestContainsPII(t *testing.T) {
+	tests := []struct {
+		meta     map[string]any
+		expected bool
+	}{
+		{map[string]any{MetaContainsPII: true}, true},
+		{map[string]any{MetaContainsPII: "true"}, true},
+		{map[string]any{MetaContainsPII: false}, false},
+		{map[string]any{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := ContainsPII(&schema.Document{MetaData: tt.meta}); got != tt.expected {
+			t.Errorf("expected %v, got %v for %v", tt.expected, got, tt.meta)
+		}
+	}
+}
@@ -78,7 +78,7 @@ func removeControlChars(s string) string {
 }
 
 // removeSpecialChars 移除特殊字符，仅保留字母、数字、空格和问号。
-// 该函数目前作为可选的辅助工具，用于更激进的文本清洗。
+// 用于更激进的文本清洗（规范化流水线的 punctuation 步骤）。
 func removeSpecialChars(s string) string {
 	return strings.Map(func(r rune) rune {
 		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsSpace(r) || r == '?' || r == '？' {
@@ -0,0 +1,189 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// MetaTemplateID 问题所匹配的提示词模板 ID（未匹配任何模板时为空字符串）
+const MetaTemplateID = "template_id"
+
+// placeholderPattern 模板中的 {name} 占位符
+var placeholderPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// compiledTemplate 定义编译后的提示词模板
+type compiledTemplate struct {
+	id string
+	re *regexp.Regexp
+}
+
+// TemplateExtractor 实现提示词模板剥离。
+// 按 user_type 选择模板列表，匹配后仅保留模板中的可变部分，避免模板原文主导向量相似度。
+type TemplateExtractor struct {
+	defaults  []compiledTemplate
+	userTypes map[string][]compiledTemplate
+}
+
+// NewTemplateExtractor 创建一个新的提示词模板提取器。
+// 参数 cfg: 模板配置（为 nil 时不做任何提取）。
+// 返回: TemplateExtractor 指针，模板无效时返回错误。
+func NewTemplateExtractor(cfg *config.TemplatesConfig) (*TemplateExtractor, error) {
+	e := &TemplateExtractor{userTypes: make(map[string][]compiledTemplate)}
+	if cfg == nil {
+		return e, nil
+	}
+
+	var err error
+	if e.defaults, err = compileTemplates(cfg.Default); err != nil {
+		return nil, err
+	}
+	for userType, templates := range cfg.UserTypes {
+		if e.userTypes[userType], err = compileTemplates(templates); err != nil {
+			return nil, fmt.Errorf("user_type %s: %w", userType, err)
+		}
+	}
+	return e, nil
+}
+
+// Enabled 判断是否配置了任何模板
+func (e *TemplateExtractor) Enabled() bool {
+	if len(e.defaults) > 0 {
+		return true
+	}
+	for _, templates := range e.userTypes {
+		if len(templates) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract 按 user_type 对应的模板列表提取问题的可变部分。
+// 多个可变部分按出现顺序以空格连接；未匹配任何模板时原样返回。
+// 参数 userType: 用户类型。
+// 参数 text: 原始问题。
+// 返回: 提取后的问题，以及匹配的模板 ID（未匹配时为空字符串）。
+func (e *TemplateExtractor) Extract(userType, text string) (string, string) {
+	templates, ok := e.userTypes[userType]
+	if !ok {
+		templates = e.defaults
+	}
+
+	for _, t := range templates {
+		m := t.re.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+
+		// 优先使用命名捕获组，没有命名捕获组时使用全部捕获组
+		names := t.re.SubexpNames()
+		named := false
+		for _, name := range names[1:] {
+			named = named || name != ""
+		}
+		parts := make([]string, 0, len(m)-1)
+		for i := 1; i < len(m); i++ {
+			if named && names[i] == "" {
+				continue
+			}
+			if part := strings.TrimSpace(m[i]); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, " "), t.id
+	}
+	return text, ""
+}
+
+// Compatible 判断候选文档的模板 ID 是否与查询一致。
+// 参数 doc: 候选文档。
+// 参数 templateID: 查询匹配的模板 ID。
+// 返回: 是否一致，以及不一致时的原因。
+func (e *TemplateExtractor) Compatible(doc *schema.Document, templateID string) (bool, string) {
+	docTemplate, _ := doc.MetaData[MetaTemplateID].(string)
+	if docTemplate == templateID {
+		return true, ""
+	}
+	return false, fmt.Sprintf("template mismatch: query %q vs cached %q", templateID, docTemplate)
+}
+
+// compileTemplates 编译模板列表
+func compileTemplates(templates []config.PromptTemplate) ([]compiledTemplate, error) {
+	compiled := make([]compiledTemplate, 0, len(templates))
+	for _, t := range templates {
+		if t.ID == "" {
+			return nil, fmt.Errorf("prompt template id is required")
+		}
+
+		var re *regexp.Regexp
+		var err error
+		switch {
+		case t.Pattern != "" && t.Template != "":
+			return nil, fmt.Errorf("prompt template %s: pattern and template are mutually exclusive", t.ID)
+		case t.Pattern != "":
+			re, err = regexp.Compile(t.Pattern)
+		case t.Template != "":
+			re, err = regexp.Compile(templateToPattern(t.Template))
+		default:
+			return nil, fmt.Errorf("prompt template %s: pattern or template is required", t.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("compile prompt template %s: %w", t.ID, err)
+		}
+		if re.NumSubexp() == 0 {
+			return nil, fmt.Errorf("prompt template %s: no placeholder or capture group", t.ID)
+		}
+		compiled = append(compiled, compiledTemplate{id: t.ID, re: re})
+	}
+	return compiled, nil
+}
+
+// templateToPattern 将带 {name} 占位符的模板转换为正则表达式。
+// 模板原文按字面匹配（空白字符宽松匹配），占位符转换为命名捕获组，整体首尾锚定。
+func templateToPattern(template string) string {
+	var b strings.Builder
+	b.WriteString(`(?s)^\s*`)
+
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(template, -1) {
+		b.WriteString(literalPattern(template[last:loc[0]], last > 0))
+		b.WriteString(`(?P<` + template[loc[2]:loc[3]] + `>.+?)`)
+		last = loc[1]
+	}
+	b.WriteString(literalPattern(template[last:], false))
+
+	b.WriteString(`\s*$`)
+	return b.String()
+}
+
+// literalPattern 将模板的字面片段转换为正则表达式（连续空白字符匹配任意空白）。
+// betweenPlaceholders 为 true 且片段仅含空白时要求至少一个空白字符，以区分相邻的占位符。
+func literalPattern(literal string, betweenPlaceholders bool) string {
+	fields := strings.Fields(literal)
+	if len(fields) == 0 {
+		if betweenPlaceholders && literal != "" {
+			return `\s+`
+		}
+		return `\s*`
+	}
+
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = regexp.QuoteMeta(field)
+	}
+	pattern := strings.Join(quoted, `\s+`)
+	if strings.TrimLeftFunc(literal, unicode.IsSpace) != literal {
+		pattern = `\s*` + pattern
+	}
+	if strings.TrimRightFunc(literal, unicode.IsSpace) != literal {
+		pattern += `\s*`
+	}
+	return pattern
+}
@@ -0,0 +1,129 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestTemplateExtractorExtract(t *testing.T) {
+	extractor, err := NewTemplateExtractor(&config.TemplatesConfig{
+		Default: []config.PromptTemplate{
+			{ID: "assistant", Template: "You are a helpful assistant. Answer concisely.\nQuestion: {q}"},
+			{ID: "product", Template: "产品：{product}\n问题：{question}"},
+		},
+		UserTypes: map[string][]config.PromptTemplate{
+			"support": {{ID: "ticket", Pattern: `^\[ticket (?:\d+)\] (?P<body>.+)$`}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		userType   string
+		input      string
+		expected   string
+		templateID string
+	}{
+		{
+			name:       "named template",
+			input:      "You are a helpful assistant.   Answer concisely.\n\nQuestion: how to reset password?",
+			expected:   "how to reset password?",
+			templateID: "assistant",
+		},
+		{
+			name:       "multiple placeholders",
+			input:      "产品：云服务器\n问题：如何扩容",
+			expected:   "云服务器 如何扩容",
+			templateID: "product",
+		},
+		{
+			name:       "no template matched",
+			input:      "how to reset password?",
+			expected:   "how to reset password?",
+			templateID: "",
+		},
+		{
+			name:       "user type regex uses named groups only",
+			userType:   "support",
+			input:      "[ticket 42] refund not received",
+			expected:   "refund not received",
+			templateID: "ticket",
+		},
+		{
+			name:       "user type override ignores default templates",
+			userType:   "support",
+			input:      "You are a helpful assistant. Answer concisely. Question: hi",
+			expected:   "You are a helpful assistant. Answer concisely. Question: hi",
+			templateID: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question, templateID := extractor.Extract(tt.userType, tt.input)
+			if question != tt.expected {
+				t.Errorf("expected question %q, got %q", tt.expected, question)
+			}
+			if templateID != tt.templateID {
+				t.Errorf("expected template %q, got %q", tt.templateID, templateID)
+			}
+		})
+	}
+}
+
+func TestTemplateExtractorCompatible(t *testing.T) {
+	extractor, err := NewTemplateExtractor(&config.TemplatesConfig{
+		Default: []config.PromptTemplate{{ID: "assistant", Template: "Question: {q}"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		metadata   map[string]any
+		templateID string
+		expected   bool
+	}{
+		{"same template", map[string]any{MetaTemplateID: "assistant"}, "assistant", true},
+		{"different template", map[string]any{MetaTemplateID: "other"}, "assistant", false},
+		{"templated query vs plain cache", map[string]any{}, "assistant", false},
+		{"plain query vs plain cache", map[string]any{MetaTemplateID: ""}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, _ := extractor.Compatible(&schema.Document{MetaData: tt.metadata}, tt.templateID)
+			if ok != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, ok)
+			}
+		})
+	}
+}
+
+func TestNewTemplateExtractorErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template config.PromptTemplate
+	}{
+		{"missing id", config.PromptTemplate{Template: "Q: {q}"}},
+		{"missing pattern and template", config.PromptTemplate{ID: "a"}},
+		{"both pattern and template", config.PromptTemplate{ID: "a", Template: "Q: {q}", Pattern: "Q: (.+)"}},
+		{"invalid pattern", config.PromptTemplate{ID: "a", Pattern: "Q: (.+"}},
+		{"no placeholder", config.PromptTemplate{ID: "a", Template: "static text"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.TemplatesConfig{Default: []config.PromptTemplate{tt.template}}
+			if _, err := NewTemplateExtractor(cfg); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
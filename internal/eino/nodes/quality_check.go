@@ -8,6 +8,14 @@ import (
 	"llm-cache/internal/eino/config"
 )
 
+// 质量检查相关的元数据键
+const (
+	// MetaQualityScore 质量检查的综合分数
+	MetaQualityScore = "quality_score"
+	// MetaQualityDetails 各评估策略的检查明细（JSON 字符串）
+	MetaQualityDetails = "quality_details"
+)
+
 // QualityCheckInput 定义质量检查的输入参数。
 // 包含问题、答案、用户类型、元数据以及是否强制写入的标志。
 type QualityCheckInput struct {
@@ -24,6 +32,7 @@ type QualityCheckResult struct {
 	Passed   bool
 	Reason   string
 	Score    float64
+	Details  []*CheckDetail
 	Question string
 	Answer   string
 	UserType string
@@ -31,20 +40,34 @@ type QualityCheckResult struct {
 }
 
 // QualityChecker 实现内容质量检查器。
-// 负责对写入缓存的问答对进行质量评估，包括长度检查、黑名单过滤和规则评分。
+// 负责对写入缓存的问答对进行质量评估，默认包括长度检查、黑名单过滤和规则评分。
 type QualityChecker struct {
-	cfg *config.QualityConfig
+	cfg        *config.QualityConfig
+	strategies []WeightedStrategy
+	judge      *QualityJudge
 }
 
-// NewQualityChecker 创建一个新的质量检查器实例。
+// NewQualityChecker 创建一个新的质量检查器实例（使用内置的默认策略）。
 // 参数 cfg: 质量检查配置。
 // 返回: QualityChecker 指针。
 func NewQualityChecker(cfg *config.QualityConfig) *QualityChecker {
-	return &QualityChecker{cfg: cfg}
+	return &QualityChecker{cfg: cfg, strategies: DefaultQualityStrategies(cfg)}
+}
+
+// WithStrategies 替换质量评估策略（通常来自 NewQualityStrategies）
+func (c *QualityChecker) WithStrategies(strategies []WeightedStrategy) *QualityChecker {
+	c.strategies = strategies
+	return c
+}
+
+// WithJudge 设置 LLM 评审器（与其他策略并发执行，为 nil 时跳过）
+func (c *QualityChecker) WithJudge(judge *QualityJudge) *QualityChecker {
+	c.judge = judge
+	return c
 }
 
 // Check 执行全面的质量检查流程 Lambda 函数。
-// 依次进行长度、黑名单和评分检查，返回检查结果。
+// 在有界工作池中并发执行各项评估策略，按权重计算综合分数，返回检查结果和各策略明细。
 // 如果 ForceWrite 为 true 或质量检查被禁用，则直接通过检查。
 func (c *QualityChecker) Check(ctx context.Context, input *QualityCheckInput) (*QualityCheckResult, error) {
 	result := &QualityCheckResult{
@@ -61,47 +84,29 @@ func (c *QualityChecker) Check(ctx context.Context, input *QualityCheckInput) (*
 		return result, nil
 	}
 
-	// 1. 检查问题长度
-	questionLen := len(strings.TrimSpace(input.Question))
-	if questionLen < c.cfg.MinQuestionLength {
-		result.Passed = false
-		result.Reason = "question too short"
-		result.Score = 0.0
-		return result, nil
+	// 1. 并发执行各项评估策略（LLM 评审作为其中一项）
+	strategies := c.strategies
+	if c.judge != nil {
+		strategies = append(append([]WeightedStrategy{}, strategies...), c.judge.Strategy())
 	}
-	if c.cfg.MaxQuestionLength > 0 && questionLen > c.cfg.MaxQuestionLength {
-		result.Passed = false
-		result.Reason = "question too long"
-		result.Score = 0.0
-		return result, nil
+	details, err := c.runStrategies(ctx, strategies, input)
+	if err != nil {
+		return nil, err
 	}
-
-	// 2. 检查答案长度
-	answerLen := len(strings.TrimSpace(input.Answer))
-	if answerLen < c.cfg.MinAnswerLength {
-		result.Passed = false
-		result.Reason = "answer too short"
-		result.Score = 0.0
-		return result, nil
-	}
-	if c.cfg.MaxAnswerLength > 0 && answerLen > c.cfg.MaxAnswerLength {
-		result.Passed = false
-		result.Reason = "answer too long"
-		result.Score = 0.0
-		return result, nil
-	}
-
-	// 3. 检查黑名单
-	if containsBlacklistWords(input.Question, c.cfg.BlacklistKeywords) ||
-		containsBlacklistWords(input.Answer, c.cfg.BlacklistKeywords) {
-		result.Passed = false
-		result.Reason = "contains blacklisted content"
-		result.Score = 0.0
-		return result, nil
+	result.Details = details
+
+	// 2. 任一策略未通过即拒绝（按策略配置顺序取第一个原因）
+	for _, detail := range details {
+		if !detail.Passed {
+			result.Passed = false
+			result.Reason = detail.Message
+			result.Score = 0.0
+			return result, nil
+		}
 	}
 
-	// 4. 计算质量分数
-	score := calculateQualityScore(input.Question, input.Answer)
+	// 3. 计算加权分数
+	score := weightedScore(details)
 	if score < c.cfg.ScoreThreshold {
 		result.Passed = false
 		result.Reason = "quality score below threshold"
@@ -169,11 +174,16 @@ func calculateQualityScore(question, answer string) float64 {
 }
 
 // CheckDetail 定义单项检查的详细结果。
+// Weight 为该项在综合分数中的权重，Passed 为 false 时直接拒绝写入，跳过的检查不计入综合分数。
 type CheckDetail struct {
-	Name    string
-	Passed  bool
-	Score   float64
-	Message string
+	Name    string  `json:"name"`
+	Passed  bool    `json:"passed"`
+	Score   float64 `json:"score"`
+	Weight  float64 `json:"weight"`
+	Message string  `json:"message,omitempty"`
+	// Outcome 执行结果：passed/failed/timeout/skipped，DurationMs 为执行耗时
+	Outcome    string `json:"outcome"`
+	DurationMs int64  `json:"duration_ms"`
 }
 
 // LengthCheck 执行独立的长度检查逻辑。
@@ -189,6 +199,12 @@ func LengthCheck(ctx context.Context, input *QualityCheckInput, cfg *config.Qual
 		detail.Message = "question too short"
 		return detail, nil
 	}
+	if cfg.MaxQuestionLength > 0 && questionLen > cfg.MaxQuestionLength {
+		detail.Passed = false
+		detail.Score = 0.0
+		detail.Message = "question too long"
+		return detail, nil
+	}
 
 	// 检查答案长度
 	answerLen := len(strings.TrimSpace(input.Answer))
@@ -198,6 +214,12 @@ func LengthCheck(ctx context.Context, input *QualityCheckInput, cfg *config.Qual
 		detail.Message = "answer too short"
 		return detail, nil
 	}
+	if cfg.MaxAnswerLength > 0 && answerLen > cfg.MaxAnswerLength {
+		detail.Passed = false
+		detail.Score = 0.0
+		detail.Message = "answer too long"
+		return detail, nil
+	}
 
 	// 计算长度分数
 	qScore := min(float64(questionLen)/100.0, 1.0)
@@ -216,14 +238,10 @@ func BlacklistCheck(ctx context.Context, input *QualityCheckInput, blacklist []s
 		return detail, nil
 	}
 
-	text := strings.ToLower(input.Question + " " + input.Answer)
-	for _, word := range blacklist {
-		if strings.Contains(text, strings.ToLower(word)) {
-			detail.Passed = false
-			detail.Score = 0.0
-			detail.Message = "contains blacklisted word: " + word
-			return detail, nil
-		}
+	if containsBlacklistWords(input.Question, blacklist) || containsBlacklistWords(input.Answer, blacklist) {
+		detail.Passed = false
+		detail.Score = 0.0
+		detail.Message = "contains blacklisted content"
 	}
 
 	return detail, nil
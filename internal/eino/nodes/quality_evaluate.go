@@ -0,0 +1,88 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import "context"
+
+// RejectedByThreshold 综合分数低于阈值时的拒绝项名称
+const RejectedByThreshold = "score_threshold"
+
+// QualityEvaluation 定义质量检查试运行的结果（不写入缓存）。
+// Score 为加权综合分数，未通过时同样计算，便于对照阈值调整配置。
+type QualityEvaluation struct {
+	Passed     bool           `json:"passed"`
+	Score      float64        `json:"score"`
+	Threshold  float64        `json:"threshold"`
+	Reason     string         `json:"reason,omitempty"`
+	RejectedBy string         `json:"rejected_by,omitempty"`
+	Details    []*CheckDetail `json:"details,omitempty"`
+}
+
+// QualityBatchEvaluation 定义批量质量检查试运行的结果。
+// Reasons 按拒绝项（检查名称或 score_threshold）统计未通过的问答对数量。
+type QualityBatchEvaluation struct {
+	Total    int                  `json:"total"`
+	Passed   int                  `json:"passed"`
+	Rejected int                  `json:"rejected"`
+	Reasons  map[string]int       `json:"reasons"`
+	Results  []*QualityEvaluation `json:"results"`
+}
+
+// Evaluate 执行与存储流程相同的质量检查，返回是否通过、综合分数和各项明细。
+// 参数 ctx: 上下文对象。
+// 参数 input: 质量检查输入（ForceWrite 会被忽略）。
+// 返回: 试运行结果，策略执行失败时返回错误。
+func (c *QualityChecker) Evaluate(ctx context.Context, input *QualityCheckInput) (*QualityEvaluation, error) {
+	checkInput := *input
+	checkInput.ForceWrite = false
+	result, err := c.Check(ctx, &checkInput)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation := &QualityEvaluation{
+		Passed:    result.Passed,
+		Score:     result.Score,
+		Threshold: c.cfg.ScoreThreshold,
+		Reason:    result.Reason,
+		Details:   result.Details,
+	}
+	if len(result.Details) > 0 {
+		evaluation.Score = weightedScore(result.Details)
+	}
+	if !result.Passed {
+		evaluation.RejectedBy = RejectedByThreshold
+		for _, detail := range result.Details {
+			if !detail.Passed {
+				evaluation.RejectedBy = detail.Name
+				break
+			}
+		}
+	}
+	return evaluation, nil
+}
+
+// EvaluateBatch 依次试运行多个问答对的质量检查，并按拒绝项汇总。
+// 参数 ctx: 上下文对象。
+// 参数 inputs: 质量检查输入列表。
+// 返回: 批量试运行结果，任一问答对执行失败时返回错误。
+func (c *QualityChecker) EvaluateBatch(ctx context.Context, inputs []*QualityCheckInput) (*QualityBatchEvaluation, error) {
+	batch := &QualityBatchEvaluation{
+		Total:   len(inputs),
+		Reasons: make(map[string]int),
+		Results: make([]*QualityEvaluation, 0, len(inputs)),
+	}
+	for _, input := range inputs {
+		evaluation, err := c.Evaluate(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if evaluation.Passed {
+			batch.Passed++
+		} else {
+			batch.Rejected++
+			batch.Reasons[evaluation.RejectedBy]++
+		}
+		batch.Results = append(batch.Results, evaluation)
+	}
+	return batch, nil
+}
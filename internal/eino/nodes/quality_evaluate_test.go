@@ -0,0 +1,105 @@
+package nodes
+
+import (
+	"context"
+	"testing"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestQualityCheckerEvaluate(t *testing.T) {
+	cfg := &config.QualityConfig{
+		Enabled:           true,
+		MinQuestionLength: 5,
+		MinAnswerLength:   10,
+		MaxQuestionLength: 1000,
+		MaxAnswerLength:   10000,
+		ScoreThreshold:    0.9,
+		BlacklistKeywords: []string{"spam"},
+	}
+	checker := NewQualityChecker(cfg)
+
+	tests := []struct {
+		name           string
+		input          *QualityCheckInput
+		wantPassed     bool
+		wantRejectedBy string
+	}{
+		{
+			name:       "passes",
+			input:      &QualityCheckInput{Question: "What is machine learning?", Answer: "Machine learning is a subset of artificial intelligence."},
+			wantPassed: true,
+		},
+		{
+			name:           "length rejection",
+			input:          &QualityCheckInput{Question: "What is machine learning?", Answer: "short"},
+			wantRejectedBy: StrategyLength,
+		},
+		{
+			name:           "blacklist rejection",
+			input:          &QualityCheckInput{Question: "What is machine learning?", Answer: "This is spam content about machine learning."},
+			wantRejectedBy: StrategyBlacklist,
+		},
+		{
+			name:           "below threshold",
+			input:          &QualityCheckInput{Question: "define ML", Answer: "Machine learning is a subset of artificial intelligence."},
+			wantRejectedBy: RejectedByThreshold,
+		},
+		{
+			name:           "force write ignored",
+			input:          &QualityCheckInput{Question: "What is machine learning?", Answer: "short", ForceWrite: true},
+			wantRejectedBy: StrategyLength,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := checker.Evaluate(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Passed != tt.wantPassed {
+				t.Errorf("expected passed %v, got %v (%s)", tt.wantPassed, result.Passed, result.Reason)
+			}
+			if result.RejectedBy != tt.wantRejectedBy {
+				t.Errorf("expected rejected_by %q, got %q", tt.wantRejectedBy, result.RejectedBy)
+			}
+			if len(result.Details) == 0 {
+				t.Errorf("expected check details")
+			}
+			if result.Threshold != cfg.ScoreThreshold {
+				t.Errorf("expected threshold %v, got %v", cfg.ScoreThreshold, result.Threshold)
+			}
+		})
+	}
+}
+
+func TestQualityCheckerEvaluateBatch(t *testing.T) {
+	checker := NewQualityChecker(&config.QualityConfig{
+		Enabled:           true,
+		MinQuestionLength: 5,
+		MinAnswerLength:   10,
+		BlacklistKeywords: []string{"spam"},
+	})
+
+	inputs := []*QualityCheckInput{
+		{Question: "What is machine learning?", Answer: "Machine learning is a subset of artificial intelligence."},
+		{Question: "What is machine learning?", Answer: "short"},
+		{Question: "What is deep learning?", Answer: "tiny"},
+		{Question: "What is machine learning?", Answer: "This is spam content about machine learning."},
+	}
+
+	result, err := checker.EvaluateBatch(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Total != 4 || result.Passed != 1 || result.Rejected != 3 {
+		t.Errorf("expected 4/1/3, got %d/%d/%d", result.Total, result.Passed, result.Rejected)
+	}
+	if result.Reasons[StrategyLength] != 2 || result.Reasons[StrategyBlacklist] != 1 {
+		t.Errorf("unexpected reasons: %v", result.Reasons)
+	}
+	if len(result.Results) != len(inputs) {
+		t.Errorf("expected %d results, got %d", len(inputs), len(result.Results))
+	}
+}
@@ -0,0 +1,154 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// StrategyJudge LLM 评审策略名称
+const StrategyJudge = "judge"
+
+// 评审失败处理方式
+const (
+	JudgeFailOpen   = "open"
+	JudgeFailClosed = "closed"
+)
+
+// defaultJudgeRubric 默认的评审评分标准
+const defaultJudgeRubric = `你是问答质量评审员。请评估答案是否准确、完整地回答了问题，按以下标准打分（0-10 分）：
+- 9-10：直接、完整、准确地回答了问题
+- 6-8：回答了问题，但不够完整或有少量无关内容
+- 3-5：部分相关，但没有真正回答问题
+- 0-2：答非所问、拒绝回答、报错信息或明显错误
+仅输出 JSON，不要输出其他内容，格式为：{"score": <0-10 的数字>, "reasons": ["<理由>", ...]}`
+
+// JudgeVerdict 定义 LLM 评审的结构化结论。
+// Score 为换算到 0-1 的分数。
+type JudgeVerdict struct {
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// QualityJudge 实现基于 LLM 的问答质量评审。
+// 将问答对连同评分标准发送给 ChatModel，解析返回的分数和理由。
+type QualityJudge struct {
+	model model.BaseChatModel
+	cfg   *config.JudgeConfig
+}
+
+// NewQualityJudge 创建一个新的 LLM 评审器。
+// 参数 chatModel: 用于评审的 ChatModel。
+// 参数 cfg: 评审配置。
+// 返回: QualityJudge 指针。
+func NewQualityJudge(chatModel model.BaseChatModel, cfg *config.JudgeConfig) *QualityJudge {
+	return &QualityJudge{model: chatModel, cfg: cfg}
+}
+
+// Judge 评审问答对的质量。
+// 参数 ctx: 上下文对象（调用方负责设置超时）。
+// 参数 question: 问题。
+// 参数 answer: 答案。
+// 返回: 评审结论，调用失败或结果无法解析时返回错误。
+func (j *QualityJudge) Judge(ctx context.Context, question, answer string) (*JudgeVerdict, error) {
+	rubric := j.cfg.Rubric
+	if rubric == "" {
+		rubric = defaultJudgeRubric
+	}
+	messages := []*schema.Message{
+		schema.SystemMessage(rubric),
+		schema.UserMessage(fmt.Sprintf("问题：%s\n答案：%s", question, answer)),
+	}
+
+	resp, err := j.model.Generate(ctx, messages)
+	if err != nil {
+		return nil, fmt.Errorf("generate judge verdict: %w", err)
+	}
+	return parseJudgeVerdict(resp.Content)
+}
+
+// Passed 判断评审结论是否达到通过分数
+func (j *QualityJudge) Passed(verdict *JudgeVerdict) bool {
+	return verdict.Score >= j.cfg.Threshold
+}
+
+// FailOpen 判断评审失败时是否放行
+func (j *QualityJudge) FailOpen() bool {
+	return j.cfg.FailMode == JudgeFailOpen
+}
+
+// Strategy 将评审器包装为质量评估策略（权重为 0，仅作为准入检查）。
+// 评审失败或超时时按 FailMode 放行或拒绝。
+func (j *QualityJudge) Strategy() WeightedStrategy {
+	onTimeout := TimeoutFail
+	if j.FailOpen() {
+		onTimeout = TimeoutPass
+	}
+	return WeightedStrategy{Name: StrategyJudge, OnTimeout: onTimeout, Check: j.Check}
+}
+
+// Check 评审问答对并返回 CheckDetail
+func (j *QualityJudge) Check(ctx context.Context, input *QualityCheckInput) (*CheckDetail, error) {
+	verdict, err := j.Judge(ctx, input.Question, input.Answer)
+	if err != nil {
+		if j.FailOpen() {
+			return &CheckDetail{Passed: true, Score: 1.0, Message: fmt.Sprintf("judge failed, passed by fail mode: %v", err)}, nil
+		}
+		return &CheckDetail{Passed: false, Score: 0.0, Message: fmt.Sprintf("judge failed: %v", err)}, nil
+	}
+
+	reasons := strings.Join(verdict.Reasons, "; ")
+	detail := &CheckDetail{Passed: j.Passed(verdict), Score: verdict.Score, Message: reasons}
+	if !detail.Passed {
+		detail.Message = fmt.Sprintf("judge rejected: score %.2f", verdict.Score)
+		if reasons != "" {
+			detail.Message += ": " + reasons
+		}
+	}
+	return detail, nil
+}
+
+// parseJudgeVerdict 从模型输出中解析评审结论。
+// 兼容 Markdown 代码块包裹和 JSON 前后的多余文本，分数兼容数字和字符串形式。
+func parseJudgeVerdict(content string) (*JudgeVerdict, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in judge output: %q", content)
+	}
+
+	var raw struct {
+		Score   any      `json:"score"`
+		Reasons []string `json:"reasons"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal judge verdict: %w", err)
+	}
+
+	var score float64
+	switch v := raw.Score.(type) {
+	case float64:
+		score = v
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid judge score %q: %w", v, err)
+		}
+		score = parsed
+	default:
+		return nil, fmt.Errorf("missing judge score")
+	}
+	if score < 0 || score > 10 {
+		return nil, fmt.Errorf("judge score out of range: %v", score)
+	}
+
+	return &JudgeVerdict{Score: score / 10, Reasons: raw.Reasons}, nil
+}
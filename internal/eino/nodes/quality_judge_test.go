@@ -0,0 +1,86 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestParseJudgeVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected float64
+		wantErr  bool
+	}{
+		{"plain json", `{"score": 8, "reasons": ["answers the question"]}`, 0.8, false},
+		{"code fence", "```json\n{\"score\": 6.5, \"reasons\": []}\n```", 0.65, false},
+		{"string score", `评审结果：{"score": "9"}`, 0.9, false},
+		{"no json", "score: 8", 0, true},
+		{"missing score", `{"reasons": ["ok"]}`, 0, true},
+		{"out of range", `{"score": 42}`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, err := parseJudgeVerdict(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && verdict.Score != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, verdict.Score)
+			}
+		})
+	}
+}
+
+func TestQualityCheckerJudge(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      string
+		err        error
+		failMode   string
+		wantPassed bool
+		wantReason string
+	}{
+		{"judge passes", `{"score": 9, "reasons": ["complete"]}`, nil, JudgeFailClosed, true, ""},
+		{"judge rejects", `{"score": 2, "reasons": ["does not answer the question"]}`, nil, JudgeFailClosed, false, "judge rejected: score 0.20: does not answer the question"},
+		{"fail closed", "", errors.New("timeout"), JudgeFailClosed, false, "judge failed"},
+		{"fail open", "", errors.New("timeout"), JudgeFailOpen, true, ""},
+		{"unparsable fail closed", "looks good", nil, JudgeFailClosed, false, "judge failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.QualityConfig{
+				Enabled:        true,
+				ScoreThreshold: 0.5,
+				CheckTimeout:   time.Second,
+				Judge:          config.JudgeConfig{Enabled: true, Threshold: 0.6, FailMode: tt.failMode},
+			}
+			chatModel := &fakeChatModel{reply: tt.reply, err: tt.err}
+			checker := NewQualityChecker(cfg).WithJudge(NewQualityJudge(chatModel, &cfg.Judge))
+
+			result, err := checker.Check(context.Background(), &QualityCheckInput{
+				Question: "How do I reset my password?",
+				Answer:   "Open Settings, choose Security and click Reset Password.",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Passed != tt.wantPassed {
+				t.Errorf("expected passed %v, got %v (%s)", tt.wantPassed, result.Passed, result.Reason)
+			}
+			if !strings.HasPrefix(result.Reason, tt.wantReason) {
+				t.Errorf("expected reason %q, got %q", tt.wantReason, result.Reason)
+			}
+			if len(chatModel.input) != 2 || chatModel.input[0].Content != defaultJudgeRubric {
+				t.Errorf("expected rubric and Q&A messages, got %v", chatModel.input)
+			}
+		})
+	}
+}
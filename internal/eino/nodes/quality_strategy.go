@@ -0,0 +1,297 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"llm-cache/internal/eino/config"
+)
+
+// 内置质量评估策略名称
+const (
+	StrategyLength    = "length"
+	StrategyBlacklist = "blacklist"
+	StrategyRule      = "rule"
+)
+
+// 单项检查超时后的处理策略
+const (
+	TimeoutPass = "pass" // 视为通过（计满分）
+	TimeoutFail = "fail" // 视为未通过，拒绝写入
+	TimeoutSkip = "skip" // 跳过该项，不计入综合分数
+)
+
+// 单项检查的执行结果
+const (
+	OutcomePassed  = "passed"
+	OutcomeFailed  = "failed"
+	OutcomeTimeout = "timeout"
+	OutcomeSkipped = "skipped"
+)
+
+// StrategyFunc 定义质量评估策略的执行函数，返回单项检查结果。
+type StrategyFunc func(ctx context.Context, input *QualityCheckInput) (*CheckDetail, error)
+
+// StrategyFactory 根据质量检查配置和策略参数创建评估策略。
+type StrategyFactory func(cfg *config.QualityConfig, params map[string]any) (StrategyFunc, error)
+
+// WeightedStrategy 定义带权重的质量评估策略。
+// Timeout 和 OnTimeout 为空时使用 QualityConfig 的 CheckTimeout 和 TimeoutPolicy。
+type WeightedStrategy struct {
+	Name      string
+	Weight    float64
+	Timeout   time.Duration
+	OnTimeout string
+	Check     StrategyFunc
+}
+
+var (
+	strategyMu       sync.RWMutex
+	strategyRegistry = map[string]StrategyFactory{
+		StrategyLength:    newLengthStrategy,
+		StrategyBlacklist: newBlacklistStrategy,
+		StrategyRule:      newRuleStrategy,
+		StrategyRefusal:   newRefusalStrategy,
+	}
+)
+
+// RegisterQualityStrategy 注册质量评估策略（同名策略会被覆盖）。
+// 参数 name: 策略名称，对应配置中的 strategies[].name。
+// 参数 factory: 策略工厂函数。
+func RegisterQualityStrategy(name string, factory StrategyFactory) {
+	strategyMu.Lock()
+	defer strategyMu.Unlock()
+	strategyRegistry[name] = factory
+}
+
+// NewQualityStrategies 根据配置创建启用的加权评估策略。
+// 未配置策略时返回内置的默认策略。
+// 参数 cfg: 质量检查配置。
+// 返回: 加权策略列表，策略未注册、权重为负或创建失败时返回错误。
+func NewQualityStrategies(cfg *config.QualityConfig) ([]WeightedStrategy, error) {
+	if len(cfg.Strategies) == 0 {
+		return DefaultQualityStrategies(cfg), nil
+	}
+
+	strategyMu.RLock()
+	defer strategyMu.RUnlock()
+
+	strategies := make([]WeightedStrategy, 0, len(cfg.Strategies))
+	for _, spec := range cfg.Strategies {
+		if !spec.Enabled {
+			continue
+		}
+		factory, ok := strategyRegistry[spec.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown quality strategy %q (available: %v)", spec.Name, registeredStrategies())
+		}
+		if spec.Weight < 0 {
+			return nil, fmt.Errorf("quality strategy %s has negative weight", spec.Name)
+		}
+		if !validTimeoutPolicy(spec.OnTimeout) {
+			return nil, fmt.Errorf("quality strategy %s has invalid on_timeout %q", spec.Name, spec.OnTimeout)
+		}
+		check, err := factory(cfg, spec.Config)
+		if err != nil {
+			return nil, fmt.Errorf("create quality strategy %s: %w", spec.Name, err)
+		}
+		strategies = append(strategies, WeightedStrategy{
+			Name:      spec.Name,
+			Weight:    spec.Weight,
+			Timeout:   spec.Timeout,
+			OnTimeout: spec.OnTimeout,
+			Check:     check,
+		})
+	}
+	return strategies, nil
+}
+
+// DefaultQualityStrategies 返回内置的默认策略。
+// 长度、黑名单和拒答检测仅作为准入检查（权重为 0），综合分数完全来自规则评分。
+func DefaultQualityStrategies(cfg *config.QualityConfig) []WeightedStrategy {
+	length, _ := newLengthStrategy(cfg, nil)
+	blacklist, _ := newBlacklistStrategy(cfg, nil)
+	rule, _ := newRuleStrategy(cfg, nil)
+	strategies := []WeightedStrategy{
+		{Name: StrategyLength, Weight: 0, Check: length},
+		{Name: StrategyBlacklist, Weight: 0, Check: blacklist},
+	}
+	if cfg.Refusal.Enabled {
+		refusal, _ := newRefusalStrategy(cfg, nil)
+		strategies = append(strategies, WeightedStrategy{Name: StrategyRefusal, Weight: 0, Check: refusal})
+	}
+	return append(strategies, WeightedStrategy{Name: StrategyRule, Weight: 1, Check: rule})
+}
+
+// runStrategies 在有界工作池中并发执行评估策略，每项检查使用独立的超时。
+// 返回的明细与策略顺序一致；策略返回错误时整体返回错误，超时按超时策略处理。
+func (c *QualityChecker) runStrategies(ctx context.Context, strategies []WeightedStrategy, input *QualityCheckInput) ([]*CheckDetail, error) {
+	workers := c.cfg.ParallelWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	details := make([]*CheckDetail, len(strategies))
+	errs := make([]error, len(strategies))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, strategy := range strategies {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, strategy WeightedStrategy) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			details[i], errs[i] = c.runStrategy(ctx, strategy, input)
+		}(i, strategy)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("quality strategy %s: %w", strategies[i].Name, err)
+		}
+	}
+	return details, nil
+}
+
+// runStrategy 执行单项策略并记录耗时和结果
+func (c *QualityChecker) runStrategy(ctx context.Context, strategy WeightedStrategy, input *QualityCheckInput) (*CheckDetail, error) {
+	timeout := strategy.Timeout
+	if timeout <= 0 {
+		timeout = c.cfg.CheckTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type checkResult struct {
+		detail *CheckDetail
+		err    error
+	}
+	done := make(chan checkResult, 1)
+	start := time.Now()
+	go func() {
+		detail, err := strategy.Check(ctx, input)
+		done <- checkResult{detail, err}
+	}()
+
+	var detail *CheckDetail
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, r.err
+		}
+		detail = r.detail
+		detail.Outcome = OutcomePassed
+		if !detail.Passed {
+			detail.Outcome = OutcomeFailed
+		}
+	case <-ctx.Done():
+		detail = c.timeoutDetail(strategy, timeout)
+	}
+
+	detail.Name = strategy.Name
+	detail.Weight = strategy.Weight
+	detail.DurationMs = time.Since(start).Milliseconds()
+	return detail, nil
+}
+
+// timeoutDetail 按超时策略生成超时检查的结果
+func (c *QualityChecker) timeoutDetail(strategy WeightedStrategy, timeout time.Duration) *CheckDetail {
+	policy := strategy.OnTimeout
+	if policy == "" {
+		policy = c.cfg.TimeoutPolicy
+	}
+
+	switch policy {
+	case TimeoutPass:
+		return &CheckDetail{Passed: true, Score: 1.0, Outcome: OutcomeTimeout, Message: fmt.Sprintf("timed out after %s, passed by policy", timeout)}
+	case TimeoutFail:
+		return &CheckDetail{Passed: false, Score: 0.0, Outcome: OutcomeTimeout, Message: fmt.Sprintf("quality check %s timed out after %s", strategy.Name, timeout)}
+	default:
+		return &CheckDetail{Passed: true, Outcome: OutcomeSkipped, Message: fmt.Sprintf("timed out after %s, skipped", timeout)}
+	}
+}
+
+// validTimeoutPolicy 判断超时策略是否有效（空字符串表示使用全局策略）
+func validTimeoutPolicy(policy string) bool {
+	switch policy {
+	case "", TimeoutPass, TimeoutFail, TimeoutSkip:
+		return true
+	default:
+		return false
+	}
+}
+
+// weightedScore 计算各项检查的加权平均分数（跳过的检查不计入，总权重为 0 时返回 1）
+func weightedScore(details []*CheckDetail) float64 {
+	var sum, total float64
+	for _, detail := range details {
+		if detail.Outcome == OutcomeSkipped {
+			continue
+		}
+		sum += detail.Score * detail.Weight
+		total += detail.Weight
+	}
+	if total == 0 {
+		return 1.0
+	}
+	return sum / total
+}
+
+// registeredStrategies 返回已注册的策略名称（调用方需持有读锁）
+func registeredStrategies() []string {
+	names := make([]string, 0, len(strategyRegistry))
+	for name := range strategyRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newLengthStrategy 创建长度检查策略
+func newLengthStrategy(cfg *config.QualityConfig, _ map[string]any) (StrategyFunc, error) {
+	return func(ctx context.Context, input *QualityCheckInput) (*CheckDetail, error) {
+		return LengthCheck(ctx, input, cfg)
+	}, nil
+}
+
+// newBlacklistStrategy 创建黑名单检查策略。
+// 参数 keywords 中的关键词会追加到全局黑名单之后。
+func newBlacklistStrategy(cfg *config.QualityConfig, params map[string]any) (StrategyFunc, error) {
+	blacklist := cfg.BlacklistKeywords
+	if raw, ok := params["keywords"]; ok {
+		items, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("keywords must be a list")
+		}
+		blacklist = append([]string{}, blacklist...)
+		for _, item := range items {
+			word, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("keywords must be strings")
+			}
+			blacklist = append(blacklist, word)
+		}
+	}
+	return func(ctx context.Context, input *QualityCheckInput) (*CheckDetail, error) {
+		return BlacklistCheck(ctx, input, blacklist)
+	}, nil
+}
+
+// newRuleStrategy 创建规则评分策略（基于长度适宜性和问题完整性打分，不直接拒绝）
+func newRuleStrategy(_ *config.QualityConfig, _ map[string]any) (StrategyFunc, error) {
+	return func(ctx context.Context, input *QualityCheckInput) (*CheckDetail, error) {
+		return &CheckDetail{
+			Name:   StrategyRule,
+			Passed: true,
+			Score:  calculateQualityScore(input.Question, input.Answer),
+		}, nil
+	}, nil
+}
@@ -0,0 +1,224 @@
+package nodes
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestNewQualityStrategies(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategies []config.QualityStrategy
+		wantNames  []string
+		wantErr    bool
+	}{
+		{"defaults", nil, []string{StrategyLength, StrategyBlacklist, StrategyRule}, false},
+		{
+			name: "disabled strategies skipped",
+			strategies: []config.QualityStrategy{
+				{Name: StrategyLength, Weight: 0.3, Enabled: true},
+				{Name: StrategyRule, Weight: 0.7, Enabled: false},
+			},
+			wantNames: []string{StrategyLength},
+		},
+		{"unknown strategy", []config.QualityStrategy{{Name: "format", Weight: 1, Enabled: true}}, nil, true},
+		{"negative weight", []config.QualityStrategy{{Name: StrategyRule, Weight: -1, Enabled: true}}, nil, true},
+		{
+			name:       "invalid params",
+			strategies: []config.QualityStrategy{{Name: StrategyBlacklist, Weight: 1, Enabled: true, Config: map[string]interface{}{"keywords": "spam"}}},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategies, err := NewQualityStrategies(&config.QualityConfig{Strategies: tt.strategies})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(strategies) != len(tt.wantNames) {
+				t.Fatalf("expected %d strategies, got %d", len(tt.wantNames), len(strategies))
+			}
+			for i, name := range tt.wantNames {
+				if strategies[i].Name != name {
+					t.Errorf("expected %s, got %s", name, strategies[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestQualityCheckerWeightedStrategies(t *testing.T) {
+	RegisterQualityStrategy("fixed", func(_ *config.QualityConfig, params map[string]any) (StrategyFunc, error) {
+		score, _ := params["score"].(float64)
+		return func(ctx context.Context, input *QualityCheckInput) (*CheckDetail, error) {
+			return &CheckDetail{Passed: true, Score: score}, nil
+		}, nil
+	})
+
+	tests := []struct {
+		name       string
+		strategies []config.QualityStrategy
+		wantPassed bool
+		wantScore  float64
+		wantReason string
+	}{
+		{
+			name: "weighted score above threshold",
+			strategies: []config.QualityStrategy{
+				{Name: "fixed", Weight: 3, Enabled: true, Config: map[string]interface{}{"score": 1.0}},
+				{Name: "fixed", Weight: 1, Enabled: true, Config: map[string]interface{}{"score": 0.2}},
+			},
+			wantPassed: true,
+			wantScore:  0.8,
+		},
+		{
+			name: "weighted score below threshold",
+			strategies: []config.QualityStrategy{
+				{Name: "fixed", Weight: 1, Enabled: true, Config: map[string]interface{}{"score": 1.0}},
+				{Name: "fixed", Weight: 3, Enabled: true, Config: map[string]interface{}{"score": 0.2}},
+			},
+			wantPassed: false,
+			wantScore:  0.4,
+			wantReason: "quality score below threshold",
+		},
+		{
+			name: "failed strategy rejects regardless of weight",
+			strategies: []config.QualityStrategy{
+				{Name: "fixed", Weight: 1, Enabled: true, Config: map[string]interface{}{"score": 1.0}},
+				{Name: StrategyBlacklist, Weight: 0, Enabled: true, Config: map[string]interface{}{"keywords": []interface{}{"refund"}}},
+			},
+			wantPassed: false,
+			wantScore:  0,
+			wantReason: "contains blacklisted content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.QualityConfig{Enabled: true, ScoreThreshold: 0.5, Strategies: tt.strategies}
+			strategies, err := NewQualityStrategies(cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			result, err := NewQualityChecker(cfg).WithStrategies(strategies).Check(context.Background(), &QualityCheckInput{
+				Question: "How do I request a refund?",
+				Answer:   "Open your orders page and choose Request Refund.",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Passed != tt.wantPassed {
+				t.Errorf("expected passed %v, got %v (%s)", tt.wantPassed, result.Passed, result.Reason)
+			}
+			if diff := result.Score - tt.wantScore; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("expected score %v, got %v", tt.wantScore, result.Score)
+			}
+			if result.Reason != tt.wantReason {
+				t.Errorf("expected reason %q, got %q", tt.wantReason, result.Reason)
+			}
+			if len(result.Details) != len(tt.strategies) {
+				t.Errorf("expected %d details, got %d", len(tt.strategies), len(result.Details))
+			}
+		})
+	}
+}
+
+func TestQualityCheckerTimeoutPolicies(t *testing.T) {
+	slow := func(ctx context.Context, input *QualityCheckInput) (*CheckDetail, error) {
+		<-ctx.Done()
+		return &CheckDetail{Passed: true, Score: 1.0}, nil
+	}
+	fixed := func(ctx context.Context, input *QualityCheckInput) (*CheckDetail, error) {
+		return &CheckDetail{Passed: true, Score: 0.4}, nil
+	}
+
+	tests := []struct {
+		name        string
+		onTimeout   string
+		wantPassed  bool
+		wantScore   float64
+		wantOutcome string
+	}{
+		{"pass counts full score", TimeoutPass, true, 0.7, OutcomeTimeout},
+		{"fail rejects", TimeoutFail, false, 0, OutcomeTimeout},
+		{"skip excludes from score", TimeoutSkip, false, 0.4, OutcomeSkipped},
+		{"global policy", "", false, 0.4, OutcomeSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.QualityConfig{
+				Enabled:         true,
+				ScoreThreshold:  0.5,
+				ParallelWorkers: 2,
+				CheckTimeout:    20 * time.Millisecond,
+				TimeoutPolicy:   TimeoutSkip,
+			}
+			checker := NewQualityChecker(cfg).WithStrategies([]WeightedStrategy{
+				{Name: "fixed", Weight: 1, Check: fixed},
+				{Name: "slow", Weight: 1, OnTimeout: tt.onTimeout, Check: slow},
+			})
+
+			result, err := checker.Check(context.Background(), &QualityCheckInput{Question: "q", Answer: "a"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Passed != tt.wantPassed {
+				t.Errorf("expected passed %v, got %v (%s)", tt.wantPassed, result.Passed, result.Reason)
+			}
+			if diff := result.Score - tt.wantScore; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("expected score %v, got %v", tt.wantScore, result.Score)
+			}
+			if got := result.Details[1].Outcome; got != tt.wantOutcome {
+				t.Errorf("expected outcome %s, got %s", tt.wantOutcome, got)
+			}
+			if result.Details[0].Outcome != OutcomePassed {
+				t.Errorf("expected outcome %s, got %s", OutcomePassed, result.Details[0].Outcome)
+			}
+		})
+	}
+}
+
+func TestQualityCheckerRunsStrategiesConcurrently(t *testing.T) {
+	var running, peak int32
+	check := func(ctx context.Context, input *QualityCheckInput) (*CheckDetail, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return &CheckDetail{Passed: true, Score: 1.0}, nil
+	}
+
+	strategies := make([]WeightedStrategy, 6)
+	for i := range strategies {
+		strategies[i] = WeightedStrategy{Name: "sleep", Weight: 1, Check: check}
+	}
+	cfg := &config.QualityConfig{Enabled: true, ParallelWorkers: 3, CheckTimeout: time.Second}
+
+	result, err := NewQualityChecker(cfg).WithStrategies(strategies).Check(context.Background(), &QualityCheckInput{Question: "q", Answer: "a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Passed || len(result.Details) != len(strategies) {
+		t.Fatalf("expected all %d checks to pass, got %+v", len(strategies), result)
+	}
+	if peak != 3 {
+		t.Errorf("expected 3 concurrent checks, got %d", peak)
+	}
+	for _, detail := range result.Details {
+		if detail.DurationMs < 20 {
+			t.Errorf("expected duration >= 20ms, got %d", detail.DurationMs)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"llm-cache/internal/eino/config"
+)
+
+// StrategyRefusal 拒答/报错检测策略名称
+const StrategyRefusal = "refusal"
+
+// 拒答检测的位置权重：拒答语通常出现在答案开头，出现在长答案中部时多为客套话
+const (
+	refusalLeadingRunes = 60  // 开头窗口（字符数）
+	refusalShortRunes   = 200 // 短答案长度上限（字符数）
+
+	refusalLeadingFactor = 1.0
+	refusalShortFactor   = 0.6
+	refusalBodyFactor    = 0.3
+
+	// 报错内容占答案（非空白字符）的比例达到该值时视为报错转储
+	errorDumpCoverage = 0.5
+)
+
+// patternPosition 特征命中位置的计分方式
+type patternPosition int
+
+const (
+	// positionDecay 按出现位置衰减权重（拒答语）
+	positionDecay patternPosition = iota
+	// positionDump 仅当报错内容位于答案开头或占答案大部分时计满权重，否则不计（解释报错的正常答案会引用报错信息）
+	positionDump
+	// positionAny 不论位置计满权重（配置的错误关键词）
+	positionAny
+)
+
+// refusalPattern 定义一个带权重的拒答/报错特征
+type refusalPattern struct {
+	re       *regexp.Regexp
+	weight   float64
+	position patternPosition
+}
+
+// stackFramePattern 堆栈帧等报错转储的续行（计入报错内容占比）
+var stackFramePattern = regexp.MustCompile(`(?m)^(?:\s+File ".+", line \d+.*|\s+at .+|\t.+\.go:\d+.*|[\w.]+\(.*\)|goroutine \d+ \[.*|[\w.]*(?:Error|Exception)(?::.*)?)$`)
+
+// builtinRefusalPatterns 内置的多语言拒答与道歉特征
+var builtinRefusalPatterns = []refusalPattern{
+	{regexp.MustCompile(`(?i)\bI(?:'m| am) (?:sorry|afraid)\b`), 0.4, positionDecay},
+	{regexp.MustCompile(`(?i)\bI apologi[sz]e\b`), 0.3, positionDecay},
+	{regexp.MustCompile(`(?i)\bI (?:can(?:no|')t|cannot|am unable to|'m unable to|won't be able to) (?:help|assist|provide|answer|comply|do that)`), 0.7, positionDecay},
+	{regexp.MustCompile(`(?i)\bas an AI(?: language model| assistant)?\b`), 0.6, positionDecay},
+	{regexp.MustCompile(`作为一?个?(?:AI|人工智能)(?:语言模型|助手)?`), 0.6, positionDecay},
+	{regexp.MustCompile(`(?:很|非常)?抱歉|对不起`), 0.4, positionDecay},
+	{regexp.MustCompile(`我(?:无法|不能)(?:回答|提供|帮助|协助|满足)`), 0.6, positionDecay},
+	{regexp.MustCompile(`申し訳(?:ありません|ございません)`), 0.4, positionDecay},
+	{regexp.MustCompile(`お答えできません|お手伝いできません`), 0.6, positionDecay},
+}
+
+// builtinErrorPatterns 内置的报错内容特征（堆栈、限流和上游接口错误），仅在报错内容位于答案开头或占答案大部分时计入
+var builtinErrorPatterns = []refusalPattern{
+	{regexp.MustCompile(`Traceback \(most recent call last\)`), 1.0, positionDump},
+	{regexp.MustCompile(`(?m)^goroutine \d+ \[`), 1.0, positionDump},
+	{regexp.MustCompile(`(?m)^panic: `), 0.6, positionDump},
+	{regexp.MustCompile(`(?m)^\s+at [\w$.<>]+\([\w$]+\.(?:java|kt|scala):\d+\)`), 1.0, positionDump},
+	{regexp.MustCompile(`Exception in thread "`), 1.0, positionDump},
+	{regexp.MustCompile(`(?i)rate limit (?:exceeded|reached)`), 1.0, positionDump},
+	{regexp.MustCompile(`(?i)\b429 Too Many Requests\b`), 1.0, positionDump},
+	{regexp.MustCompile(`(?i)\binsufficient_quota\b|\bcontext_length_exceeded\b`), 1.0, positionDump},
+	{regexp.MustCompile(`(?i)\binternal server error\b`), 0.8, positionDump},
+	{regexp.MustCompile(`(?i)"error"\s*:\s*\{`), 0.8, positionDump},
+	{regexp.MustCompile(`(?i)\b(?:request|connection) timed? ?out\b`), 0.6, positionDump},
+}
+
+// configKeywordWeight 配置的道歉/错误关键词的权重：单个关键词即可判定，道歉关键词按位置衰减，错误关键词不论位置
+const configKeywordWeight = 1.0
+
+// RefusalDetector 实现拒答和报错答案的检测。
+// 分别累加命中的拒答特征和报错特征的权重，任一类达到阈值即判定答案不可缓存。
+type RefusalDetector struct {
+	threshold float64
+	refusals  []refusalPattern
+	errors    []refusalPattern
+}
+
+// NewRefusalDetector 创建一个新的拒答检测器。
+// 参数 cfg: 拒答检测配置（ApologyKeywords、ErrorKeywords 作为额外特征）。
+// 返回: RefusalDetector 指针。
+func NewRefusalDetector(cfg *config.RefusalConfig) *RefusalDetector {
+	d := &RefusalDetector{
+		threshold: cfg.Threshold,
+		refusals:  append([]refusalPattern{}, builtinRefusalPatterns...),
+		errors:    append([]refusalPattern{}, builtinErrorPatterns...),
+	}
+	for _, keyword := range cfg.ApologyKeywords {
+		if keyword != "" {
+			d.refusals = append(d.refusals, refusalPattern{regexp.MustCompile(`(?i)` + regexp.QuoteMeta(keyword)), configKeywordWeight, positionDecay})
+		}
+	}
+	for _, keyword := range cfg.ErrorKeywords {
+		if keyword != "" {
+			d.errors = append(d.errors, refusalPattern{regexp.MustCompile(`(?i)` + regexp.QuoteMeta(keyword)), configKeywordWeight, positionAny})
+		}
+	}
+	return d
+}
+
+// Check 检测答案是否为拒答或报错内容，返回 CheckDetail。
+// 未通过时 Message 为专门的拒绝原因，Score 为 1 减去较高的一类命中分数。
+func (d *RefusalDetector) Check(ctx context.Context, input *QualityCheckInput) (*CheckDetail, error) {
+	detail := &CheckDetail{Name: StrategyRefusal, Passed: true, Score: 1.0}
+
+	errorScore, errorMatch := matchScore(d.errors, input.Answer)
+	refusalScore, refusalMatch := matchScore(d.refusals, input.Answer)
+	detail.Score = 1.0 - max(errorScore, refusalScore)
+
+	switch {
+	case errorScore >= d.threshold:
+		detail.Passed = false
+		detail.Message = fmt.Sprintf("answer looks like an error response: %q", errorMatch)
+	case refusalScore >= d.threshold:
+		detail.Passed = false
+		detail.Message = fmt.Sprintf("answer looks like a refusal: %q", refusalMatch)
+	}
+	return detail, nil
+}
+
+// matchScore 累加命中特征的权重（上限为 1），返回分数和权重最高的命中文本
+func matchScore(patterns []refusalPattern, text string) (float64, string) {
+	totalRunes := utf8.RuneCountInString(text)
+	dump := -1 // 报错内容是否占答案大部分（-1 表示尚未计算）
+
+	var score, best float64
+	var bestMatch string
+	for _, p := range patterns {
+		loc := p.re.FindStringIndex(text)
+		if loc == nil {
+			continue
+		}
+
+		weight := p.weight
+		switch p.position {
+		case positionDecay:
+			weight *= positionFactor(utf8.RuneCountInString(text[:loc[0]]), totalRunes)
+		case positionDump:
+			if !leadingMatch(text[:loc[0]]) {
+				if dump < 0 {
+					dump = 0
+					if errorCoverage(patterns, text) >= errorDumpCoverage {
+						dump = 1
+					}
+				}
+				weight *= float64(dump)
+			}
+		}
+		if weight == 0 {
+			continue
+		}
+		score += weight
+		if weight > best {
+			best = weight
+			bestMatch = text[loc[0]:loc[1]]
+		}
+	}
+
+	if score > 1 {
+		score = 1
+	}
+	return score, bestMatch
+}
+
+// leadingMatch 判断命中是否位于答案开头（之前只有空白、标点和符号，如代码块标记或 JSON 括号）
+func leadingMatch(prefix string) bool {
+	return strings.IndexFunc(prefix, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0
+}
+
+// errorCoverage 计算报错特征和堆栈帧覆盖的非空白字符占答案非空白字符的比例
+func errorCoverage(patterns []refusalPattern, text string) float64 {
+	covered := make([]bool, len(text))
+	mark := func(locs [][]int) {
+		for _, loc := range locs {
+			for i := loc[0]; i < loc[1]; i++ {
+				covered[i] = true
+			}
+		}
+	}
+	for _, p := range patterns {
+		if p.position == positionDump {
+			mark(p.re.FindAllStringIndex(text, -1))
+		}
+	}
+	mark(stackFramePattern.FindAllStringIndex(text, -1))
+
+	var total, dump int
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if covered[i] {
+			dump++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(dump) / float64(total)
+}
+
+// positionFactor 根据命中位置计算权重系数
+func positionFactor(offset, totalRunes int) float64 {
+	switch {
+	case offset <= refusalLeadingRunes:
+		return refusalLeadingFactor
+	case totalRunes <= refusalShortRunes:
+		return refusalShortFactor
+	default:
+		return refusalBodyFactor
+	}
+}
+
+// newRefusalStrategy 创建拒答/报错检测策略
+func newRefusalStrategy(cfg *config.QualityConfig, _ map[string]any) (StrategyFunc, error) {
+	return NewRefusalDetector(&cfg.Refusal).Check, nil
+}
@@ -0,0 +1,90 @@
+package nodes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestRefusalDetectorCheck(t *testing.T) {
+	detector := NewRefusalDetector(&config.RefusalConfig{
+		Enabled:         true,
+		Threshold:       0.6,
+		ApologyKeywords: []string{"超出了我的能力范围"},
+		ErrorKeywords:   []string{"upstream_unavailable"},
+	})
+
+	tests := []struct {
+		name       string
+		answer     string
+		wantPassed bool
+		wantReason string
+	}{
+		{"normal answer", "Open Settings, choose Security and click Reset Password.", true, ""},
+		{"english refusal", "I'm sorry, but I can't help with that request.", false, "answer looks like a refusal"},
+		{"chinese refusal", "作为一个AI语言模型，我没有个人观点。", false, "answer looks like a refusal"},
+		{"chinese apology and inability", "很抱歉，我无法回答这个问题。", false, "answer looks like a refusal"},
+		{"japanese refusal", "申し訳ありませんが、その質問にはお答えできません。", false, "answer looks like a refusal"},
+		{"configured keyword with apology", "抱歉，这个问题超出了我的能力范围，请咨询专业人士。", false, "answer looks like a refusal"},
+		{"apology alone", "抱歉让你久等了，退款已经在处理中，预计三个工作日到账。", true, ""},
+		{
+			name:       "apology deep in long answer",
+			answer:     strings.Repeat("Rotate the key in the console, then update every client with the new value. ", 5) + "I'm sorry, I cannot provide the old key.",
+			wantPassed: true,
+		},
+		{"python traceback", "Traceback (most recent call last):\n  File \"app.py\", line 3, in <module>", false, "answer looks like an error response"},
+		{"go panic", "panic: runtime error\n\ngoroutine 1 [running]:\nmain.main()", false, "answer looks like an error response"},
+		{"java stack trace", "java.lang.NullPointerException\n\tat com.example.App.main(App.java:12)", false, "answer looks like an error response"},
+		{"rate limit", "Error: Rate limit exceeded, please retry later.", false, "answer looks like an error response"},
+		{"json error body", `{"error": {"code": 500, "message": "internal server error"}}`, false, "answer looks like an error response"},
+		{"traceback after a short label", "The job failed:\nTraceback (most recent call last):\n  File \"app.py\", line 3, in <module>\nValueError: bad input", false, "answer looks like an error response"},
+		{"configured apology keyword alone", "这个问题超出了我的能力范围。", false, "answer looks like a refusal"},
+		{"configured error keyword in body", strings.Repeat("Retry the request with the same payload. ", 8) + "upstream_unavailable", false, "answer looks like an error response"},
+		{"explains an http error", "A 500 Internal Server Error means the server hit an unexpected condition; check the server logs for the root cause.", true, ""},
+		{"explains rate limiting", "When the API responds with \"rate limit exceeded\", back off exponentially and retry after the Retry-After header.", true, ""},
+		{
+			name: "python debugging answer quoting a traceback",
+			answer: "This happens because the list is empty when you index it. The interpreter reports:\n\n```\nTraceback (most recent call last):\n" +
+				"  File \"app.py\", line 3, in <module>\n    print(items[0])\nIndexError: list index out of range\n```\n\n" +
+				"Check that the list is not empty before indexing, for example with `if items:`, or use `next(iter(items), None)` to get a default value.",
+			wantPassed: true,
+		},
+		{"chinese technical limitation", "该接口无法提供事务保证，需要在调用方自行实现补偿逻辑。", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail, err := detector.Check(context.Background(), &QualityCheckInput{Answer: tt.answer})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if detail.Passed != tt.wantPassed {
+				t.Errorf("expected passed %v, got %v (%s, score %.2f)", tt.wantPassed, detail.Passed, detail.Message, detail.Score)
+			}
+			if !strings.HasPrefix(detail.Message, tt.wantReason) {
+				t.Errorf("expected reason %q, got %q", tt.wantReason, detail.Message)
+			}
+		})
+	}
+}
+
+func TestQualityCheckerRejectsRefusal(t *testing.T) {
+	cfg := &config.QualityConfig{
+		Enabled:        true,
+		ScoreThreshold: 0.3,
+		Refusal:        config.RefusalConfig{Enabled: true, Threshold: 0.6},
+	}
+
+	result, err := NewQualityChecker(cfg).Check(context.Background(), &QualityCheckInput{
+		Question: "How do I pick a lock?",
+		Answer:   "I'm sorry, but I can't assist with that.",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Passed || !strings.HasPrefix(result.Reason, "answer looks like a refusal") {
+		t.Errorf("expected refusal rejection, got passed=%v reason=%q", result.Passed, result.Reason)
+	}
+}
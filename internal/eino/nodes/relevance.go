@@ -0,0 +1,42 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"fmt"
+
+	"llm-cache/internal/eino/components"
+)
+
+// MetaRelevanceScore 问题与答案向量的余弦相似度（仅启用相关性检查时存在）
+const MetaRelevanceScore = "relevance_score"
+
+// relevanceAnswerRunes 参与相关性检查的答案前缀长度（字符数）。
+// 答案开头通常直接回应问题，截断可以避免长答案稀释向量。
+const relevanceAnswerRunes = 512
+
+// RelevanceEnabled 判断是否需要检查问题与答案的语义相关性。
+// 质量检查被禁用、强制写入或阈值未配置时跳过。
+func (c *QualityChecker) RelevanceEnabled(forceWrite bool) bool {
+	return c.cfg.Enabled && !forceWrite && c.cfg.SemanticRelevanceThreshold > 0
+}
+
+// AnswerChunk 返回参与相关性检查的答案前缀
+func AnswerChunk(answer string) string {
+	runes := []rune(answer)
+	if len(runes) <= relevanceAnswerRunes {
+		return answer
+	}
+	return string(runes[:relevanceAnswerRunes])
+}
+
+// CheckRelevance 根据问题和答案向量的余弦相似度判断问答对是否相关。
+// 参数 questionVector: 问题向量。
+// 参数 answerVector: 答案（前缀）向量。
+// 返回: 相似度分数、是否通过以及未通过时的原因。
+func (c *QualityChecker) CheckRelevance(questionVector, answerVector []float64) (float64, bool, string) {
+	score := components.CosineSimilarity(questionVector, answerVector)
+	if score < c.cfg.SemanticRelevanceThreshold {
+		return score, false, fmt.Sprintf("answer not relevant to question: similarity %.2f below %.2f", score, c.cfg.SemanticRelevanceThreshold)
+	}
+	return score, true, ""
+}
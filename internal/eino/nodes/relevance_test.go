@@ -0,0 +1,66 @@
+package nodes
+
+import (
+	"strings"
+	"testing"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestQualityCheckerRelevance(t *testing.T) {
+	checker := NewQualityChecker(&config.QualityConfig{Enabled: true, SemanticRelevanceThreshold: 0.3})
+
+	tests := []struct {
+		name       string
+		question   []float64
+		answer     []float64
+		wantPassed bool
+	}{
+		{"same direction", []float64{1, 0}, []float64{1, 0.1}, true},
+		{"orthogonal", []float64{1, 0}, []float64{0, 1}, false},
+		{"dimension mismatch", []float64{1, 0}, []float64{1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, passed, reason := checker.CheckRelevance(tt.question, tt.answer)
+			if passed != tt.wantPassed {
+				t.Errorf("expected %v, got %v (%s)", tt.wantPassed, passed, reason)
+			}
+		})
+	}
+}
+
+func TestQualityCheckerRelevanceEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.QualityConfig
+		forceWrite bool
+		expected   bool
+	}{
+		{"enabled", config.QualityConfig{Enabled: true, SemanticRelevanceThreshold: 0.3}, false, true},
+		{"force write", config.QualityConfig{Enabled: true, SemanticRelevanceThreshold: 0.3}, true, false},
+		{"quality disabled", config.QualityConfig{Enabled: false, SemanticRelevanceThreshold: 0.3}, false, false},
+		{"zero threshold", config.QualityConfig{Enabled: true}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewQualityChecker(&tt.cfg).RelevanceEnabled(tt.forceWrite); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAnswerChunk(t *testing.T) {
+	short := "答案很短"
+	if got := AnswerChunk(short); got != short {
+		t.Errorf("expected %q, got %q", short, got)
+	}
+
+	long := strings.Repeat("长", relevanceAnswerRunes+10)
+	if got := []rune(AnswerChunk(long)); len(got) != relevanceAnswerRunes {
+		t.Errorf("expected %d runes, got %d", relevanceAnswerRunes, len(got))
+	}
+}
@@ -0,0 +1,35 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/components"
+)
+
+// ApplyRerankScores 将重排序分数作为文档的最终分数，并按阈值过滤。
+// 原向量相似度保留在 MetaData["vector_score"]，重排序分数写入 MetaData["score"]，
+// 以便后续的结果选择节点基于重排序结果工作。
+// 参数 docs: 已重排序的文档列表（需包含 rerank_score 元数据）。
+// 参数 threshold: 重排序分数阈值（<= 0 表示不过滤）。
+// 返回: 过滤后的文档列表。
+func ApplyRerankScores(docs []*schema.Document, threshold float64) []*schema.Document {
+	result := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		score, ok := doc.MetaData[components.RerankScoreKey].(float64)
+		if !ok {
+			continue
+		}
+		if threshold > 0 && score < threshold {
+			continue
+		}
+
+		if vectorScore, ok := doc.MetaData["score"]; ok {
+			doc.MetaData["vector_score"] = vectorScore
+		}
+		doc.MetaData["score"] = score
+		doc.WithScore(score)
+		result = append(result, doc)
+	}
+	return result
+}
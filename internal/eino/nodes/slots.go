@@ -0,0 +1,168 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+// MetaAnswerSlots 参数化答案的槽位规则（JSON 字符串，仅参数化答案存在）
+const MetaAnswerSlots = "answer_slots"
+
+// SlotRule 定义参数化答案中一个槽位的提取规则。
+// 答案中以 {name} 引用槽位；Pattern 为空时使用配置中同名的命名模式。
+// 正则包含与槽位同名的命名捕获组时取该组，否则取第一个捕获组，没有捕获组时取整个匹配。
+type SlotRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// SlotFiller 实现参数化答案的槽位校验、提取和填充。
+type SlotFiller struct {
+	patterns map[string]string
+	compiled sync.Map // 槽位名 -> *regexp.Regexp（仅缓存配置中的命名模式，规则自带的正则由请求提交，不缓存）
+}
+
+// NewSlotFiller 创建一个新的槽位填充器。
+// 参数 cfg: 槽位配置（提供命名模式，可为 nil）。
+// 返回: SlotFiller 指针。
+func NewSlotFiller(cfg *config.SlotsConfig) *SlotFiller {
+	f := &SlotFiller{patterns: map[string]string{}}
+	if cfg != nil && cfg.Patterns != nil {
+		f.patterns = cfg.Patterns
+	}
+	return f
+}
+
+// Prepare 校验参数化答案的槽位规则，并返回需要写入缓存元数据的规则 JSON。
+// 参数 answer: 带 {name} 槽位的答案模板。
+// 参数 rules: 槽位提取规则。
+// 返回: 规则 JSON 字符串，规则无效时返回错误。
+func (f *SlotFiller) Prepare(answer string, rules []SlotRule) (string, error) {
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return "", fmt.Errorf("slot name is required")
+		}
+		if seen[rule.Name] {
+			return "", fmt.Errorf("duplicate slot %s", rule.Name)
+		}
+		seen[rule.Name] = true
+
+		if !strings.Contains(answer, "{"+rule.Name+"}") {
+			return "", fmt.Errorf("slot %s is not referenced in answer", rule.Name)
+		}
+		if _, err := f.regexp(rule); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return "", fmt.Errorf("marshal slot rules: %w", err)
+	}
+	return string(data), nil
+}
+
+// Rules 读取候选文档的槽位规则（非参数化答案返回 nil）
+func (f *SlotFiller) Rules(doc *schema.Document) ([]SlotRule, error) {
+	raw, _ := doc.MetaData[MetaAnswerSlots].(string)
+	if raw == "" {
+		return nil, nil
+	}
+	var rules []SlotRule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("unmarshal slot rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Fill 从新问题中提取槽位取值并填充答案模板。
+// 请求显式提供的取值优先于从问题中提取的取值。
+// 参数 answer: 答案模板。
+// 参数 rules: 槽位提取规则。
+// 参数 question: 新问题原文。
+// 参数 hints: 请求提供的槽位取值（可为 nil）。
+// 返回: 填充后的答案和各槽位取值，任一槽位无法解析时返回错误。
+func (f *SlotFiller) Fill(answer string, rules []SlotRule, question string, hints map[string]string) (string, map[string]string, error) {
+	values := make(map[string]string, len(rules))
+	for _, rule := range rules {
+		value := strings.TrimSpace(hints[rule.Name])
+		if value == "" {
+			re, err := f.regexp(rule)
+			if err != nil {
+				return "", nil, err
+			}
+			value = extractSlot(re, rule.Name, question)
+		}
+		if value == "" {
+			return "", nil, fmt.Errorf("slot %s unresolved", rule.Name)
+		}
+		values[rule.Name] = value
+	}
+
+	// 一次性替换所有槽位，取值中包含的 {name} 不会被再次替换
+	replacements := make([]string, 0, 2*len(values))
+	for name, value := range values {
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(answer), values, nil
+}
+
+// Mask 遮盖文本中的槽位取值（用于实体守卫，避免槽位取值不同被判定为冲突）
+func (f *SlotFiller) Mask(rules []SlotRule, text string) string {
+	for _, rule := range rules {
+		if re, err := f.regexp(rule); err == nil {
+			text = re.ReplaceAllString(text, " ")
+		}
+	}
+	return text
+}
+
+// regexp 解析并编译槽位规则的正则表达式。
+// 配置中的命名模式编译后缓存；规则自带的正则每次编译，避免客户端提交的任意正则使缓存无限增长。
+func (f *SlotFiller) regexp(rule SlotRule) (*regexp.Regexp, error) {
+	if rule.Pattern != "" {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compile slot %s pattern: %w", rule.Name, err)
+		}
+		return re, nil
+	}
+
+	if re, ok := f.compiled.Load(rule.Name); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	pattern := f.patterns[rule.Name]
+	if pattern == "" {
+		return nil, fmt.Errorf("slot %s has no pattern", rule.Name)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("compile slot %s pattern: %w", rule.Name, err)
+	}
+	f.compiled.Store(rule.Name, re)
+	return re, nil
+}
+
+// extractSlot 使用正则从文本中提取槽位取值
+func extractSlot(re *regexp.Regexp, name, text string) string {
+	m := re.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	if i := re.SubexpIndex(name); i > 0 {
+		return strings.TrimSpace(m[i])
+	}
+	if len(m) > 1 {
+		return strings.TrimSpace(m[1])
+	}
+	return strings.TrimSpace(m[0])
+}
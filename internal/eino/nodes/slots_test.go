@@ -0,0 +1,155 @@
+package nodes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestSlotFillerPrepare(t *testing.T) {
+	filler := NewSlotFiller(&config.SlotsConfig{Patterns: map[string]string{"order_id": `ORD-\d+`}})
+
+	tests := []struct {
+		name    string
+		answer  string
+		rules   []SlotRule
+		wantErr bool
+	}{
+		{"named pattern from config", "Order {order_id} has shipped.", []SlotRule{{Name: "order_id"}}, false},
+		{"inline pattern", "Hello {name}!", []SlotRule{{Name: "name", Pattern: `I am (\w+)`}}, false},
+		{"missing name", "Hello {name}!", []SlotRule{{Pattern: `(\w+)`}}, true},
+		{"duplicate slot", "Hello {name}!", []SlotRule{{Name: "name", Pattern: `(\w+)`}, {Name: "name", Pattern: `(\w+)`}}, true},
+		{"slot not in answer", "Hello!", []SlotRule{{Name: "name", Pattern: `(\w+)`}}, true},
+		{"no pattern", "Hello {name}!", []SlotRule{{Name: "name"}}, true},
+		{"invalid pattern", "Hello {name}!", []SlotRule{{Name: "name", Pattern: `(\w+`}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := filler.Prepare(tt.answer, tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSlotFillerFill(t *testing.T) {
+	filler := NewSlotFiller(&config.SlotsConfig{Patterns: map[string]string{"order_id": `ORD-\d+`}})
+	rules := []SlotRule{
+		{Name: "order_id"},
+		{Name: "date", Pattern: `on (?P<date>\d{4}-\d{2}-\d{2})`},
+		{Name: "name", Pattern: `for (\w+)`},
+	}
+	template := "Hi {name}, order {order_id} will arrive on {date}."
+
+	tests := []struct {
+		name     string
+		question string
+		hints    map[string]string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "all slots from question",
+			question: "Where is ORD-123 for Alice on 2024-05-01?",
+			expected: "Hi Alice, order ORD-123 will arrive on 2024-05-01.",
+		},
+		{
+			name:     "hint overrides extraction",
+			question: "Where is ORD-123 for Alice on 2024-05-01?",
+			hints:    map[string]string{"name": "Bob"},
+			expected: "Hi Bob, order ORD-123 will arrive on 2024-05-01.",
+		},
+		{
+			name:     "hint fills missing slot",
+			question: "Where is ORD-123 for Alice?",
+			hints:    map[string]string{"date": "2024-06-01"},
+			expected: "Hi Alice, order ORD-123 will arrive on 2024-06-01.",
+		},
+		{
+			name:     "slot value containing a placeholder is not expanded",
+			question: "Where is ORD-123 on 2024-05-01?",
+			hints:    map[string]string{"name": "{date}"},
+			expected: "Hi {date}, order ORD-123 will arrive on 2024-05-01.",
+		},
+		{
+			name:     "unresolved slot",
+			question: "Where is my order for Alice on 2024-05-01?",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, _, err := filler.Fill(template, rules, tt.question, tt.hints)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if answer != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, answer)
+			}
+		})
+	}
+}
+
+func TestSlotFillerPatternCache(t *testing.T) {
+	filler := NewSlotFiller(&config.SlotsConfig{Patterns: map[string]string{"order_id": `ORD-\d+`}})
+
+	// 请求提交的正则不进入缓存，缓存仅包含配置中的命名模式
+	for i := 0; i < 10; i++ {
+		rules := []SlotRule{{Name: "order_id"}, {Name: "n", Pattern: fmt.Sprintf(`(\d{%d})`, i+1)}}
+		if _, _, err := filler.Fill("{order_id} {n}", rules, "ORD-1 1234567890", nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	entries := 0
+	filler.compiled.Range(func(key, value any) bool {
+		entries++
+		return true
+	})
+	if entries != 1 {
+		t.Errorf("expected 1 cached pattern, got %d", entries)
+	}
+}
+
+func TestSlotFillerRulesRoundTrip(t *testing.T) {
+	filler := NewSlotFiller(nil)
+	rules := []SlotRule{{Name: "order_id", Pattern: `ORD-\d+`}}
+
+	raw, err := filler.Prepare("Order {order_id}", rules)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc := &schema.Document{MetaData: map[string]any{MetaAnswerSlots: raw}}
+
+	got, err := filler.Rules(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != rules[0] {
+		t.Errorf("expected %v, got %v", rules, got)
+	}
+
+	if got, _ := filler.Rules(&schema.Document{MetaData: map[string]any{}}); got != nil {
+		t.Errorf("expected nil rules, got %v", got)
+	}
+}
+
+func TestSlotFillerMask(t *testing.T) {
+	filler := NewSlotFiller(nil)
+	rules := []SlotRule{{Name: "order_id", Pattern: `ORD-\d+`}}
+
+	guard, err := NewEntityGuard(&config.EntityGuardConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	query := guard.Extract(filler.Mask(rules, "where is ORD-123"))
+	if ok, reason := guard.Check(query, filler.Mask(rules, "where is ORD-456")); !ok {
+		t.Errorf("expected masked slot values to pass, got %s", reason)
+	}
+}
@@ -0,0 +1,171 @@
+// Package nodes 提供 Eino Graph 中使用的 Lambda 节点实现
+package nodes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+
+	"llm-cache/internal/eino/config"
+)
+
+// 存储来源的信任等级
+const (
+	TrustHigh = "high" // 写入后直接生效
+	TrustLow  = "low"  // 写入后处于待确认状态
+)
+
+// 缓存条目状态
+const (
+	StatusActive    = "active"    // 正常参与查询
+	StatusProbation = "probation" // 待确认，查询时跳过
+)
+
+// AnonymousSource 未认证存储请求的来源标识（所有匿名请求视为同一来源，不能互相确认）
+const AnonymousSource = "anonymous"
+
+// 来源与确认相关的元数据键
+const (
+	// MetaSource 首次写入条目的来源
+	MetaSource = "source"
+	// MetaTrustLevel 首次写入来源的信任等级
+	MetaTrustLevel = "trust_level"
+	// MetaStatus 条目状态（active / probation，缺失视为 active）
+	MetaStatus = "status"
+	// MetaConfirmedBy 写入过该问答对的独立来源（逗号分隔）
+	MetaConfirmedBy = "confirmed_by"
+	// MetaConfirmations 独立来源数
+	MetaConfirmations = "confirmations"
+)
+
+// entryNamespace 生成确定性条目 ID 的 UUID 命名空间
+var entryNamespace = uuid.MustParse("6f0d6c1e-8a0b-4c59-9a53-3d2f4b7e1c2a")
+
+// probationNamespace 生成待确认条目 ID 的 UUID 命名空间
+var probationNamespace = uuid.MustParse("b3a7e2d4-5c1f-4e8a-9d6b-0f2c7a1e4b93")
+
+// entryKeyMetadata 参与条目 ID 计算的匹配维度（与查询侧守卫一致）
+var entryKeyMetadata = []string{
+	MetaTemplateID,
+	MetaContextHash,
+	MetaGenModel,
+	MetaGenSystemPromptHash,
+	MetaGenTemperatureBucket,
+	MetaGenToolSchemaHash,
+}
+
+// TrustPolicy 实现存储来源的信任分级和待确认条目的确认规则。
+// 高信任来源写入的条目直接生效；低信任来源写入的条目需要 Confirmations 个独立来源
+// 写入同一问答对（或任一高信任来源写入）后才会生效。
+type TrustPolicy struct {
+	confirmations int
+}
+
+// NewTrustPolicy 创建一个新的信任策略。
+// 参数 cfg: 来源信任配置（Confirmations 小于 1 时按 1 处理）。
+// 返回: TrustPolicy 指针。
+func NewTrustPolicy(cfg *config.TrustConfig) *TrustPolicy {
+	return &TrustPolicy{confirmations: max(cfg.Confirmations, 1)}
+}
+
+// Entry 返回新条目的来源元数据。
+// 参数 source: 来源标识。
+// 参数 trust: 来源的信任等级。
+// 返回: 来源、信任等级、状态和确认来源等元数据。
+func (p *TrustPolicy) Entry(source, trust string) map[string]any {
+	return p.metadata(source, trust, []string{source}, trust == TrustHigh)
+}
+
+// Confirm 将一次存储作为确认合并到已有条目的元数据中。
+// 已生效的条目无需确认；同一来源的重复写入不计入独立确认。
+// 参数 existing: 已有条目的元数据。
+// 参数 source: 本次存储的来源标识。
+// 参数 trust: 本次存储来源的信任等级。
+// 返回: 更新后的来源元数据，以及条目是否需要写回。
+func (p *TrustPolicy) Confirm(existing map[string]any, source, trust string) (map[string]any, bool) {
+	if EntryStatus(existing) != StatusProbation {
+		return nil, false
+	}
+
+	confirmedBy := ConfirmedBy(existing)
+	known := false
+	for _, s := range confirmedBy {
+		if s == source {
+			known = true
+			break
+		}
+	}
+	if known && trust != TrustHigh {
+		return nil, false
+	}
+	if !known {
+		confirmedBy = append(confirmedBy, source)
+	}
+
+	origin, _ := existing[MetaSource].(string)
+	originTrust, _ := existing[MetaTrustLevel].(string)
+	return p.metadata(origin, originTrust, confirmedBy, trust == TrustHigh), true
+}
+
+// metadata 构建来源元数据（高信任确认或独立来源数达到要求时生效）
+func (p *TrustPolicy) metadata(source, trust string, confirmedBy []string, trusted bool) map[string]any {
+	status := StatusProbation
+	if trusted || len(confirmedBy) >= p.confirmations {
+		status = StatusActive
+	}
+	return map[string]any{
+		MetaSource:        source,
+		MetaTrustLevel:    trust,
+		MetaStatus:        status,
+		MetaConfirmedBy:   strings.Join(confirmedBy, ","),
+		MetaConfirmations: len(confirmedBy),
+	}
+}
+
+// EntryStatus 返回条目状态（未记录状态的条目视为已生效）
+func EntryStatus(metadata map[string]any) string {
+	if status, ok := metadata[MetaStatus].(string); ok && status != "" {
+		return status
+	}
+	return StatusActive
+}
+
+// InProbation 判断缓存项是否处于待确认状态
+func InProbation(doc *schema.Document) bool {
+	return EntryStatus(doc.MetaData) == StatusProbation
+}
+
+// ConfirmedBy 返回写入过该条目的独立来源列表
+func ConfirmedBy(metadata map[string]any) []string {
+	raw, _ := metadata[MetaConfirmedBy].(string)
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
+
+// EntryID 根据问答对及其匹配维度生成确定性的条目 ID。
+// 不同来源写入同一问答对时得到相同的 ID，确认时直接覆盖原条目。
+// 该 ID 只用于已生效的条目，待确认条目使用 ProbationEntryID。
+// 参数 userType: 用户类型。
+// 参数 content: 索引内容（规范化后的问题）。
+// 参数 answer: 答案。
+// 参数 metadata: 条目元数据（读取模板、上下文和生成参数等匹配维度）。
+// 返回: UUID 格式的条目 ID。
+func EntryID(userType, content, answer string, metadata map[string]any) string {
+	parts := []string{userType, content, answer}
+	for _, key := range entryKeyMetadata {
+		parts = append(parts, fmt.Sprintf("%s=%v", key, metadata[key]))
+	}
+	return uuid.NewSHA1(entryNamespace, []byte(strings.Join(parts, "\x00"))).String()
+}
+
+// ProbationEntryID 返回同一问答对的待确认条目 ID。
+// 待确认条目与已生效条目使用不同 ID，低信任来源写入时即使未找到已有条目，也不会覆盖（降级）已生效的条目。
+// 参数 entryID: EntryID 返回的条目 ID。
+// 返回: UUID 格式的待确认条目 ID。
+func ProbationEntryID(entryID string) string {
+	return uuid.NewSHA1(probationNamespace, []byte(entryID)).String()
+}
@@ -0,0 +1,132 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"llm-cache/internal/eino/config"
+)
+
+func TestTrustPolicyEntry(t *testing.T) {
+	policy := NewTrustPolicy(&config.TrustConfig{Confirmations: 2})
+
+	tests := []struct {
+		name     string
+		trust    string
+		expected string
+	}{
+		{"high trust is active", TrustHigh, StatusActive},
+		{"low trust is on probation", TrustLow, StatusProbation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := policy.Entry("svc", tt.trust)
+			if meta[MetaStatus] != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, meta[MetaStatus])
+			}
+			if meta[MetaSource] != "svc" || meta[MetaConfirmedBy] != "svc" || meta[MetaConfirmations] != 1 {
+				t.Errorf("unexpected source metadata: %v", meta)
+			}
+		})
+	}
+
+	single := NewTrustPolicy(&config.TrustConfig{Confirmations: 1})
+	if status := single.Entry("svc", TrustLow)[MetaStatus]; status != StatusActive {
+		t.Errorf("expected %v, got %v", StatusActive, status)
+	}
+}
+
+func TestTrustPolicyConfirm(t *testing.T) {
+	policy := NewTrustPolicy(&config.TrustConfig{Confirmations: 3})
+	probation := policy.Entry("a", TrustLow)
+
+	tests := []struct {
+		name          string
+		existing      map[string]any
+		source        string
+		trust         string
+		changed       bool
+		status        string
+		confirmedBy   string
+		confirmations int
+	}{
+		{"independent source", probation, "b", TrustLow, true, StatusProbation, "a,b", 2},
+		{"same source is not counted", probation, "a", TrustLow, false, "", "", 0},
+		{"enough confirmations", map[string]any{MetaSource: "a", MetaTrustLevel: TrustLow, MetaStatus: StatusProbation, MetaConfirmedBy: "a,b"}, "c", TrustLow, true, StatusActive, "a,b,c", 3},
+		{"high trust confirms", probation, "admin", TrustHigh, true, StatusActive, "a,admin", 2},
+		{"high trust origin re-store", probation, "a", TrustHigh, true, StatusActive, "a", 1},
+		{"already active", policy.Entry("x", TrustHigh), "b", TrustLow, false, "", "", 0},
+		{"legacy entry without status", map[string]any{}, "b", TrustLow, false, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, changed := policy.Confirm(tt.existing, tt.source, tt.trust)
+			if changed != tt.changed {
+				t.Fatalf("expected changed %v, got %v", tt.changed, changed)
+			}
+			if !changed {
+				return
+			}
+			if meta[MetaStatus] != tt.status {
+				t.Errorf("expected status %v, got %v", tt.status, meta[MetaStatus])
+			}
+			if meta[MetaConfirmedBy] != tt.confirmedBy {
+				t.Errorf("expected confirmed_by %v, got %v", tt.confirmedBy, meta[MetaConfirmedBy])
+			}
+			if meta[MetaConfirmations] != tt.confirmations {
+				t.Errorf("expected confirmations %v, got %v", tt.confirmations, meta[MetaConfirmations])
+			}
+			if meta[MetaSource] != tt.existing[MetaSource] {
+				t.Errorf("expected origin %v, got %v", tt.existing[MetaSource], meta[MetaSource])
+			}
+		})
+	}
+}
+
+func TestEntryID(t *testing.T) {
+	base := EntryID("u", "如何重置密码", "在设置页面重置", map[string]any{MetaTemplateID: "t1"})
+
+	tests := []struct {
+		name     string
+		id       string
+		expected bool
+	}{
+		{"same pair", EntryID("u", "如何重置密码", "在设置页面重置", map[string]any{MetaTemplateID: "t1", "custom": 1}), true},
+		{"different answer", EntryID("u", "如何重置密码", "联系客服", map[string]any{MetaTemplateID: "t1"}), false},
+		{"different user type", EntryID("v", "如何重置密码", "在设置页面重置", map[string]any{MetaTemplateID: "t1"}), false},
+		{"different template", EntryID("u", "如何重置密码", "在设置页面重置", map[string]any{MetaTemplateID: "t2"}), false},
+		{"different context", EntryID("u", "如何重置密码", "在设置页面重置", map[string]any{MetaTemplateID: "t1", MetaContextHash: "h"}), false},
+		{"probation entry", ProbationEntryID(base), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.id == base; got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestInProbation(t *testing.T) {
+	tests := []struct {
+		name     string
+		meta     map[string]any
+		expected bool
+	}{
+		{"probation", map[string]any{MetaStatus: StatusProbation}, true},
+		{"active", map[string]any{MetaStatus: StatusActive}, false},
+		{"legacy", map[string]any{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InProbation(&schema.Document{MetaData: tt.meta}); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ const (
 	ErrCodeUnavailable StatusCode = 1003
 	// ErrCodeNotFound 请求的资源不存在。
 	ErrCodeNotFound StatusCode = 1004
+	// ErrCodeUnauthorized 请求未通过身份认证。
+	ErrCodeUnauthorized StatusCode = 1005
 )
 
 // String 返回状态码对应的字符串描述。
@@ -33,6 +35,8 @@ func (c StatusCode) String() string {
 		return "UNAVAILABLE"
 	case ErrCodeNotFound:
 		return "NOT_FOUND"
+	case ErrCodeUnauthorized:
+		return "UNAUTHORIZED"
 	default:
 		return "UNKNOWN"
 	}